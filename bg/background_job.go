@@ -2,11 +2,15 @@ package bg
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/rajnandan1/smaraka/config"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
 	"github.com/rajnandan1/smaraka/postgres"
 	"github.com/rajnandan1/smaraka/services"
 	"github.com/riverqueue/river"
@@ -16,92 +20,286 @@ import (
 )
 
 type Background interface {
-	SubmitURLs(ctx context.Context, urls []string, orgId string) (*rivertype.JobInsertResult, error)
+	SubmitURLs(ctx context.Context, urls []string, orgId, lane, batchID string) ([]*rivertype.JobInsertResult, error)
+	RetryURLs(ctx context.Context, tx pgx.Tx, urls []string, orgId, batchID, strategy string) ([]*rivertype.JobInsertResult, error)
+	ReindexURL(ctx context.Context, url, orgId string) (*rivertype.JobInsertResult, error)
+	SubmitSummary(ctx context.Context, urlID string) (*rivertype.JobInsertResult, error)
+	ListIngestJobs(ctx context.Context, orgId, state, cursor string, limit int) (*models.IngestJobsResponse, error)
 	Close(ctx context.Context) error
 }
 
+// ErrInvalidCursor is a job list cursor that was not handed out by ListIngestJobs
+var ErrInvalidCursor = errors.New("invalid job list cursor")
+
 type BackgroundImplementation struct {
-	riverClient  *river.Client[pgx.Tx]
-	URLQueueName string
+	riverClient *river.Client[pgx.Tx]
+	browserPool *services.BrowserPool
+	svc         services.Services
 }
 
-func ConfigureBackground(ctx context.Context, pg postgres.Postgres, svc services.Services, maxWorkers int) (Background, error) {
+const (
+	// recrawlBatchSize is the number of stale url stores refetched per recrawl run
+	recrawlBatchSize = 100
+	// linkCheckBatchSize is the number of links checked per link check run
+	linkCheckBatchSize = 500
+	// languageBatchSize is the number of url stores saved before language detection handled per run
+	languageBatchSize = 1000
+	// summaryBatchSize is the number of completed or changed url stores summarized per run
+	summaryBatchSize = 200
+	// jobCancelTimeout is how long cancelled jobs get to record their failure once the graceful stop ran out of time
+	jobCancelTimeout = 10 * time.Second
+)
 
-	queueName := "url_fetch"
+func ConfigureBackground(ctx context.Context, pg postgres.Postgres, svc services.Services, browserPool *services.BrowserPool, config config.Config) (Background, error) {
+	maxWorkers := config.MaxWorkers
 
 	dbPool := pg.GetConnectionPool()
 	workers := river.NewWorkers()
 
-	// Register both workers
+	// imports and schedules of many organizations share their lanes, a single save or a recrawl does not compete
+	river.AddWorker(workers, &URLIngestWorker{
+		Service: svc,
+		DB:      pg,
+		FairShared: map[string]int{
+			LaneBulk:     maxWorkers,
+			LaneSchedule: config.ScheduleWorkers,
+		},
+	})
 	river.AddWorker(workers, &URLStoreProcessWorker{
 		Service: svc,
 	})
 	river.AddWorker(workers, &PeriodicJobWorker{
 		Service: svc,
-	}) // Add this line
-
-	riverClient, err := river.NewClient(riverpgxv5.New(dbPool), &river.Config{
-		Queues: map[string]river.QueueConfig{
-			queueName:          {MaxWorkers: maxWorkers},
-			river.QueueDefault: {MaxWorkers: maxWorkers},
-		},
-		Logger:  slog.New(&slogutil.SlogMessageOnlyHandler{Level: slog.LevelWarn}),
-		Workers: workers,
-		PeriodicJobs: []*river.PeriodicJob{
-			river.NewPeriodicJob(
-				river.PeriodicInterval(24*time.Hour),
-				func() (river.JobArgs, *river.InsertOpts) {
-					return PeriodicJobArgs{
-						Interval: 1,
-					}, nil
-				},
-				&river.PeriodicJobOpts{RunOnStart: true},
-			),
-			river.NewPeriodicJob(
-				river.PeriodicInterval(7*24*time.Hour),
-				func() (river.JobArgs, *river.InsertOpts) {
-					return PeriodicJobArgs{
-						Interval: 7,
-					}, nil
-				},
-				&river.PeriodicJobOpts{RunOnStart: true},
-			), river.NewPeriodicJob(
-				river.PeriodicInterval(30*24*time.Hour),
-				func() (river.JobArgs, *river.InsertOpts) {
-					return PeriodicJobArgs{
-						Interval: 30,
-					}, nil
-				},
-				&river.PeriodicJobOpts{RunOnStart: true},
-			),
-		},
 	})
+	river.AddWorker(workers, &RecrawlWorker{
+		Service: svc,
+	})
+	river.AddWorker(workers, &LinkCheckWorker{
+		Service: svc,
+	})
+	river.AddWorker(workers, &LanguageDetectWorker{
+		Service: svc,
+	})
+	river.AddWorker(workers, &SummarizeWorker{
+		Service: svc,
+	})
+	river.AddWorker(workers, &SummarizePendingWorker{
+		Service: svc,
+	})
+	river.AddWorker(workers, &IdempotencyKeyCleanupWorker{
+		DB: pg,
+	})
+
+	riverClient, err := river.NewClient(riverpgxv5.New(dbPool), riverConfig(config, workers))
 	if err != nil {
-		log.Fatalf("Failed to create River client: %v", err)
+		return nil, fmt.Errorf("failed to create River client: %v", err)
 	}
 
-	if err := riverClient.Start(ctx); err != nil {
-		log.Fatalf("Failed to start River client: %v", err)
+	if config.RunsWorkers() {
+		// cancelling the start context would cancel every running job at once, Close drains them instead
+		if err := riverClient.Start(context.WithoutCancel(ctx)); err != nil {
+			return nil, fmt.Errorf("failed to start River client: %v", err)
+		}
 	}
 
 	return &BackgroundImplementation{
-		riverClient:  riverClient,
-		URLQueueName: queueName,
+		riverClient: riverClient,
+		browserPool: browserPool,
+		svc:         svc,
 	}, nil
 }
 
+// riverConfig configures river for the mode of the process, without queues it only inserts jobs
+func riverConfig(config config.Config, workers *river.Workers) *river.Config {
+	maxWorkers := config.MaxWorkers
+	riverConfig := &river.Config{
+		Logger:  slog.New(&slogutil.SlogMessageOnlyHandler{Level: slog.LevelWarn}),
+		Workers: workers,
+		// the job list, failure reasons and dead letters are read from finished jobs, river's cleaner
+		// would otherwise delete cancelled jobs after a day
+		CompletedJobRetentionPeriod: time.Duration(max(1, config.CompletedJobRetentionDays)) * 24 * time.Hour,
+		CancelledJobRetentionPeriod: time.Duration(max(1, config.FailedJobRetentionDays)) * 24 * time.Hour,
+		DiscardedJobRetentionPeriod: time.Duration(max(1, config.FailedJobRetentionDays)) * 24 * time.Hour,
+	}
+	// a server only inserts jobs, the workers of other processes work them. Periodic jobs are inserted
+	// by the elected leader among the processes working jobs, so schedules run once however many there are
+	if config.RunsWorkers() {
+		riverConfig.Queues = map[string]river.QueueConfig{
+			LaneInteractive:    {MaxWorkers: max(1, config.InteractiveWorkers)},
+			LaneBulk:           {MaxWorkers: maxWorkers},
+			LaneSchedule:       {MaxWorkers: max(1, config.ScheduleWorkers)},
+			LaneRecrawl:        {MaxWorkers: max(1, config.RecrawlWorkers)},
+			river.QueueDefault: {MaxWorkers: maxWorkers},
+		}
+		riverConfig.PeriodicJobs = periodicJobs(config)
+	}
+	return riverConfig
+}
+
+// periodicJobs are the jobs inserted on a schedule
+func periodicJobs(config config.Config) []*river.PeriodicJob {
+	return []*river.PeriodicJob{
+		river.NewPeriodicJob(
+			river.PeriodicInterval(24*time.Hour),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return PeriodicJobArgs{
+					Interval: 1,
+				}, nil
+			},
+			&river.PeriodicJobOpts{RunOnStart: true},
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(7*24*time.Hour),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return PeriodicJobArgs{
+					Interval: 7,
+				}, nil
+			},
+			&river.PeriodicJobOpts{RunOnStart: true},
+		), river.NewPeriodicJob(
+			river.PeriodicInterval(30*24*time.Hour),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return PeriodicJobArgs{
+					Interval: 30,
+				}, nil
+			},
+			&river.PeriodicJobOpts{RunOnStart: true},
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(6*time.Hour),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return RecrawlArgs{
+					MaxAgeDays: config.RecrawlDays,
+					BatchSize:  recrawlBatchSize,
+				}, nil
+			},
+			nil,
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(1*time.Hour),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return LinkCheckArgs{
+					MaxAgeDays: config.LinkCheckDays,
+					BatchSize:  linkCheckBatchSize,
+				}, nil
+			},
+			nil,
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(1*time.Hour),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return LanguageDetectArgs{
+					BatchSize: languageBatchSize,
+				}, nil
+			},
+			&river.PeriodicJobOpts{RunOnStart: true},
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(5*time.Minute),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return SummarizePendingArgs{
+					BatchSize: summaryBatchSize,
+				}, nil
+			},
+			&river.PeriodicJobOpts{RunOnStart: true},
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(1*time.Hour),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return IdempotencyKeyCleanupArgs{}, nil
+			},
+			nil,
+		),
+	}
+}
+
+// Close stops fetching new jobs and lets running jobs finish until ctx is done, jobs still running then are cancelled
+// and handed back to be retried. The browser pool is closed once no job uses it any more
 func (b *BackgroundImplementation) Close(ctx context.Context) error {
-	return b.riverClient.StopAndCancel(ctx)
+	return stopJobs(ctx, b.riverClient, b.browserPool)
+}
+
+// jobStopper is the part of the river client stopping it
+type jobStopper interface {
+	Stop(ctx context.Context) error
+	StopAndCancel(ctx context.Context) error
+}
+
+func stopJobs(ctx context.Context, jobs jobStopper, browserPool interface{ Close() }) error {
+	err := jobs.Stop(ctx)
+	if ctx.Err() != nil {
+		logger.LogInfo("Jobs still running at shutdown are cancelled")
+		cancelCtx, cancel := context.WithTimeout(context.Background(), jobCancelTimeout)
+		defer cancel()
+		err = jobs.StopAndCancel(cancelCtx)
+	}
+	browserPool.Close()
+	return err
+}
+
+// SubmitURLs queues every url as a job of its own on a lane, so urls are retried and fail one by one.
+// batchID is the import batch the urls came with, empty for urls saved outside of an import
+func (b *BackgroundImplementation) SubmitURLs(ctx context.Context, urls []string, orgId, lane, batchID string) ([]*rivertype.JobInsertResult, error) {
+	return insertURLJobs(ctx, b.svc, b.riverClient, urls, lane, ingestMetadata{OrgID: orgId, BatchID: batchID})
+}
+
+// RetryURLs queues failed urls again in tx on the bulk lane, fetched with strategy whatever their domain's strategy is.
+// No progress is published, the jobs do not exist before tx commits
+func (b *BackgroundImplementation) RetryURLs(ctx context.Context, tx pgx.Tx, urls []string, orgId, batchID, strategy string) ([]*rivertype.JobInsertResult, error) {
+	return b.riverClient.InsertManyTx(ctx, tx, urlIngestParams(urls, LaneBulk, ingestMetadata{OrgID: orgId, BatchID: batchID, FetchStrategy: strategy}))
+}
+
+// ReindexURL queues a url that is complete already on the interactive lane to be fetched again,
+// it stays complete until the new fetch is stored
+func (b *BackgroundImplementation) ReindexURL(ctx context.Context, url, orgId string) (*rivertype.JobInsertResult, error) {
+	results, err := insertURLJobs(ctx, b.svc, b.riverClient, []string{url}, LaneInteractive, ingestMetadata{OrgID: orgId, Refetch: true})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// ListIngestJobs pages through an organization's url ingest jobs newest first, optionally in one state only
+func (b *BackgroundImplementation) ListIngestJobs(ctx context.Context, orgId, state, cursor string, limit int) (*models.IngestJobsResponse, error) {
+	params := river.NewJobListParams().
+		Kinds(URLIngestArgs{}.Kind()).
+		Metadata(string(ingestMetadata{OrgID: orgId}.encode())).
+		OrderBy(river.JobListOrderByID, river.SortOrderDesc).
+		First(limit)
+	if state != "" {
+		params = params.States(rivertype.JobState(state))
+	}
+	if cursor != "" {
+		after := &river.JobListCursor{}
+		if err := after.UnmarshalText([]byte(cursor)); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		params = params.After(after)
+	}
+
+	result, err := b.riverClient.JobList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.IngestJobsResponse{Jobs: make([]models.IngestJob, 0, len(result.Jobs))}
+	for _, row := range result.Jobs {
+		response.Jobs = append(response.Jobs, ingestJob(row))
+	}
+	if len(result.Jobs) == limit && result.LastCursor != nil {
+		next, err := result.LastCursor.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		response.NextCursor = string(next)
+	}
+	return response, nil
 }
 
-func (b *BackgroundImplementation) SubmitURLs(ctx context.Context, urls []string, orgId string) (*rivertype.JobInsertResult, error) {
-	res, err := b.riverClient.Insert(ctx, URLStoreProcessArgs{
-		URLs:    urls,
-		OrgUser: orgId,
+func (b *BackgroundImplementation) SubmitSummary(ctx context.Context, urlID string) (*rivertype.JobInsertResult, error) {
+	return b.riverClient.Insert(ctx, SummarizeArgs{
+		URLID: urlID,
 	}, &river.InsertOpts{
-		Queue:       b.URLQueueName,
 		MaxAttempts: 3,
 	})
-
-	return res, err
 }
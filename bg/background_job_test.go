@@ -0,0 +1,113 @@
+package bg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rajnandan1/smaraka/config"
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/postgres"
+	"github.com/rajnandan1/smaraka/services"
+	"github.com/riverqueue/river"
+)
+
+// noPoolDB has no database, river can insert jobs with it but not work them
+type noPoolDB struct {
+	postgres.Postgres
+}
+
+func (db *noPoolDB) GetConnectionPool() *pgxpool.Pool { return nil }
+
+func TestRiverConfigByMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		working bool
+	}{
+		{mode: constants.ModeServer, working: false},
+		{mode: constants.ModeWorker, working: true},
+		{mode: constants.ModeAll, working: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			c := config.Config{Mode: tt.mode, MaxWorkers: 4}
+			riverConfig := riverConfig(c, river.NewWorkers())
+			if working := len(riverConfig.Queues) > 0; working != tt.working {
+				t.Fatalf("queues %v, want working %v", riverConfig.Queues, tt.working)
+			}
+			if periodic := len(riverConfig.PeriodicJobs) > 0; periodic != tt.working {
+				t.Fatalf("%d periodic jobs, want them only when working", len(riverConfig.PeriodicJobs))
+			}
+
+			// without a database pool river refuses to work jobs, a server never needs one to start
+			background, err := ConfigureBackground(context.Background(), &noPoolDB{}, nil, services.NewBrowserPool(1, 0), c)
+			if tt.working && err == nil {
+				t.Fatal("ConfigureBackground started workers without a database")
+			}
+			if !tt.working {
+				if err != nil {
+					t.Fatalf("ConfigureBackground: %v", err)
+				}
+				if err := background.Close(context.Background()); err != nil {
+					t.Fatalf("Close: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// orderedStop records the order river and the browser pool are stopped in
+type orderedStop struct {
+	calls []string
+	// block keeps Stop waiting for its context, jobs that do not finish in time
+	block bool
+}
+
+func (o *orderedStop) Stop(ctx context.Context) error {
+	o.calls = append(o.calls, "stop")
+	if o.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (o *orderedStop) StopAndCancel(ctx context.Context) error {
+	o.calls = append(o.calls, "stop_and_cancel")
+	return nil
+}
+
+func (o *orderedStop) Close() {
+	o.calls = append(o.calls, "browser_pool")
+}
+
+func TestStopJobsBeforeBrowserPool(t *testing.T) {
+	tests := []struct {
+		name  string
+		block bool
+		calls []string
+	}{
+		{name: "jobs drain", calls: []string{"stop", "browser_pool"}},
+		{name: "jobs cancelled", block: true, calls: []string{"stop", "stop_and_cancel", "browser_pool"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stop := &orderedStop{block: tt.block}
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			if err := stopJobs(ctx, stop, stop); err != nil {
+				t.Fatalf("stopJobs: %v", err)
+			}
+			if len(stop.calls) != len(tt.calls) {
+				t.Fatalf("calls %v, want %v", stop.calls, tt.calls)
+			}
+			for i := range tt.calls {
+				if stop.calls[i] != tt.calls[i] {
+					t.Fatalf("calls %v, want %v", stop.calls, tt.calls)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package bg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/postgres"
+	"github.com/rajnandan1/smaraka/services"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertype"
+)
+
+// lanes urls are fetched on, each queue has workers of its own so a large import never holds back a single save
+const (
+	LaneInteractive = "url_interactive"
+	LaneBulk        = "url_fetch"
+	LaneSchedule    = "url_schedule"
+	LaneRecrawl     = "recrawl"
+)
+
+const (
+	// ingestMaxAttempts is how often a url that keeps failing with a retryable error is tried
+	ingestMaxAttempts = 5
+	// ingestMaxThrottleWait bounds how long a host that keeps throttling us can hold a url back
+	ingestMaxThrottleWait = 24 * time.Hour
+	// fairShareDelay is how long a job waits when its organization already has its share of the workers
+	fairShareDelay = 10 * time.Second
+	// orgCountTTL is how long the number of organizations on a lane is reused for their fair share
+	orgCountTTL = 10 * time.Second
+	// ingestTimeout is how long one url may take, the light fetch, the full fetch and the snapshot together
+	ingestTimeout = 5 * time.Minute
+	// ingestBaseBackoff doubles with every failed attempt up to ingestMaxBackoff
+	ingestBaseBackoff = 30 * time.Second
+	ingestMaxBackoff  = 6 * time.Hour
+)
+
+// URLIngestArgs saves one url for one organization, a url is queued at most once per organization at a time
+type URLIngestArgs struct {
+	URL   string `json:"url"`
+	OrgID string `json:"org_id"`
+}
+
+func (URLIngestArgs) Kind() string { return "url_ingest" }
+
+func (URLIngestArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		Queue:       LaneBulk,
+		MaxAttempts: ingestMaxAttempts,
+		// finished jobs are left out so a url can be submitted again once it is done or has failed
+		UniqueOpts: river.UniqueOpts{
+			ByArgs: true,
+			ByState: []rivertype.JobState{
+				rivertype.JobStateAvailable,
+				rivertype.JobStatePending,
+				rivertype.JobStateRetryable,
+				rivertype.JobStateRunning,
+				rivertype.JobStateScheduled,
+			},
+		},
+	}
+}
+
+type URLIngestWorker struct {
+	river.WorkerDefaults[URLIngestArgs]
+	Service services.Services
+	DB      postgres.Postgres
+	// FairShared are the lanes many organizations compete on and the number of workers each has
+	FairShared map[string]int
+
+	orgsMu sync.Mutex
+	orgs   map[string]orgCount
+}
+
+// orgCount is the number of organizations on a lane when it was counted
+type orgCount struct {
+	count     int
+	countedAt time.Time
+}
+
+func (w *URLIngestWorker) Timeout(job *river.Job[URLIngestArgs]) time.Duration {
+	return ingestTimeout
+}
+
+// NextRetry backs off exponentially with some jitter so urls of one host failing together do not come back together.
+// Failures are counted by the errors recorded, pauses and fair share waits are snoozes which give their attempt back
+func (w *URLIngestWorker) NextRetry(job *river.Job[URLIngestArgs]) time.Time {
+	backoff := ingestMaxBackoff
+	if failures := len(job.Errors); failures < 16 {
+		backoff = min(ingestBaseBackoff<<failures, ingestMaxBackoff)
+	}
+	jitter := time.Duration(rand.Int63n(int64(backoff / 10)))
+	return time.Now().Add(backoff + jitter)
+}
+
+// wait tells if a job has to wait before it runs and why, because its organization paused its imports
+// or already has its share of the lane's workers
+func (w *URLIngestWorker) wait(ctx context.Context, job *river.Job[URLIngestArgs]) (time.Duration, string) {
+	workers, fairShared := w.FairShared[job.Queue]
+	if !fairShared {
+		return 0, ""
+	}
+	if job.Queue == LaneBulk {
+		paused, err := w.DB.IsIngestPaused(ctx, job.Args.OrgID)
+		if err != nil {
+			logger.LogError("Error getting ingest pause", job.Args.OrgID, err)
+		}
+		if paused {
+			return postgres.PausedIngestDelay, "imports of the organization are paused"
+		}
+	}
+
+	activeOrgs, err := w.activeOrgs(ctx, job.Queue)
+	if err != nil {
+		logger.LogError("Error counting ingest organizations", job.Queue, err)
+		return 0, ""
+	}
+	running, err := w.DB.CountRunningIngestJobs(ctx, job.Args.OrgID, job.Queue)
+	if err != nil {
+		logger.LogError("Error counting running ingest jobs", job.Args.OrgID, err)
+		return 0, ""
+	}
+	// running counts this job as well
+	if share := max(1, workers/max(1, activeOrgs)); running > share {
+		reason := fmt.Sprintf("the organization has its share of %d of the %d workers of %s", share, workers, job.Queue)
+		return fairShareDelay + time.Duration(rand.Int63n(int64(fairShareDelay))), reason
+	}
+	return 0, ""
+}
+
+// deferral is why a job last had to wait, it is recorded as the job's output and listed with the job
+type deferral struct {
+	Reason string    `json:"deferred_reason"`
+	At     time.Time `json:"deferred_at"`
+}
+
+// recordOutput stores the output of the running job, it is river.RecordOutput outside of tests
+var recordOutput = river.RecordOutput
+
+// recordDeferral keeps reason on the job, river stores it with the job row whatever the attempt ends in, snoozes too
+func recordDeferral(ctx context.Context, reason string) {
+	if err := recordOutput(ctx, deferral{Reason: reason, At: time.Now()}); err != nil {
+		logger.LogError("Error recording deferral", reason, err)
+	}
+}
+
+// activeOrgs returns the number of organizations on a lane, counting them reads every waiting job
+// so the count is reused for orgCountTTL
+func (w *URLIngestWorker) activeOrgs(ctx context.Context, queue string) (int, error) {
+	w.orgsMu.Lock()
+	defer w.orgsMu.Unlock()
+	if cached, ok := w.orgs[queue]; ok && time.Since(cached.countedAt) < orgCountTTL {
+		return cached.count, nil
+	}
+	count, err := w.DB.CountIngestOrgs(ctx, queue)
+	if err != nil {
+		return 0, err
+	}
+	if w.orgs == nil {
+		w.orgs = make(map[string]orgCount)
+	}
+	w.orgs[queue] = orgCount{count: count, countedAt: time.Now()}
+	return count, nil
+}
+
+func (w *URLIngestWorker) Work(ctx context.Context, job *river.Job[URLIngestArgs]) error {
+	if delay, reason := w.wait(ctx, job); reason != "" {
+		recordDeferral(ctx, reason)
+		return river.JobSnooze(delay)
+	}
+	// fetches waiting for a slot on their host or its crawl delay are recorded the same way
+	ctx = services.WithDeferralHook(ctx, func(reason string) {
+		recordDeferral(ctx, reason)
+	})
+
+	metadata := jobMetadata(job.JobRow)
+	batchID := metadata.BatchID
+	if metadata.FetchStrategy != "" {
+		ctx = services.WithFetchStrategy(ctx, metadata.FetchStrategy)
+	}
+	if metadata.Refetch {
+		ctx = services.WithRefetch(ctx)
+	}
+	// a user waits for a single save, it may use the browser tabs imports are kept off
+	if job.Queue == LaneInteractive {
+		ctx = services.WithPriority(ctx)
+	}
+	err := w.Service.IngestURL(ctx, job.Args.URL, job.Args.OrgID)
+	if err == nil {
+		w.recordOutcome(ctx, batchID, job.Args.URL, constants.ImportOutcomeAdded, "")
+		return nil
+	}
+
+	// river records the returned error with the job, its message starts with the failure class
+	failure := services.ClassifyIngestError(err)
+	// snoozing does not use up attempts, hosts telling us when to come back are believed a few times
+	snooze := failure.Class == services.FailureThrottled && failure.RetryAfter > 0 && time.Since(job.CreatedAt) < ingestMaxThrottleWait
+	final := !failure.Retryable || !snooze && job.Attempt >= job.MaxAttempts
+	if final {
+		w.recordOutcome(ctx, batchID, job.Args.URL, constants.ImportOutcomeFailed, failure.Error())
+	}
+	w.Service.PublishProgress(ctx, models.IngestProgress{
+		OrgID:        job.Args.OrgID,
+		URL:          job.Args.URL,
+		Stage:        constants.IngestStageFailed,
+		Attempt:      job.Attempt,
+		Final:        final,
+		Error:        failure.Error(),
+		FailureClass: failure.Class,
+	})
+	switch {
+	case !failure.Retryable:
+		return river.JobCancel(failure)
+	case snooze:
+		recordDeferral(ctx, failure.Error())
+		return river.JobSnooze(failure.RetryAfter)
+	}
+	return failure
+}
+
+// recordOutcome tells the import batch a url came with what became of it, urls saved outside of an import have no batch
+func (w *URLIngestWorker) recordOutcome(ctx context.Context, batchID, url, outcome, reason string) {
+	if batchID == "" {
+		return
+	}
+	if err := w.DB.UpdateImportBatchURLOutcome(ctx, batchID, url, outcome, reason); err != nil {
+		logger.LogError("Error recording import outcome", batchID, url, err)
+	}
+}
+
+// ingestMetadata tags a job with its organization, the import batch it came with and the fetch strategy
+// a retry asked for, jobs are listed and counted per organization by it. It is kept out of the args
+// so a url stays unique per organization
+type ingestMetadata struct {
+	OrgID         string `json:"org_id"`
+	BatchID       string `json:"batch_id,omitempty"`
+	FetchStrategy string `json:"fetch_strategy,omitempty"`
+	// Refetch fetches a url that is complete already again
+	Refetch bool `json:"refetch,omitempty"`
+}
+
+func (m ingestMetadata) encode() []byte {
+	metadata, _ := json.Marshal(m)
+	return metadata
+}
+
+func jobMetadata(row *rivertype.JobRow) ingestMetadata {
+	var metadata ingestMetadata
+	json.Unmarshal(row.Metadata, &metadata)
+	return metadata
+}
+
+// urlIngestParams is one ingest job for every url on a lane
+func urlIngestParams(urls []string, lane string, metadata ingestMetadata) []river.InsertManyParams {
+	params := make([]river.InsertManyParams, 0, len(urls))
+	for _, url := range urls {
+		params = append(params, river.InsertManyParams{
+			Args:       URLIngestArgs{URL: url, OrgID: metadata.OrgID},
+			InsertOpts: &river.InsertOpts{Queue: lane, Metadata: metadata.encode()},
+		})
+	}
+	return params
+}
+
+// insertURLJobs inserts the ingest jobs of urls on a lane, urls already queued for the organization are skipped by river
+func insertURLJobs(ctx context.Context, svc services.Services, client *river.Client[pgx.Tx], urls []string, lane string, metadata ingestMetadata) ([]*rivertype.JobInsertResult, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	results, err := client.InsertMany(ctx, urlIngestParams(urls, lane, metadata))
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range results {
+		if !result.UniqueSkippedAsDuplicate {
+			svc.PublishProgress(ctx, models.IngestProgress{OrgID: metadata.OrgID, URL: urls[i], Stage: constants.IngestStageQueued})
+		}
+	}
+	return results, nil
+}
+
+// ingestJob is what the ui is shown of a river job row
+func ingestJob(row *rivertype.JobRow) models.IngestJob {
+	var args URLIngestArgs
+	json.Unmarshal(row.EncodedArgs, &args)
+
+	job := models.IngestJob{
+		ID:          row.ID,
+		URL:         args.URL,
+		State:       string(row.State),
+		Queue:       row.Queue,
+		Attempt:     row.Attempt,
+		MaxAttempts: row.MaxAttempts,
+		Errors:      make([]models.IngestJobError, 0, len(row.Errors)),
+		CreatedAt:   row.CreatedAt,
+		ScheduledAt: row.ScheduledAt,
+		AttemptedAt: row.AttemptedAt,
+		FinalizedAt: row.FinalizedAt,
+	}
+	var metadata struct {
+		Output *deferral `json:"output"`
+	}
+	if json.Unmarshal(row.Metadata, &metadata) == nil && metadata.Output != nil {
+		job.DeferredReason = metadata.Output.Reason
+		job.DeferredAt = &metadata.Output.At
+	}
+	for _, attemptErr := range row.Errors {
+		job.Errors = append(job.Errors, models.IngestJobError{
+			Attempt:      attemptErr.Attempt,
+			At:           attemptErr.At,
+			Error:        attemptErr.Error,
+			FailureClass: services.FailureClassOf(attemptErr.Error),
+		})
+	}
+	return job
+}
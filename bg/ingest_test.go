@@ -0,0 +1,169 @@
+package bg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/postgres"
+	"github.com/rajnandan1/smaraka/services"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertype"
+)
+
+func TestMain(m *testing.M) {
+	logger.StartLogger(constants.EnvDevelopment)
+	os.Exit(m.Run())
+}
+
+// ingestDB answers the fair share questions of the worker
+type ingestDB struct {
+	postgres.Postgres
+	paused  bool
+	orgs    int
+	running int
+}
+
+func (db *ingestDB) IsIngestPaused(ctx context.Context, orgID string) (bool, error) {
+	return db.paused, nil
+}
+
+func (db *ingestDB) CountIngestOrgs(ctx context.Context, queue string) (int, error) {
+	return db.orgs, nil
+}
+
+func (db *ingestDB) CountRunningIngestJobs(ctx context.Context, orgID, queue string) (int, error) {
+	return db.running, nil
+}
+
+// ingestService runs ingest instead of the real service
+type ingestService struct {
+	services.Services
+	ingest func(ctx context.Context) error
+}
+
+func (s *ingestService) IngestURL(ctx context.Context, url, orgId string) error {
+	return s.ingest(ctx)
+}
+
+func (s *ingestService) PublishProgress(ctx context.Context, event models.IngestProgress) {}
+
+// recordDeferrals keeps the deferrals a test's jobs record instead of river
+func recordDeferrals(t *testing.T) *[]deferral {
+	recorded := &[]deferral{}
+	recordOutput = func(ctx context.Context, output any) error {
+		*recorded = append(*recorded, output.(deferral))
+		return nil
+	}
+	t.Cleanup(func() { recordOutput = river.RecordOutput })
+	return recorded
+}
+
+func ingestJobOn(queue string) *river.Job[URLIngestArgs] {
+	return &river.Job[URLIngestArgs]{
+		JobRow: &rivertype.JobRow{Queue: queue, Attempt: 1, MaxAttempts: ingestMaxAttempts, CreatedAt: time.Now(), Metadata: []byte(`{}`)},
+		Args:   URLIngestArgs{URL: "https://example.com/page", OrgID: "org_1"},
+	}
+}
+
+func TestURLIngestWorkerRecordsSnoozeReasons(t *testing.T) {
+	tests := []struct {
+		name   string
+		db     *ingestDB
+		ingest func(ctx context.Context) error
+		reason string
+	}{
+		{
+			name:   "paused",
+			db:     &ingestDB{paused: true},
+			reason: "paused",
+		},
+		{
+			name:   "fair share",
+			db:     &ingestDB{orgs: 2, running: 3},
+			reason: "its share of 2 of the 4 workers",
+		},
+		{
+			name: "throttled",
+			db:   &ingestDB{orgs: 1, running: 1},
+			ingest: func(ctx context.Context) error {
+				return &services.ThrottledError{Host: "example.com", StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}
+			},
+			reason: "throttled us with status 429",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorded := recordDeferrals(t)
+			worker := &URLIngestWorker{
+				Service:    &ingestService{ingest: tt.ingest},
+				DB:         tt.db,
+				FairShared: map[string]int{LaneBulk: 4},
+			}
+
+			err := worker.Work(context.Background(), ingestJobOn(LaneBulk))
+			var snoozeErr *rivertype.JobSnoozeError
+			if !errors.As(err, &snoozeErr) {
+				t.Fatalf("Work = %v, want a snooze", err)
+			}
+			if len(*recorded) != 1 || !strings.Contains((*recorded)[0].Reason, tt.reason) {
+				t.Fatalf("recorded %+v, want one reason containing %q", *recorded, tt.reason)
+			}
+		})
+	}
+}
+
+func TestURLIngestWorkerRecordsHostDeferrals(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	recorded := recordDeferrals(t)
+
+	scheduler := services.NewCrawlScheduler(1, 0)
+	worker := &URLIngestWorker{
+		Service: &ingestService{ingest: func(ctx context.Context) error {
+			// another job holds the only slot on the host
+			release, err := scheduler.Wait(context.Background(), server.URL+"/first")
+			if err != nil {
+				return err
+			}
+			defer release()
+			waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			if _, err := scheduler.Wait(waitCtx, server.URL+"/second"); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("second Wait = %v, want it to time out waiting for the slot", err)
+			}
+			return nil
+		}},
+		DB: &ingestDB{},
+	}
+
+	if err := worker.Work(context.Background(), ingestJobOn(LaneInteractive)); err != nil {
+		t.Fatalf("Work: %v", err)
+	}
+	if len(*recorded) != 1 || !strings.Contains((*recorded)[0].Reason, "waiting for a free slot") {
+		t.Fatalf("recorded %+v, want the wait for a free slot", *recorded)
+	}
+}
+
+func TestIngestJobListsDeferral(t *testing.T) {
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	output, _ := json.Marshal(map[string]any{"output": deferral{Reason: "imports of the organization are paused", At: at}})
+	job := ingestJob(&rivertype.JobRow{EncodedArgs: []byte(`{"url":"https://example.com"}`), Metadata: output})
+	if job.DeferredReason != "imports of the organization are paused" || job.DeferredAt == nil || !job.DeferredAt.Equal(at) {
+		t.Fatalf("ingestJob = %+v", job)
+	}
+
+	job = ingestJob(&rivertype.JobRow{EncodedArgs: []byte(`{"url":"https://example.com"}`), Metadata: []byte(`{"org_id":"org_1"}`)})
+	if job.DeferredReason != "" || job.DeferredAt != nil {
+		t.Fatalf("ingestJob without deferral = %+v", job)
+	}
+}
@@ -2,12 +2,17 @@ package bg
 
 import (
 	"context"
-	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/postgres"
 	"github.com/rajnandan1/smaraka/services"
 	"github.com/riverqueue/river"
 )
 
+// URLStoreProcessArgs is a chunk of urls as they were queued before every url got its own job,
+// chunks still queued are split into ingest jobs
 type URLStoreProcessArgs struct {
 	URLs    []string `json:"urls"`
 	OrgUser string   `json:"org_user"`
@@ -21,8 +26,7 @@ type URLStoreProcessWorker struct {
 }
 
 func (w *URLStoreProcessWorker) Work(ctx context.Context, job *river.Job[URLStoreProcessArgs]) error {
-	err := w.Service.BulkLightAndFullJob(job.Args.URLs, job.Args.OrgUser)
-	fmt.Println("Job done")
+	_, err := insertURLJobs(ctx, w.Service, river.ClientFromContext[pgx.Tx](ctx), job.Args.URLs, LaneBulk, ingestMetadata{OrgID: job.Args.OrgUser})
 	return err
 }
 
@@ -39,7 +43,7 @@ func (PeriodicJobArgs) Kind() string { return "periodic" }
 
 func (w *PeriodicJobWorker) Work(ctx context.Context, job *river.Job[PeriodicJobArgs]) error {
 
-	fmt.Printf("Running %s job at %d\n", job.Kind, job.Args.Interval)
+	logger.LogDebug("Running periodic job", job.Kind, job.Args.Interval)
 
 	orgDataURLs, err := w.Service.RunSchedule(ctx, job.Args.Interval)
 	if err != nil {
@@ -47,10 +51,126 @@ func (w *PeriodicJobWorker) Work(ctx context.Context, job *river.Job[PeriodicJob
 		return err
 	}
 
-	//loop through the orgDataURLs and create a job for each
+	//loop through the orgDataURLs and create a job for each url
+	client := river.ClientFromContext[pgx.Tx](ctx)
 	for _, orgData := range *orgDataURLs {
-		w.Service.BulkLightAndFullJob(orgData.URLs, orgData.OrganizationID)
+		if _, err := insertURLJobs(ctx, w.Service, client, orgData.URLs, LaneSchedule, ingestMetadata{OrgID: orgData.OrganizationID}); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+type RecrawlArgs struct {
+	MaxAgeDays int `json:"max_age_days"`
+	BatchSize  int `json:"batch_size"`
+}
+
+func (RecrawlArgs) Kind() string { return "recrawl" }
+
+func (RecrawlArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{Queue: LaneRecrawl}
+}
+
+type RecrawlWorker struct {
+	river.WorkerDefaults[RecrawlArgs]
+	Service services.Services
+}
+
+func (w *RecrawlWorker) Timeout(job *river.Job[RecrawlArgs]) time.Duration {
+	return 30 * time.Minute
+}
+
+func (w *RecrawlWorker) Work(ctx context.Context, job *river.Job[RecrawlArgs]) error {
+	maxAge := time.Duration(job.Args.MaxAgeDays) * 24 * time.Hour
+	return w.Service.RecrawlStale(ctx, maxAge, job.Args.BatchSize)
+}
+
+type LinkCheckArgs struct {
+	MaxAgeDays int `json:"max_age_days"`
+	BatchSize  int `json:"batch_size"`
+}
+
+func (LinkCheckArgs) Kind() string { return "link_check" }
+
+type LinkCheckWorker struct {
+	river.WorkerDefaults[LinkCheckArgs]
+	Service services.Services
+}
+
+func (w *LinkCheckWorker) Timeout(job *river.Job[LinkCheckArgs]) time.Duration {
+	return 30 * time.Minute
+}
+
+func (w *LinkCheckWorker) Work(ctx context.Context, job *river.Job[LinkCheckArgs]) error {
+	maxAge := time.Duration(job.Args.MaxAgeDays) * 24 * time.Hour
+	return w.Service.CheckLinks(ctx, maxAge, job.Args.BatchSize)
+}
+
+type LanguageDetectArgs struct {
+	BatchSize int `json:"batch_size"`
+}
+
+func (LanguageDetectArgs) Kind() string { return "language_detect" }
+
+type LanguageDetectWorker struct {
+	river.WorkerDefaults[LanguageDetectArgs]
+	Service services.Services
+}
+
+func (w *LanguageDetectWorker) Timeout(job *river.Job[LanguageDetectArgs]) time.Duration {
+	return 30 * time.Minute
+}
+
+func (w *LanguageDetectWorker) Work(ctx context.Context, job *river.Job[LanguageDetectArgs]) error {
+	return w.Service.DetectLanguages(ctx, job.Args.BatchSize)
+}
+
+type SummarizeArgs struct {
+	URLID string `json:"url_id"`
+}
+
+func (SummarizeArgs) Kind() string { return "summarize" }
+
+type SummarizeWorker struct {
+	river.WorkerDefaults[SummarizeArgs]
+	Service services.Services
+}
+
+func (w *SummarizeWorker) Work(ctx context.Context, job *river.Job[SummarizeArgs]) error {
+	return w.Service.SummarizeURLStore(ctx, job.Args.URLID)
+}
+
+type SummarizePendingArgs struct {
+	BatchSize int `json:"batch_size"`
+}
+
+func (SummarizePendingArgs) Kind() string { return "summarize_pending" }
+
+type SummarizePendingWorker struct {
+	river.WorkerDefaults[SummarizePendingArgs]
+	Service services.Services
+}
+
+func (w *SummarizePendingWorker) Timeout(job *river.Job[SummarizePendingArgs]) time.Duration {
+	return 30 * time.Minute
+}
+
+func (w *SummarizePendingWorker) Work(ctx context.Context, job *river.Job[SummarizePendingArgs]) error {
+	return w.Service.SummarizePending(ctx, job.Args.BatchSize)
+}
+
+// IdempotencyKeyCleanupArgs deletes idempotency keys of saves older than postgres.IdempotencyKeyTTL
+type IdempotencyKeyCleanupArgs struct{}
+
+func (IdempotencyKeyCleanupArgs) Kind() string { return "idempotency_key_cleanup" }
+
+type IdempotencyKeyCleanupWorker struct {
+	river.WorkerDefaults[IdempotencyKeyCleanupArgs]
+	DB postgres.Postgres
+}
+
+func (w *IdempotencyKeyCleanupWorker) Work(ctx context.Context, job *river.Job[IdempotencyKeyCleanupArgs]) error {
+	return w.DB.DeleteExpiredIdempotencyKeys(ctx)
+}
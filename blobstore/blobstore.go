@@ -0,0 +1,67 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rajnandan1/smaraka/config"
+)
+
+var (
+	ErrNotFound      = errors.New("blob not found")
+	ErrQuotaExceeded = errors.New("storage quota exceeded")
+)
+
+// ObjectInfo describes a stored blob
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModifiedAt  time.Time
+}
+
+// Store keeps binary objects like page snapshots and thumbnails under a key
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// SignedURL returns a url anyone holding it can download the blob from until it expires
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// ConfigureStore builds the store selected in config, wrapped with the per organization quota
+func ConfigureStore(c config.Config, usage UsageTracker) (*QuotaStore, error) {
+	var store Store
+	var err error
+	switch strings.ToLower(c.BlobBackend) {
+	case "local":
+		// signed urls of local blobs are checked with a key of their own, leaking one must not leak the vault token
+		signingKey := c.BlobSigningKey
+		if signingKey == "" {
+			if signingKey, err = generatedSigningKey(c.BlobDir); err != nil {
+				return nil, err
+			}
+		}
+		store, err = NewLocalStore(c.BlobDir, signingKey)
+	case "s3":
+		store, err = NewS3Store(S3Options{
+			Endpoint:     c.S3Endpoint,
+			Region:       c.S3Region,
+			Bucket:       c.S3Bucket,
+			AccessKey:    c.S3AccessKey,
+			SecretKey:    c.S3SecretKey,
+			UsePathStyle: c.S3UsePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("invalid blob backend %q", c.BlobBackend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewQuotaStore(store, usage, int64(c.OrgBlobQuotaMB)<<20), nil
+}
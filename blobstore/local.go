@@ -0,0 +1,217 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalBlobPath is where signed urls of the local store are served
+const LocalBlobPath = "/blob/"
+
+// LocalStore keeps blobs as files below a root directory.
+// The content type is not stored, it is derived from the extension of the key
+type LocalStore struct {
+	Root       string
+	signingKey []byte
+}
+
+// generatedSigningKey returns the signing key kept next to the blob directory, creating it on first use.
+// It is used when SMARAKA_BLOB_SIGNING_KEY is not set, outside of the directory so it is never served as a blob
+func generatedSigningKey(root string) (string, error) {
+	path := filepath.Join(filepath.Dir(filepath.Clean(root)), "blob_signing_key")
+	if key, err := os.ReadFile(path); err == nil {
+		return strings.TrimSpace(string(key)), nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to read blob signing key: %v", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate blob signing key: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create blob signing key directory: %v", err)
+	}
+	// the key is linked in place once written, another process starting at the same time may have
+	// linked its key first, that one is kept
+	tmp, err := os.CreateTemp(filepath.Dir(path), "blob_signing_key-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create blob signing key: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	key := hex.EncodeToString(secret)
+	_, err = tmp.WriteString(key)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write blob signing key: %v", err)
+	}
+	if err := os.Link(tmp.Name(), path); errors.Is(err, fs.ErrExist) {
+		return generatedSigningKey(root)
+	} else if err != nil {
+		return "", fmt.Errorf("failed to store blob signing key: %v", err)
+	}
+	return key, nil
+}
+
+func NewLocalStore(root, signingKey string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %v", err)
+	}
+	return &LocalStore{
+		Root:       root,
+		signingKey: []byte(signingKey),
+	}, nil
+}
+
+// path maps a key to a file below root, keys must not escape it
+func (l *LocalStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(l.Root, filepath.FromSlash(clean)), nil
+}
+
+func (l *LocalStore) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %v", err)
+	}
+
+	// write to a temp file first so readers never see a partial blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write blob: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store blob: %v", err)
+	}
+	return nil
+}
+
+func (l *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %v", err)
+	}
+	return file, nil
+}
+
+func (l *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %v", err)
+	}
+	return nil
+}
+
+func (l *LocalStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat blob: %v", err)
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &ObjectInfo{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: contentType,
+		ModifiedAt:  info.ModTime(),
+	}, nil
+}
+
+func (l *LocalStore) sign(key string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expiresAt, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignedURL returns a path on this server, the local store serves it through ServeHTTP
+func (l *LocalStore) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if _, err := l.path(key); err != nil {
+		return "", err
+	}
+	expiresAt := time.Now().Add(expires).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt, 10))
+	query.Set("signature", l.sign(key, expiresAt))
+	return LocalBlobPath + key + "?" + query.Encode(), nil
+}
+
+// ServeHTTP serves blobs of signed urls, it expects LocalBlobPath to be stripped from the path
+func (l *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	expiresAt, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		http.Error(w, "link expired", http.StatusForbidden)
+		return
+	}
+	if !hmac.Equal([]byte(l.sign(key, expiresAt)), []byte(r.URL.Query().Get("signature"))) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	info, err := l.Stat(r.Context(), key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := l.Get(r.Context(), key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, filepath.Base(key), info.ModifiedAt, body.(io.ReadSeeker))
+}
@@ -0,0 +1,40 @@
+package blobstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rajnandan1/smaraka/config"
+)
+
+func TestConfigureStoreKeepsGeneratedSigningKey(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "blobs")
+	c := config.Config{BlobBackend: "local", BlobDir: dir, OrgBlobQuotaMB: 1}
+
+	first, err := ConfigureStore(c, nil)
+	if err != nil {
+		t.Fatalf("ConfigureStore without a signing key: %v", err)
+	}
+	second, err := ConfigureStore(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstKey := string(first.Store.(*LocalStore).signingKey)
+	if len(firstKey) != 64 || string(second.Store.(*LocalStore).signingKey) != firstKey {
+		t.Fatalf("signing keys %q and %q, want one generated key kept across starts", firstKey, second.Store.(*LocalStore).signingKey)
+	}
+	// the key lives next to the blobs, a blob key can never reach it
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "blob_signing_key")); err != nil {
+		t.Fatalf("generated key not kept next to the blob directory: %v", err)
+	}
+
+	c.BlobSigningKey = "configured"
+	configured, err := ConfigureStore(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(configured.Store.(*LocalStore).signingKey); got != "configured" {
+		t.Fatalf("signing key = %q, want the configured one", got)
+	}
+}
@@ -0,0 +1,113 @@
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// UsageTracker records which organization owns which blob and how large it is
+type UsageTracker interface {
+	// GetOrgBlobUsage sums the blobs of an organization, leaving out exceptKey which is about to be overwritten
+	GetOrgBlobUsage(ctx context.Context, orgID, exceptKey string) (int64, error)
+	// ChargeBlobObject records the size of a blob unless the organization would use more than limit, atomically
+	ChargeBlobObject(ctx context.Context, key, orgID string, size, limit int64) (bool, error)
+	DeleteBlobObject(ctx context.Context, key string) error
+}
+
+type orgKey struct{}
+
+// WithOrg charges blobs written with ctx to the organization
+func WithOrg(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, orgKey{}, orgID)
+}
+
+// OrgFromContext returns the organization set by WithOrg
+func OrgFromContext(ctx context.Context) string {
+	orgID, _ := ctx.Value(orgKey{}).(string)
+	return orgID
+}
+
+// QuotaStore enforces a storage limit per organization on top of another store.
+// Blobs written without an organization in the context, like recrawl snapshots, are not charged
+type QuotaStore struct {
+	Store
+	Usage UsageTracker
+	Limit int64
+}
+
+func NewQuotaStore(store Store, usage UsageTracker, limit int64) *QuotaStore {
+	return &QuotaStore{
+		Store: store,
+		Usage: usage,
+		Limit: limit,
+	}
+}
+
+func (q *QuotaStore) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	orgID := OrgFromContext(ctx)
+	if orgID == "" || q.Usage == nil {
+		return q.Store.Put(ctx, key, body, contentType)
+	}
+
+	// the blob is streamed, reading stops one byte past the room the organization has left
+	var room int64 = -1
+	if q.Limit > 0 {
+		used, err := q.Usage.GetOrgBlobUsage(ctx, orgID, key)
+		if err != nil {
+			return err
+		}
+		if room = q.Limit - used; room < 0 {
+			return ErrQuotaExceeded
+		}
+		body = io.LimitReader(body, room+1)
+	}
+	counted := &countingReader{r: body}
+	if err := q.Store.Put(ctx, key, counted, contentType); err != nil {
+		return err
+	}
+
+	// concurrent writes of the organization may have used the room meanwhile, the charge decides
+	charged := room < 0 || counted.n <= room
+	if charged {
+		var err error
+		if charged, err = q.Usage.ChargeBlobObject(ctx, key, orgID, counted.n, q.Limit); err != nil {
+			return err
+		}
+	}
+	if !charged {
+		// whatever the key held before was overwritten, its record goes with it
+		if err := q.Delete(context.WithoutCancel(ctx), key); err != nil {
+			return fmt.Errorf("failed to delete blob over quota: %v", err)
+		}
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (q *QuotaStore) Delete(ctx context.Context, key string) error {
+	if err := q.Store.Delete(ctx, key); err != nil {
+		return err
+	}
+	if q.Usage == nil {
+		return nil
+	}
+	return q.Usage.DeleteBlobObject(ctx, key)
+}
+
+// Unwrap returns the store the quota is enforced on
+func (q *QuotaStore) Unwrap() Store {
+	return q.Store
+}
@@ -0,0 +1,143 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// memoryUsage tracks blob sizes in memory, charges are taken under one lock like the advisory lock of postgres
+type memoryUsage struct {
+	mu    sync.Mutex
+	sizes map[string]int64
+}
+
+func (m *memoryUsage) used(exceptKey string) int64 {
+	var used int64
+	for key, size := range m.sizes {
+		if key != exceptKey {
+			used += size
+		}
+	}
+	return used
+}
+
+func (m *memoryUsage) GetOrgBlobUsage(ctx context.Context, orgID, exceptKey string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.used(exceptKey), nil
+}
+
+func (m *memoryUsage) ChargeBlobObject(ctx context.Context, key, orgID string, size, limit int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if limit > 0 && m.used(key)+size > limit {
+		return false, nil
+	}
+	m.sizes[key] = size
+	return true, nil
+}
+
+func (m *memoryUsage) DeleteBlobObject(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sizes, key)
+	return nil
+}
+
+// endlessReader is a body that never ends, counting what was read of it
+type endlessReader struct {
+	n int64
+}
+
+func (e *endlessReader) Read(p []byte) (int, error) {
+	e.n += int64(len(p))
+	return len(p), nil
+}
+
+func newQuotaStore(t *testing.T, limit int64) (*QuotaStore, *memoryUsage) {
+	local, err := NewLocalStore(t.TempDir(), "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := &memoryUsage{sizes: make(map[string]int64)}
+	return NewQuotaStore(local, usage, limit), usage
+}
+
+func TestQuotaStoreStopsReadingPastTheRoomLeft(t *testing.T) {
+	store, usage := newQuotaStore(t, 1000)
+	ctx := WithOrg(context.Background(), "org_1")
+	if err := store.Put(ctx, "snapshots/a.mhtml", bytes.NewReader(make([]byte, 600)), "multipart/related"); err != nil {
+		t.Fatalf("Put within quota: %v", err)
+	}
+
+	body := &endlessReader{}
+	if err := store.Put(ctx, "snapshots/b.mhtml", body, "multipart/related"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Put past quota = %v, want ErrQuotaExceeded", err)
+	}
+	// reads are buffered, what matters is that the body is not read to its end
+	if body.n > 64<<10 {
+		t.Fatalf("read %d bytes of a body that could never fit in 400", body.n)
+	}
+	if _, err := store.Get(ctx, "snapshots/b.mhtml"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("blob over quota kept: %v", err)
+	}
+	if used, _ := usage.GetOrgBlobUsage(ctx, "org_1", ""); used != 600 {
+		t.Fatalf("usage = %d, want 600", used)
+	}
+}
+
+func TestQuotaStoreConcurrentPutsStayWithinQuota(t *testing.T) {
+	store, usage := newQuotaStore(t, 100)
+	ctx := WithOrg(context.Background(), "org_1")
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = store.Put(ctx, fmt.Sprintf("thumbnails/%d.jpg", i), bytes.NewReader(make([]byte, 30)), "image/jpeg")
+		}()
+	}
+	wg.Wait()
+
+	stored := 0
+	for i, err := range errs {
+		key := fmt.Sprintf("thumbnails/%d.jpg", i)
+		switch {
+		case err == nil:
+			stored++
+		case errors.Is(err, ErrQuotaExceeded):
+			if reader, err := store.Get(ctx, key); err == nil {
+				reader.Close()
+				t.Errorf("%s was refused but kept", key)
+			}
+		default:
+			t.Fatalf("Put %s: %v", key, err)
+		}
+	}
+	if used, _ := usage.GetOrgBlobUsage(ctx, "org_1", ""); used > 100 || stored != 3 {
+		t.Fatalf("stored %d blobs using %d bytes, want 3 within 100", stored, used)
+	}
+}
+
+func TestQuotaStoreWithoutOrgIsNotCharged(t *testing.T) {
+	store, usage := newQuotaStore(t, 10)
+	if err := store.Put(context.Background(), "snapshots/recrawl.mhtml", bytes.NewReader(make([]byte, 100)), "multipart/related"); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := store.Get(context.Background(), "snapshots/recrawl.mhtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if len(data) != 100 || len(usage.sizes) != 0 {
+		t.Fatalf("stored %d bytes, charged %v", len(data), usage.sizes)
+	}
+}
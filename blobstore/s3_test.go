@@ -0,0 +1,218 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 serves a path style bucket like MinIO does and rejects requests whose signature does not match
+type fakeS3 struct {
+	t       *testing.T
+	store   *S3Store
+	bucket  string
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	body        []byte
+	contentType string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f.verify(r); err != nil {
+		f.t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if !ok {
+		http.Error(w, "no such bucket", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if got := r.Header.Get("X-Amz-Content-Sha256"); got != hex.EncodeToString(sum[:]) {
+			http.Error(w, "content sha256 mismatch", http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = fakeObject{body: body, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet, http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "no such key", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", strconv.Itoa(len(object.body)))
+			return
+		}
+		w.Write(object.body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// verify recomputes the signature of a request from what the server received
+func (f *fakeS3) verify(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, s3Algorithm+" ") {
+		return errors.New("missing signature v4 authorization")
+	}
+	var signedHeaders, signature string
+	for _, part := range strings.Split(strings.TrimPrefix(auth, s3Algorithm+" "), ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		switch name {
+		case "SignedHeaders":
+			signedHeaders = value
+		case "Signature":
+			signature = value
+		}
+	}
+	now, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+
+	names := strings.Split(signedHeaders, ";")
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		s3EncodePath(r.URL.Path),
+		s3CanonicalQuery(r.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	if want := f.store.signature(now, canonicalRequest); want != signature {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func newFakeS3(t *testing.T) (*S3Store, *fakeS3) {
+	fake := &fakeS3{t: t, bucket: "smaraka", objects: make(map[string]fakeObject)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Store(S3Options{
+		Endpoint:     server.URL,
+		Region:       "us-east-1",
+		Bucket:       fake.bucket,
+		AccessKey:    "minioadmin",
+		SecretKey:    "minioadmin",
+		UsePathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.store = store
+	return store, fake
+}
+
+// testStoreRoundTrip puts, reads, stats and deletes a blob with a key that needs encoding
+func testStoreRoundTrip(t *testing.T, store Store) {
+	ctx := context.Background()
+	key := "snapshots/url_1/page one+two.mhtml"
+	body := []byte("MIME-Version: 1.0\r\n\r\nsnapshot")
+
+	if err := store.Put(ctx, key, bytes.NewReader(body), "multipart/related"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	reader, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(got, body) {
+		t.Fatalf("Get = %q, want %q", got, body)
+	}
+	info, err := store.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != int64(len(body)) || info.ContentType != "multipart/related" {
+		t.Fatalf("Stat = %+v", info)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	// deleting what is gone already is not an error
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete again: %v", err)
+	}
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	store, _ := newFakeS3(t)
+	testStoreRoundTrip(t, store)
+}
+
+func TestS3StoreSignedURL(t *testing.T) {
+	store, _ := newFakeS3(t)
+	signed, err := store.SignedURL(context.Background(), "thumbnails/url_1/large.jpg", 30*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, param := range []string{"X-Amz-Algorithm=AWS4-HMAC-SHA256", "X-Amz-Expires=604800", "X-Amz-SignedHeaders=host", "X-Amz-Signature="} {
+		if !strings.Contains(signed, param) {
+			t.Errorf("signed url %s has no %s", signed, param)
+		}
+	}
+	if !strings.Contains(signed, "/smaraka/thumbnails/url_1/large.jpg?") {
+		t.Errorf("signed url %s is not path style", signed)
+	}
+}
+
+// TestS3StoreMinIO runs against a real MinIO when SMARAKA_TEST_S3_ENDPOINT is set, the bucket has to exist
+func TestS3StoreMinIO(t *testing.T) {
+	endpoint := os.Getenv("SMARAKA_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("SMARAKA_TEST_S3_ENDPOINT is not set")
+	}
+	store, err := NewS3Store(S3Options{
+		Endpoint:     endpoint,
+		Region:       os.Getenv("SMARAKA_TEST_S3_REGION"),
+		Bucket:       os.Getenv("SMARAKA_TEST_S3_BUCKET"),
+		AccessKey:    os.Getenv("SMARAKA_TEST_S3_ACCESS_KEY"),
+		SecretKey:    os.Getenv("SMARAKA_TEST_S3_SECRET_KEY"),
+		UsePathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStoreRoundTrip(t, store)
+}
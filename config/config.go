@@ -6,12 +6,17 @@ import (
 	"strconv"
 
 	"github.com/joho/godotenv"
+	"github.com/rajnandan1/smaraka/constants"
 )
 
 type Config struct {
 	Port                    int
 	Environment             string
+	Mode                    string
 	MaxWorkers              int
+	InteractiveWorkers      int
+	ScheduleWorkers         int
+	RecrawlWorkers          int
 	GracefulShutDownTimeout int
 	VaultToken              string
 	SessionTimeout          int
@@ -24,23 +29,73 @@ type Config struct {
 
 	FrontBasePath  string
 	SearchAffinity int
+
+	FetchStrategy string
+	FetchRules    string
+	BrowserTabs   int
+	// BrowserReservedTabs are kept for single saves, imports never hold every tab
+	BrowserReservedTabs int
+	PageTimeout         int
+	// MaxFetchMB bounds the body of a plain http fetch, pdfs included
+	MaxFetchMB int
+
+	// job rows are the only record of ingestion, finished ones are kept this many days
+	CompletedJobRetentionDays int
+	FailedJobRetentionDays    int
+
+	CrawlHostConcurrency int
+	CrawlMinDelayMs      int
+	RecrawlDays          int
+	LinkCheckDays        int
+
+	// BlobSigningKey signs urls of the local blob store, when empty a key is generated once and kept next to BlobDir
+	BlobBackend    string
+	BlobDir        string
+	BlobSigningKey string
+	OrgBlobQuotaMB int
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3UsePathStyle bool
 }
 
 func LoadConfig() (*Config, error) {
 	godotenv.Load()
 	port, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_PORT", "1323"))
 	maxWorkers, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_MAX_WORKERS", "10"))
+	interactiveWorkers, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_INTERACTIVE_WORKERS", "4"))
+	scheduleWorkers, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_SCHEDULE_WORKERS", "2"))
+	recrawlWorkers, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_RECRAWL_WORKERS", "1"))
+	completedJobRetentionDays, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_COMPLETED_JOB_RETENTION_DAYS", "30"))
+	failedJobRetentionDays, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_FAILED_JOB_RETENTION_DAYS", "365"))
 	shutdownTimeout, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_GRACE_TIMEOUT", "60"))
 	dbPort, _ := strconv.Atoi(requireEnv("SMARAKA_PG_PORT"))
 	sessionTimeout, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_TIMEOUT_MINUTES", "262800"))
 	searchAffinity, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_SEARCH_AFFINITY", "60"))
+	browserTabs, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_BROWSER_TABS", "4"))
+	browserReservedTabs, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_BROWSER_RESERVED_TABS", "1"))
+	pageTimeout, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_PAGE_TIMEOUT", "45"))
+	maxFetchMB, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_MAX_FETCH_MB", "50"))
+	crawlHostConcurrency, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_CRAWL_HOST_CONCURRENCY", "2"))
+	crawlMinDelayMs, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_CRAWL_MIN_DELAY_MS", "1000"))
+	recrawlDays, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_RECRAWL_DAYS", "7"))
+	linkCheckDays, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_LINK_CHECK_DAYS", "7"))
+	orgBlobQuotaMB, _ := strconv.Atoi(getEnvOrDefault("SMARAKA_ORG_BLOB_QUOTA_MB", "1024"))
+	s3UsePathStyle, _ := strconv.ParseBool(getEnvOrDefault("SMARAKA_S3_PATH_STYLE", "true"))
+	vaultToken := requireEnv("SMARAKA_VAULT_TOKEN")
 
 	config := &Config{
 		Port:                    port,
 		Environment:             getEnvOrDefault("SMARAKA_ENV", "development"),
+		Mode:                    getEnvOrDefault("SMARAKA_MODE", constants.ModeAll),
 		MaxWorkers:              maxWorkers,
+		InteractiveWorkers:      interactiveWorkers,
+		ScheduleWorkers:         scheduleWorkers,
+		RecrawlWorkers:          recrawlWorkers,
 		GracefulShutDownTimeout: shutdownTimeout,
-		VaultToken:              requireEnv("SMARAKA_VAULT_TOKEN"),
+		VaultToken:              vaultToken,
 		SessionTimeout:          sessionTimeout,
 
 		PostgresUser:     requireEnv("SMARAKA_PG_USER"),
@@ -51,6 +106,36 @@ func LoadConfig() (*Config, error) {
 
 		FrontBasePath:  getEnvOrDefault("PUBLIC_SMARAKA_FRONT_BASE", "/app"),
 		SearchAffinity: searchAffinity,
+
+		FetchStrategy:       getEnvOrDefault("SMARAKA_FETCH_STRATEGY", "CHROME"),
+		FetchRules:          getEnvOrDefault("SMARAKA_FETCH_RULES", ""),
+		BrowserTabs:         browserTabs,
+		BrowserReservedTabs: browserReservedTabs,
+		PageTimeout:         pageTimeout,
+		MaxFetchMB:          maxFetchMB,
+
+		CompletedJobRetentionDays: completedJobRetentionDays,
+		FailedJobRetentionDays:    failedJobRetentionDays,
+
+		CrawlHostConcurrency: crawlHostConcurrency,
+		CrawlMinDelayMs:      crawlMinDelayMs,
+		RecrawlDays:          recrawlDays,
+		LinkCheckDays:        linkCheckDays,
+
+		BlobBackend:    getEnvOrDefault("SMARAKA_BLOB_BACKEND", "local"),
+		BlobDir:        getEnvOrDefault("SMARAKA_BLOB_DIR", "./data/blobs"),
+		BlobSigningKey: getEnvOrDefault("SMARAKA_BLOB_SIGNING_KEY", ""),
+		OrgBlobQuotaMB: orgBlobQuotaMB,
+		S3Endpoint:     getEnvOrDefault("SMARAKA_S3_ENDPOINT", ""),
+		S3Region:       getEnvOrDefault("SMARAKA_S3_REGION", "us-east-1"),
+		S3Bucket:       getEnvOrDefault("SMARAKA_S3_BUCKET", ""),
+		S3AccessKey:    getEnvOrDefault("SMARAKA_S3_ACCESS_KEY", ""),
+		S3SecretKey:    getEnvOrDefault("SMARAKA_S3_SECRET_KEY", ""),
+		S3UsePathStyle: s3UsePathStyle,
+	}
+
+	if err := config.SetMode(config.Mode); err != nil {
+		return nil, err
 	}
 
 	return config, nil
@@ -70,6 +155,36 @@ func requireEnv(key string) string {
 	}
 	return value
 }
+
+// SetMode sets the mode the process runs in, the server, the workers or both
+func (c *Config) SetMode(mode string) error {
+	switch mode {
+	case constants.ModeServer, constants.ModeWorker, constants.ModeAll:
+		c.Mode = mode
+		return nil
+	}
+	return fmt.Errorf("invalid mode %q, expected %s, %s or %s", mode, constants.ModeServer, constants.ModeWorker, constants.ModeAll)
+}
+
+// SetModeFromArgs lets a subcommand, smaraka server, smaraka worker or smaraka all, override SMARAKA_MODE.
+// args are the arguments after the program name
+func (c *Config) SetModeFromArgs(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	return c.SetMode(args[0])
+}
+
+// RunsServer tells if the process serves the api
+func (c *Config) RunsServer() bool {
+	return c.Mode != constants.ModeWorker
+}
+
+// RunsWorkers tells if the process works jobs, a process that does not only inserts them
+func (c *Config) RunsWorkers() bool {
+	return c.Mode != constants.ModeServer
+}
+
 func (c *Config) GetPostgresURL() string {
 	return fmt.Sprintf("postgresql://%s:%s@%s:%d/%s?sslmode=disable",
 		c.PostgresUser,
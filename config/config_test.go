@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/rajnandan1/smaraka/constants"
+)
+
+func TestModeSelection(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         string
+		args        []string
+		mode        string
+		server      bool
+		workers     bool
+		invalidEnv  bool
+		invalidArgs bool
+	}{
+		{name: "default", mode: constants.ModeAll, server: true, workers: true},
+		{name: "env server", env: "server", mode: constants.ModeServer, server: true},
+		{name: "env worker", env: "worker", mode: constants.ModeWorker, workers: true},
+		{name: "subcommand", args: []string{"worker"}, mode: constants.ModeWorker, workers: true},
+		{name: "subcommand overrides env", env: "worker", args: []string{"server", "--ignored"}, mode: constants.ModeServer, server: true},
+		{name: "subcommand all", env: "server", args: []string{"all"}, mode: constants.ModeAll, server: true, workers: true},
+		{name: "invalid env", env: "both", invalidEnv: true},
+		{name: "invalid subcommand", args: []string{"serve"}, invalidArgs: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"SMARAKA_PG_PORT", "SMARAKA_VAULT_TOKEN", "SMARAKA_PG_USER", "SMARAKA_PG_PASS", "SMARAKA_PG_HOST", "SMARAKA_PG_DB"} {
+				t.Setenv(key, "5432")
+			}
+			t.Setenv("SMARAKA_MODE", tt.env)
+
+			config, err := LoadConfig()
+			if tt.invalidEnv {
+				if err == nil {
+					t.Fatalf("LoadConfig with SMARAKA_MODE=%q succeeded", tt.env)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = config.SetModeFromArgs(tt.args)
+			if tt.invalidArgs {
+				if err == nil {
+					t.Fatalf("SetModeFromArgs(%q) succeeded", tt.args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Mode != tt.mode || config.RunsServer() != tt.server || config.RunsWorkers() != tt.workers {
+				t.Fatalf("mode %q, server %v, workers %v, want %q, %v, %v", config.Mode, config.RunsServer(), config.RunsWorkers(), tt.mode, tt.server, tt.workers)
+			}
+		})
+	}
+}
@@ -46,4 +46,19 @@ const (
 
 	ERRORMSG_INVALID_PASSWORD  = "Invalid password"
 	ERRORCODE_INVALID_PASSWORD = "ERROR_INVALID_PASSWORD"
+
+	ERRORMSG_SNAPSHOT_NOT_FOUND  = "Snapshot not found"
+	ERRORCODE_SNAPSHOT_NOT_FOUND = "ERROR_SNAPSHOT_NOT_FOUND"
+
+	ERRORMSG_DOCUMENT_NOT_FOUND  = "Document not found"
+	ERRORCODE_DOCUMENT_NOT_FOUND = "ERROR_DOCUMENT_NOT_FOUND"
+
+	ERRORMSG_IMPORT_NOT_FOUND  = "Import not found"
+	ERRORCODE_IMPORT_NOT_FOUND = "ERROR_IMPORT_NOT_FOUND"
+
+	ERRORMSG_IDEMPOTENCY_KEY_REUSED  = "Idempotency key was used for another url"
+	ERRORCODE_IDEMPOTENCY_KEY_REUSED = "ERROR_IDEMPOTENCY_KEY_REUSED"
+
+	ERRORMSG_IDEMPOTENCY_KEY_IN_FLIGHT  = "A save with this idempotency key is in progress"
+	ERRORCODE_IDEMPOTENCY_KEY_IN_FLIGHT = "ERROR_IDEMPOTENCY_KEY_IN_FLIGHT"
 )
@@ -20,13 +20,26 @@ const (
 	JobStatusPending  = "PENDING"
 	JobStatusComplete = "COMPLETE"
 
-	//JobQueue Status
-	JobQueueStatusPending    = "PENDING"
-	JobQueueStatusProcessing = "PROCESSING"
-	JobQueueStatusComplete   = "COMPLETE"
-	JobQueueStatusDeclined   = "DECLINED"
-	JobQueueStatusFailed     = "FAILED"
-	JobQueueStatusQueued     = "QUEUED"
+	//IngestStages a url moves through while it is saved, reported to the ui as it happens
+	IngestStageQueued    = "queued"
+	IngestStageFetching  = "fetching"
+	IngestStageExtracted = "extracted"
+	IngestStageIndexed   = "indexed"
+	IngestStageFailed    = "failed"
+
+	//ImportOutcomes of the urls of an import batch
+	ImportOutcomePending        = "PENDING"
+	ImportOutcomeAdded          = "ADDED"
+	ImportOutcomeAlreadyPresent = "ALREADY_PRESENT"
+	ImportOutcomeInvalid        = "INVALID"
+	ImportOutcomeFailed         = "FAILED"
+	ImportOutcomeCancelled      = "CANCELLED"
+
+	//ImportSources urls are imported from
+	ImportSourceAPI      = "api"
+	ImportSourceURLs     = "urls"
+	ImportSourceBrowsers = "browsers"
+	ImportSourceGithub   = "github"
 
 	PrefixDatabaseUser    = "user"
 	PrefixDatabaseOrg     = "org"
@@ -34,6 +47,21 @@ const (
 	PrefixDatabaseUserOrg = "user_org"
 	PrefixDatabaseURLOrg  = "url_org"
 
+	//FetchStrategies
+	FetchStrategyHTTP           = "HTTP"
+	FetchStrategyChrome         = "CHROME"
+	FetchStrategyChromeFallback = "CHROME_FALLBACK"
+
+	//LinkStates
+	LinkStateOK            = "OK"
+	LinkStateBroken        = "BROKEN"
+	LinkStateRedirectHome  = "REDIRECT_HOME"
+	LinkStateDomainChanged = "DOMAIN_CHANGED"
+	LinkStateUnreachable   = "UNREACHABLE"
+
+	//CrawlerAgentToken is matched against User-agent lines of robots.txt
+	CrawlerAgentToken = "Smaraka"
+
 	//HeadlessUserAgent
 	HeadlessUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/127.0.0.0 Safari/537.36"
 
@@ -41,6 +69,11 @@ const (
 	EnvDevelopment = "development"
 	EnvProduction  = "production"
 
+	//Modes a process runs in, workers fetch with chrome and can be scaled apart from the api
+	ModeServer = "server"
+	ModeWorker = "worker"
+	ModeAll    = "all"
+
 	//SecretStatus
 	SecretStatusActive   = "ACTIVE"
 	SecretStatusInactive = "INACTIVE"
@@ -0,0 +1,41 @@
+package extractors
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// ArXiv reads abstract pages, the pdfs themselves go through the pdf extraction
+var ArXiv = &Extractor{
+	Kind:    KindArXiv,
+	Hosts:   []string{"arxiv.org", "www.arxiv.org", "export.arxiv.org"},
+	Path:    regexp.MustCompile(`^/abs/`),
+	Extract: extractArXiv,
+}
+
+func extractArXiv(u *url.URL, doc *goquery.Document) *models.SiteMetadata {
+	paper := &models.ArXivMetadata{
+		ID:             meta(doc, "citation_arxiv_id"),
+		Authors:        metaAll(doc, "citation_author"),
+		Abstract:       meta(doc, "citation_abstract"),
+		Published:      meta(doc, "citation_date"),
+		PrimarySubject: text(doc.Find(".primary-subject").First()),
+		PDFURL:         meta(doc, "citation_pdf_url"),
+	}
+	if paper.ID == "" {
+		paper.ID = strings.TrimPrefix(u.Path, "/abs/")
+	}
+	if paper.Abstract == "" {
+		abstract := doc.Find("blockquote.abstract").First()
+		abstract.Find(".descriptor").Remove()
+		paper.Abstract = text(abstract)
+	}
+	if len(paper.Authors) == 0 && paper.Abstract == "" {
+		return nil
+	}
+	return &models.SiteMetadata{ArXiv: paper}
+}
@@ -0,0 +1,182 @@
+package extractors
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/rajnandan1/smaraka/models"
+	"golang.org/x/net/html"
+)
+
+const (
+	KindGitHub        = "GITHUB"
+	KindYouTube       = "YOUTUBE"
+	KindArXiv         = "ARXIV"
+	KindStackOverflow = "STACKOVERFLOW"
+	KindWikipedia     = "WIKIPEDIA"
+
+	// long texts like readmes and answers are cut to this many characters
+	maxTextLength = 5000
+)
+
+// Extractor pulls structured metadata out of the pages of one site
+type Extractor struct {
+	Kind string
+	// Hosts are matched like fetch rules, "*.wikipedia.org" matches wikipedia.org and every subdomain
+	Hosts []string
+	// Path must match the path of the url, nil matches every page of the hosts
+	Path *regexp.Regexp
+	// Extract returns nil when the page does not have what the extractor looks for
+	Extract func(u *url.URL, doc *goquery.Document) *models.SiteMetadata
+}
+
+func (e *Extractor) Matches(u *url.URL) bool {
+	if e.Path != nil && !e.Path.MatchString(u.Path) {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range e.Hosts {
+		if strings.HasPrefix(pattern, "*.") {
+			base := strings.TrimPrefix(pattern, "*.")
+			if host == base || strings.HasSuffix(host, "."+base) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry holds extractors, the first one matching a url is used
+type Registry struct {
+	extractors []*Extractor
+}
+
+func NewRegistry(extractors ...*Extractor) *Registry {
+	return &Registry{
+		extractors: extractors,
+	}
+}
+
+func (r *Registry) Register(extractor *Extractor) {
+	r.extractors = append(r.extractors, extractor)
+}
+
+// Extract runs the extractor registered for the url on its html, nil means no extractor matched or it found nothing
+func (r *Registry) Extract(rawURL, html string) *models.SiteMetadata {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	for _, extractor := range r.extractors {
+		if !extractor.Matches(u) {
+			continue
+		}
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			return nil
+		}
+		metadata := extractor.Extract(u, doc)
+		if metadata != nil {
+			metadata.Kind = extractor.Kind
+		}
+		return metadata
+	}
+	return nil
+}
+
+// Default knows the sites we have extractors for
+var Default = NewRegistry(
+	GitHub,
+	YouTube,
+	ArXiv,
+	StackOverflow,
+	Wikipedia,
+)
+
+// text returns the whitespace collapsed text of a selection
+func text(sel *goquery.Selection) string {
+	return strings.Join(strings.Fields(sel.Text()), " ")
+}
+
+// blockText is text for longer content like readmes and answers, elements are kept apart by spaces
+// so a heading does not run into the paragraph below it
+func blockText(sel *goquery.Selection) string {
+	var b strings.Builder
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		switch node.Type {
+		case html.TextNode:
+			b.WriteString(node.Data)
+		case html.ElementNode:
+			if node.Data == "script" || node.Data == "style" {
+				return
+			}
+			b.WriteString(" ")
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	for _, node := range sel.Nodes {
+		walk(node)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// truncate cuts s to maxTextLength characters
+func truncate(s string) string {
+	if runes := []rune(s); len(runes) > maxTextLength {
+		return string(runes[:maxTextLength]) + "…"
+	}
+	return s
+}
+
+// meta returns the content of the first meta tag with the name, property or itemprop
+func meta(doc *goquery.Document, name string) string {
+	sel := doc.Find(`meta[name="` + name + `"], meta[property="` + name + `"], meta[itemprop="` + name + `"]`).First()
+	return strings.TrimSpace(sel.AttrOr("content", ""))
+}
+
+// metaAll returns the content of every meta tag with the name
+func metaAll(doc *goquery.Document, name string) []string {
+	values := make([]string, 0)
+	doc.Find(`meta[name="` + name + `"]`).Each(func(i int, s *goquery.Selection) {
+		if content := strings.TrimSpace(s.AttrOr("content", "")); content != "" {
+			values = append(values, content)
+		}
+	})
+	return values
+}
+
+// texts returns the text of every element of a selection, empty ones left out
+func texts(sel *goquery.Selection) []string {
+	values := make([]string, 0)
+	sel.Each(func(i int, s *goquery.Selection) {
+		if t := text(s); t != "" {
+			values = append(values, t)
+		}
+	})
+	return values
+}
+
+// parseCount reads counts as sites print them, "12,345", "1.2k" or "3M"
+func parseCount(s string) int {
+	s = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(s, ",", "")))
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier, s = 1e3, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "m"):
+		multiplier, s = 1e6, strings.TrimSuffix(s, "m")
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return int(n * multiplier)
+}
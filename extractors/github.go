@@ -0,0 +1,72 @@
+package extractors
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// GitHub reads repository pages, github.com/<owner>/<repo>
+var GitHub = &Extractor{
+	Kind:    KindGitHub,
+	Hosts:   []string{"github.com"},
+	Path:    regexp.MustCompile(`^/[^/]+/[^/]+/?$`),
+	Extract: extractGitHub,
+}
+
+// paths on github.com that look like repositories but are not
+var githubReservedOwners = map[string]bool{
+	"orgs": true, "settings": true, "marketplace": true, "topics": true, "collections": true,
+	"sponsors": true, "features": true, "apps": true, "login": true, "notifications": true,
+}
+
+func extractGitHub(u *url.URL, doc *goquery.Document) *models.SiteMetadata {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if githubReservedOwners[strings.ToLower(parts[0])] {
+		return nil
+	}
+
+	repo := &models.GitHubMetadata{
+		Owner:       parts[0],
+		Repo:        parts[1],
+		Description: meta(doc, "og:description"),
+		Topics:      texts(doc.Find("a.topic-tag")),
+		Readme:      truncate(blockText(doc.Find("article.markdown-body").First())),
+	}
+	if about := text(doc.Find(".BorderGrid-cell p.f4").First()); about != "" {
+		repo.Description = about
+	}
+
+	// the counters carry the exact number in their title, the text is abbreviated
+	if stars := doc.Find("#repo-stars-counter-star").First(); stars.Length() > 0 {
+		repo.Stars = parseCount(stars.AttrOr("title", text(stars)))
+	}
+	if forks := doc.Find("#repo-network-counter").First(); forks.Length() > 0 {
+		repo.Forks = parseCount(forks.AttrOr("title", text(forks)))
+	}
+
+	// the languages list of the sidebar is ordered by share, the first is the main language
+	doc.Find(".BorderGrid-cell h2").EachWithBreak(func(i int, h *goquery.Selection) bool {
+		if !strings.EqualFold(text(h), "Languages") {
+			return true
+		}
+		repo.Language = text(h.Parent().Find("li span.text-bold").First())
+		return false
+	})
+	if repo.Language == "" {
+		repo.Language = text(doc.Find(`[itemprop="programmingLanguage"]`).First())
+	}
+
+	doc.Find(".BorderGrid-cell a").EachWithBreak(func(i int, a *goquery.Selection) bool {
+		if a.Find("svg.octicon-law").Length() == 0 {
+			return true
+		}
+		repo.License = strings.TrimSuffix(text(a), " license")
+		return false
+	})
+
+	return &models.SiteMetadata{GitHub: repo}
+}
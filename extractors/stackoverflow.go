@@ -0,0 +1,57 @@
+package extractors
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+var stackOverflowQuestionRe = regexp.MustCompile(`^/questions/(\d+)`)
+
+// StackOverflow reads question pages of Stack Overflow and the other Stack Exchange sites
+var StackOverflow = &Extractor{
+	Kind:    KindStackOverflow,
+	Hosts:   []string{"stackoverflow.com", "*.stackoverflow.com", "*.stackexchange.com", "superuser.com", "serverfault.com", "askubuntu.com", "mathoverflow.net"},
+	Path:    stackOverflowQuestionRe,
+	Extract: extractStackOverflow,
+}
+
+// voteCount reads the score of a post
+func voteCount(post *goquery.Selection) int {
+	votes := post.Find(".js-vote-count").First()
+	if score, err := strconv.Atoi(votes.AttrOr("data-value", "")); err == nil {
+		return score
+	}
+	score, _ := strconv.Atoi(text(votes))
+	return score
+}
+
+func extractStackOverflow(u *url.URL, doc *goquery.Document) *models.SiteMetadata {
+	question := doc.Find("#question").First()
+	if question.Length() == 0 {
+		return nil
+	}
+
+	qa := &models.StackOverflowMetadata{
+		QuestionID: stackOverflowQuestionRe.FindStringSubmatch(u.Path)[1],
+		Score:      voteCount(question),
+		Tags:       texts(question.Find(".post-taglist a.post-tag")),
+	}
+	if count, err := strconv.Atoi(doc.Find("#answers-header [data-answercount]").AttrOr("data-answercount", "")); err == nil {
+		qa.AnswerCount = count
+	} else {
+		qa.AnswerCount = doc.Find("#answers .answer").Length()
+	}
+
+	accepted := doc.Find(".answer.accepted-answer, .answer.js-accepted-answer").First()
+	if accepted.Length() > 0 {
+		qa.Answered = true
+		qa.AcceptedAnswer = truncate(blockText(accepted.Find(".js-post-body, .s-prose").First()))
+		qa.AcceptedAnswerScore = voteCount(accepted)
+	}
+
+	return &models.SiteMetadata{StackOverflow: qa}
+}
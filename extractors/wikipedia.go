@@ -0,0 +1,47 @@
+package extractors
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// Wikipedia reads articles of every language edition
+var Wikipedia = &Extractor{
+	Kind:    KindWikipedia,
+	Hosts:   []string{"*.wikipedia.org"},
+	Path:    regexp.MustCompile(`^/wiki/`),
+	Extract: extractWikipedia,
+}
+
+func extractWikipedia(u *url.URL, doc *goquery.Document) *models.SiteMetadata {
+	article := text(doc.Find("#firstHeading").First())
+	if article == "" {
+		return nil
+	}
+
+	language := doc.Find("html").AttrOr("lang", "")
+	if host := strings.Split(u.Hostname(), "."); language == "" && len(host) > 2 {
+		language = host[0]
+	}
+
+	// the lead is the first real paragraph, footnote markers would only be noise
+	content := doc.Find("#mw-content-text .mw-parser-output").First()
+	content.Find("sup.reference, .mw-empty-elt").Remove()
+	summary := ""
+	content.ChildrenFiltered("p").EachWithBreak(func(i int, p *goquery.Selection) bool {
+		summary = text(p)
+		return summary == ""
+	})
+
+	return &models.SiteMetadata{Wikipedia: &models.WikipediaMetadata{
+		Language:   language,
+		Article:    article,
+		Summary:    truncate(summary),
+		Categories: texts(doc.Find("#mw-normal-catlinks ul li a")),
+		LastEdited: strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(text(doc.Find("#footer-info-lastmod")), "This page was last edited on"), ".")),
+	}}
+}
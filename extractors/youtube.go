@@ -0,0 +1,65 @@
+package extractors
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/utils"
+)
+
+// YouTube reads video pages, watch urls, shorts and youtu.be links
+var YouTube = &Extractor{
+	Kind:    KindYouTube,
+	Hosts:   []string{"*.youtube.com", "youtu.be"},
+	Extract: extractYouTube,
+}
+
+var lengthSecondsRe = regexp.MustCompile(`"lengthSeconds":"(\d+)"`)
+
+// youtubeVideoID finds the id of the video a url points at
+func youtubeVideoID(u *url.URL) string {
+	if strings.EqualFold(u.Hostname(), "youtu.be") {
+		return strings.Trim(u.Path, "/")
+	}
+	if id := u.Query().Get("v"); id != "" {
+		return id
+	}
+	for _, prefix := range []string{"/shorts/", "/embed/", "/live/"} {
+		if strings.HasPrefix(u.Path, prefix) {
+			return strings.Split(strings.TrimPrefix(u.Path, prefix), "/")[0]
+		}
+	}
+	return ""
+}
+
+func extractYouTube(u *url.URL, doc *goquery.Document) *models.SiteMetadata {
+	videoID := youtubeVideoID(u)
+	if videoID == "" {
+		return nil
+	}
+
+	author := doc.Find(`[itemprop="author"]`).First()
+	video := &models.YouTubeMetadata{
+		VideoID:         videoID,
+		Channel:         author.Find(`[itemprop="name"]`).AttrOr("content", ""),
+		ChannelURL:      author.Find(`[itemprop="url"]`).AttrOr("href", ""),
+		DurationSeconds: utils.ParseISODuration(meta(doc, "duration")),
+		UploadDate:      meta(doc, "uploadDate"),
+		Views:           parseCount(meta(doc, "interactionCount")),
+	}
+
+	// the player response is inlined as javascript, it knows the length and the caption tracks
+	html, _ := doc.Html()
+	if video.DurationSeconds == 0 {
+		if match := lengthSecondsRe.FindStringSubmatch(html); match != nil {
+			video.DurationSeconds, _ = strconv.Atoi(match[1])
+		}
+	}
+	video.HasTranscript = strings.Contains(html, `"captionTracks"`)
+
+	return &models.SiteMetadata{YouTube: video}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
 	"github.com/rajnandan1/smaraka/mddls"
 	"github.com/rajnandan1/smaraka/models"
 )
@@ -58,16 +59,21 @@ func (h *HandlersImplementation) PatchTextDataByID(c echo.Context) error {
 			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
 		})
 	}
+	// the summary is made from the text that was just replaced
+	if _, err := h.bg.SubmitSummary(ctx, bookmark.ID); err != nil {
+		logger.LogError("Error submitting summary", err)
+	}
 	return c.JSON(http.StatusOK, newBookmark)
 }
 
 func (h *HandlersImplementation) GithubStarsImport(c echo.Context) error {
+	ctx := c.Request().Context()
 	var req models.PostGithubStarsImportRequest
 	if err := c.Bind(&req); err != nil {
 		return err
 	}
 
-	stars, err := h.svc.ImportGithubStars(req.Username)
+	stars, err := h.svc.ImportGithubStars(ctx, req.Username)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, models.Error{
 			Message: constants.ERRORMSG_UNKNOWN_ERROR,
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/mddls"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+const (
+	defaultDeadLettersLimit = 50
+	maxDeadLettersLimit     = 200
+	// maxDeadLetterAction is how many dead letters one retry or keep acts on, a large class takes a few calls
+	maxDeadLetterAction = 1000
+	keptWithoutContent  = "kept without content"
+)
+
+// GetDeadLetters lists the organization's urls that failed for good, grouped by failure class
+func (h *HandlersImplementation) GetDeadLetters(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req models.DeadLettersRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Error{Message: constants.ERRORMSG_INVALID_DETAIL, Code: constants.ERRORCODE_INVALID_DETAIL})
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultDeadLettersLimit
+	}
+	if req.Limit > maxDeadLettersLimit {
+		req.Limit = maxDeadLettersLimit
+	}
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	groups, err := h.db.GetDeadLetterGroups(ctx, orgUser.OrganizationID)
+	if err != nil {
+		logger.LogError("Error getting dead letter groups", orgUser.OrganizationID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+	}
+	letters, err := h.db.GetDeadLetters(ctx, orgUser.OrganizationID, req.FailureClass, nil, req.After, req.Limit)
+	if err != nil {
+		logger.LogError("Error getting dead letters", orgUser.OrganizationID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+	}
+	res := models.DeadLettersResponse{Groups: groups, Data: letters}
+	if len(letters) == req.Limit {
+		res.NextAfter = letters[len(letters)-1].ID
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// RetryDeadLetters queues dead letters again, fetched over plain http or with chrome
+func (h *HandlersImplementation) RetryDeadLetters(c echo.Context) error {
+	ctx := c.Request().Context()
+	req, letters, err := h.deadLetterTargets(c)
+	if err != nil {
+		return err
+	}
+	if req.Strategy != constants.FetchStrategyHTTP && req.Strategy != constants.FetchStrategyChrome {
+		return c.JSON(http.StatusBadRequest, models.Error{Message: constants.ERRORMSG_INVALID_DETAIL, Code: constants.ERRORCODE_INVALID_DETAIL})
+	}
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	queued := make([]string, 0, len(letters))
+	err = h.db.RetryDeadLetters(ctx, orgUser.OrganizationID, letters, func(tx pgx.Tx, batchID string, urls []string) error {
+		results, err := h.bg.RetryURLs(ctx, tx, urls, orgUser.OrganizationID, batchID, req.Strategy)
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			if !result.UniqueSkippedAsDuplicate {
+				queued = append(queued, urls[i])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.LogError("Error retrying dead letters", orgUser.OrganizationID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+	}
+	for _, url := range queued {
+		h.svc.PublishProgress(ctx, models.IngestProgress{OrgID: orgUser.OrganizationID, URL: url, Stage: constants.IngestStageQueued})
+	}
+	return c.JSON(http.StatusOK, models.DeadLetterActionResponse{Count: len(letters)})
+}
+
+// KeepDeadLetters saves dead letters as bookmarks of just their url, for pages that will never be fetched
+func (h *HandlersImplementation) KeepDeadLetters(c echo.Context) error {
+	ctx := c.Request().Context()
+	_, letters, err := h.deadLetterTargets(c)
+	if err != nil {
+		return err
+	}
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	kept := make([]models.DeadLetter, 0, len(letters))
+	for _, letter := range letters {
+		if err := h.svc.KeepWithoutContent(ctx, letter.URL, orgUser.OrganizationID); err != nil {
+			logger.LogError("Error keeping dead letter", letter.URL, err)
+			continue
+		}
+		if letter.BatchID != "" {
+			if err := h.db.UpdateFailedImportBatchURLs(ctx, letter.BatchID, []string{letter.URL}, constants.ImportOutcomeAdded, keptWithoutContent); err != nil {
+				logger.LogError("Error recording import outcome", letter.BatchID, err)
+			}
+		}
+		kept = append(kept, letter)
+	}
+	return h.clearDeadLetters(c, orgUser.OrganizationID, kept)
+}
+
+// deadLetterTargets binds a dead letter action and gets the dead letters it picks,
+// the returned error is the response already written when the request can not go on
+func (h *HandlersImplementation) deadLetterTargets(c echo.Context) (*models.DeadLetterActionRequest, []models.DeadLetter, error) {
+	var req models.DeadLetterActionRequest
+	if err := c.Bind(&req); err != nil || len(req.IDs) == 0 && req.FailureClass == "" || len(req.IDs) > maxDeadLetterAction {
+		return nil, nil, c.JSON(http.StatusBadRequest, models.Error{Message: constants.ERRORMSG_INVALID_DETAIL, Code: constants.ERRORCODE_INVALID_DETAIL})
+	}
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+	letters, err := h.db.GetDeadLetters(c.Request().Context(), orgUser.OrganizationID, req.FailureClass, req.IDs, 0, maxDeadLetterAction)
+	if err != nil {
+		logger.LogError("Error getting dead letters", orgUser.OrganizationID, err)
+		return nil, nil, c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+	}
+	return &req, letters, nil
+}
+
+// clearDeadLetters drops dead letters that were dealt with so they leave the view
+func (h *HandlersImplementation) clearDeadLetters(c echo.Context, orgID string, letters []models.DeadLetter) error {
+	ids := make([]int64, 0, len(letters))
+	for _, letter := range letters {
+		ids = append(ids, letter.ID)
+	}
+	if len(ids) > 0 {
+		if err := h.db.DeleteDeadLetters(c.Request().Context(), orgID, ids); err != nil {
+			logger.LogError("Error deleting dead letters", orgID, err)
+			return c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+		}
+	}
+	return c.JSON(http.StatusOK, models.DeadLetterActionResponse{Count: len(ids)})
+}
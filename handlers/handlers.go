@@ -5,6 +5,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/rajnandan1/smaraka/bg"
+	"github.com/rajnandan1/smaraka/blobstore"
 	"github.com/rajnandan1/smaraka/config"
 	"github.com/rajnandan1/smaraka/crypt"
 	"github.com/rajnandan1/smaraka/postgres"
@@ -16,6 +17,15 @@ type Handlers interface {
 	SearchBookmarks(c echo.Context) error
 	GetAllBookmarks(c echo.Context) error
 	GetBookmarkByID(c echo.Context) error
+	GetBookmarkDiff(c echo.Context) error
+	GetReaderView(c echo.Context) error
+	RegenerateSummary(c echo.Context) error
+	GetBrokenLinks(c echo.Context) error
+	GetSnapshot(c echo.Context) error
+	GetDocument(c echo.Context) error
+	GetThumbnail(c echo.Context) error
+	GetImage(c echo.Context) error
+	GetStorageUsage(c echo.Context) error
 	IndexBookmarkByID(c echo.Context) error
 	GithubStarsImport(c echo.Context) error
 	AddBulkNewBookmarks(c echo.Context) error
@@ -25,8 +35,20 @@ type Handlers interface {
 	GetUserByID(c echo.Context) error
 	PatchTextDataByID(c echo.Context) error
 	JobQueueStatus(c echo.Context) error
+	GetIngestJobs(c echo.Context) error
+	StreamIngestProgress(c echo.Context) error
+	PauseImports(c echo.Context) error
+	ResumeImports(c echo.Context) error
+	CancelImports(c echo.Context) error
+	GetImportBatches(c echo.Context) error
+	GetImportBatch(c echo.Context) error
+	GetImportBatchReport(c echo.Context) error
+	GetDeadLetters(c echo.Context) error
+	RetryDeadLetters(c echo.Context) error
+	KeepDeadLetters(c echo.Context) error
 	DeleteBookmarkByID(c echo.Context) error
 	BulkDelete(c echo.Context) error
+	AcceptSuggestedTags(c echo.Context) error
 	TermsPage(c echo.Context) error
 	RefundPage(c echo.Context) error
 	ContactPage(c echo.Context) error
@@ -50,20 +72,24 @@ type Handlers interface {
 	RunOrgSchedules(c echo.Context) error
 }
 type HandlersImplementation struct {
-	db     postgres.Postgres
-	bg     bg.Background
-	svc    services.Services
-	crypto crypt.Crypt
-	config config.Config
+	db       postgres.Postgres
+	bg       bg.Background
+	svc      services.Services
+	crypto   crypt.Crypt
+	config   config.Config
+	blobs    blobstore.Store
+	progress *services.ProgressHub
 }
 
-func ConfigureHandlers(db postgres.Postgres, bg bg.Background, svc services.Services, config config.Config, crypto crypt.Crypt) (Handlers, error) {
+func ConfigureHandlers(db postgres.Postgres, bg bg.Background, svc services.Services, config config.Config, crypto crypt.Crypt, blobs blobstore.Store, progress *services.ProgressHub) (Handlers, error) {
 	return &HandlersImplementation{
-		db:     db,
-		bg:     bg,
-		svc:    svc,
-		config: config,
-		crypto: crypto,
+		db:       db,
+		bg:       bg,
+		svc:      svc,
+		config:   config,
+		crypto:   crypto,
+		blobs:    blobs,
+		progress: progress,
 	}, nil
 }
 
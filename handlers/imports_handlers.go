@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/mddls"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/validators"
+)
+
+const (
+	defaultImportBatchesLimit = 20
+	defaultImportURLsLimit    = 100
+	maxImportURLsLimit        = 1000
+)
+
+// importSource keeps the source of an import to the known ones
+func importSource(source string) string {
+	switch source {
+	case constants.ImportSourceURLs, constants.ImportSourceBrowsers, constants.ImportSourceGithub:
+		return source
+	}
+	return constants.ImportSourceAPI
+}
+
+// importBatchURLs decides the outcome of the urls of an import that is known before fetching them: invalid urls,
+// urls listed twice and urls the organization already saved. The urls left are pending and returned to be queued
+func (h *HandlersImplementation) importBatchURLs(ctx context.Context, orgID string, submitted []string) ([]models.ImportBatchURL, []string, error) {
+	urls := make([]models.ImportBatchURL, 0, len(submitted))
+	candidates := make([]string, 0, len(submitted))
+	seen := make(map[string]bool)
+	for i, url := range submitted {
+		url = strings.TrimSpace(url)
+		entry := models.ImportBatchURL{Position: i + 1, URL: url, Outcome: constants.ImportOutcomePending}
+		switch {
+		case !validators.IsValidURL(url):
+			entry.Outcome, entry.Reason = constants.ImportOutcomeInvalid, "not a valid url"
+		case seen[url]:
+			entry.Outcome, entry.Reason = constants.ImportOutcomeAlreadyPresent, "listed more than once"
+		default:
+			seen[url] = true
+			candidates = append(candidates, url)
+		}
+		urls = append(urls, entry)
+	}
+	if len(candidates) == 0 {
+		return urls, candidates, nil
+	}
+
+	present, err := h.db.GetOrganizationURLsPresent(ctx, orgID, candidates)
+	if err != nil {
+		return nil, nil, err
+	}
+	pending := make([]string, 0, len(candidates))
+	for i := range urls {
+		if urls[i].Outcome != constants.ImportOutcomePending {
+			continue
+		}
+		if present[urls[i].URL] {
+			urls[i].Outcome, urls[i].Reason = constants.ImportOutcomeAlreadyPresent, "already saved"
+			continue
+		}
+		pending = append(pending, urls[i].URL)
+	}
+	return urls, pending, nil
+}
+
+// GetImportBatches lists the organization's imports newest first with the number of urls for each outcome
+func (h *HandlersImplementation) GetImportBatches(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req models.GetImportBatchesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Error{Message: constants.ERRORMSG_INVALID_DETAIL, Code: constants.ERRORCODE_INVALID_DETAIL})
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultImportBatchesLimit
+	}
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	// one more than asked tells if this is the last page
+	batches, err := h.db.GetImportBatches(ctx, orgUser.OrganizationID, req.NextID, req.Limit+1)
+	if err != nil {
+		logger.LogError("Error getting import batches", orgUser.OrganizationID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+	}
+	resp := models.ImportBatchListResponse{Data: batches, IsLast: len(batches) <= req.Limit}
+	if !resp.IsLast {
+		resp.Data = batches[:req.Limit]
+		resp.NextID = resp.Data[len(resp.Data)-1].ID
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetImportBatch returns an import with its urls and their outcomes, a page at a time
+func (h *HandlersImplementation) GetImportBatch(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req models.GetImportBatchRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Error{Message: constants.ERRORMSG_INVALID_DETAIL, Code: constants.ERRORCODE_INVALID_DETAIL})
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultImportURLsLimit
+	}
+	if req.Limit > maxImportURLsLimit {
+		req.Limit = maxImportURLsLimit
+	}
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	batch, err := h.db.GetImportBatch(ctx, orgUser.OrganizationID, req.ID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.Error{Message: constants.ERRORMSG_IMPORT_NOT_FOUND, Code: constants.ERRORCODE_IMPORT_NOT_FOUND})
+	}
+	urls, err := h.db.GetImportBatchURLs(ctx, batch.ID, req.Outcome, req.After, req.Limit)
+	if err != nil {
+		logger.LogError("Error getting import batch urls", batch.ID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+	}
+	resp := models.ImportBatchResponse{ImportBatch: batch, URLs: urls}
+	if len(urls) == req.Limit {
+		resp.NextPosition = urls[len(urls)-1].Position
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetImportBatchReport downloads every url of an import with its outcome as csv
+func (h *HandlersImplementation) GetImportBatchReport(c echo.Context) error {
+	ctx := c.Request().Context()
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+	batch, err := h.db.GetImportBatch(ctx, orgUser.OrganizationID, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.Error{Message: constants.ERRORMSG_IMPORT_NOT_FOUND, Code: constants.ERRORCODE_IMPORT_NOT_FOUND})
+	}
+	urls, err := h.db.GetImportBatchURLs(ctx, batch.ID, "", 0, 0)
+	if err != nil {
+		logger.LogError("Error getting import batch urls", batch.ID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="import_`+batch.ID+`.csv"`)
+	res.WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(res)
+	w.Write([]string{"position", "url", "outcome", "reason", "updated_at"})
+	for _, url := range urls {
+		w.Write([]string{strconv.Itoa(url.Position), csvCell(url.URL), url.Outcome, csvCell(url.Reason), url.UpdatedAt.UTC().Format(time.RFC3339)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// csvCell keeps spreadsheets from reading an imported url or a fetch error as a formula
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rajnandan1/smaraka/bg"
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/mddls"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/riverqueue/river/rivertype"
+)
+
+const (
+	defaultIngestJobsLimit = 50
+	maxIngestJobsLimit     = 200
+	// progressKeepAlive is how often an idle event stream sends a comment so proxies do not close it
+	progressKeepAlive = 25 * time.Second
+)
+
+// GetIngestJobs lists the organization's url ingest jobs with their state, attempts, errors and timings
+func (h *HandlersImplementation) GetIngestJobs(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req models.IngestJobsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Error{Message: constants.ERRORMSG_INVALID_DETAIL, Code: constants.ERRORCODE_INVALID_DETAIL})
+	}
+	if req.State != "" && !validJobState(req.State) {
+		return c.JSON(http.StatusBadRequest, models.Error{Message: constants.ERRORMSG_INVALID_DETAIL, Code: constants.ERRORCODE_INVALID_DETAIL})
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultIngestJobsLimit
+	}
+	if req.Limit > maxIngestJobsLimit {
+		req.Limit = maxIngestJobsLimit
+	}
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	jobs, err := h.bg.ListIngestJobs(ctx, orgUser.OrganizationID, req.State, req.Cursor, req.Limit)
+	if errors.Is(err, bg.ErrInvalidCursor) {
+		return c.JSON(http.StatusBadRequest, models.Error{Message: constants.ERRORMSG_INVALID_DETAIL, Code: constants.ERRORCODE_INVALID_DETAIL})
+	}
+	if err != nil {
+		logger.LogError("Error listing ingest jobs", orgUser.OrganizationID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+	}
+	if jobs.Paused, err = h.db.IsIngestPaused(ctx, orgUser.OrganizationID); err != nil {
+		logger.LogError("Error getting ingest pause", orgUser.OrganizationID, err)
+	}
+	return c.JSON(http.StatusOK, jobs)
+}
+
+// PauseImports holds back the organization's imports that have not started, interactive saves keep running
+func (h *HandlersImplementation) PauseImports(c echo.Context) error {
+	ctx := c.Request().Context()
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+	jobs, err := h.db.PauseIngestJobs(ctx, orgUser.OrganizationID, bg.LaneBulk)
+	if err != nil {
+		logger.LogError("Error pausing imports", orgUser.OrganizationID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+	}
+	return c.JSON(http.StatusOK, models.IngestBatchActionResponse{Jobs: jobs, Paused: true})
+}
+
+// ResumeImports lets the organization's paused imports run again
+func (h *HandlersImplementation) ResumeImports(c echo.Context) error {
+	ctx := c.Request().Context()
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+	jobs, err := h.db.ResumeIngestJobs(ctx, orgUser.OrganizationID, bg.LaneBulk)
+	if err != nil {
+		logger.LogError("Error resuming imports", orgUser.OrganizationID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+	}
+	return c.JSON(http.StatusOK, models.IngestBatchActionResponse{Jobs: jobs})
+}
+
+// CancelImports cancels the organization's imports that have not started, paused or not
+func (h *HandlersImplementation) CancelImports(c echo.Context) error {
+	ctx := c.Request().Context()
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+	jobs, err := h.db.CancelIngestJobs(ctx, orgUser.OrganizationID, bg.LaneBulk)
+	if err != nil {
+		logger.LogError("Error cancelling imports", orgUser.OrganizationID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{Message: constants.ERRORMSG_INTERNAL_ERROR, Code: constants.ERRORCODE_INTERNAL_DETAIL})
+	}
+	return c.JSON(http.StatusOK, models.IngestBatchActionResponse{Jobs: jobs})
+}
+
+// StreamIngestProgress streams the organization's ingest progress as server sent events, the event name is the stage
+func (h *HandlersImplementation) StreamIngestProgress(c echo.Context) error {
+	ctx := c.Request().Context()
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+	events, unsubscribe := h.progress.Subscribe(orgUser.OrganizationID)
+	defer unsubscribe()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	// nginx buffers responses unless told not to
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	keepAlive := time.NewTicker(progressKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-h.progress.Done():
+			// the server is shutting down, clients reconnect to another instance
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger.LogError("Error encoding ingest progress", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event.Stage, data); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+	}
+}
+
+func validJobState(state string) bool {
+	for _, known := range rivertype.JobStates() {
+		if string(known) == state {
+			return true
+		}
+	}
+	return false
+}
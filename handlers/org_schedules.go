@@ -4,11 +4,11 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/rajnandan1/smaraka/bg"
 	"github.com/rajnandan1/smaraka/constants"
 	"github.com/rajnandan1/smaraka/logger"
 	"github.com/rajnandan1/smaraka/mddls"
 	"github.com/rajnandan1/smaraka/models"
-	"github.com/rajnandan1/smaraka/utils"
 	"github.com/rajnandan1/smaraka/validators"
 )
 
@@ -184,20 +184,11 @@ func (h *HandlersImplementation) RunOrgSchedules(c echo.Context) error {
 		}
 	}
 	if len(validURLs) > 0 {
-		validURLChunks := utils.ChunkStringArray(validURLs, h.config.MaxWorkers)
-
-		for _, validURLsChunked := range validURLChunks {
-			h.bg.SubmitURLs(ctx, validURLsChunked, orgUser.OrganizationID)
+		if _, err := h.bg.SubmitURLs(ctx, validURLs, orgUser.OrganizationID, bg.LaneSchedule, ""); err != nil {
+			logger.LogError("Error submitting scheduled urls", orgUser.OrganizationID, err)
 		}
-
 	}
 
-	go func() {
-		for _, orgData := range *orgDataURLs {
-			h.svc.BulkLightAndFullJob(orgData.URLs, orgData.OrganizationID)
-		}
-	}()
-
 	return c.JSON(http.StatusOK, orgDataURLs)
 
 }
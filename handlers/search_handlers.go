@@ -2,16 +2,17 @@ package handlers
 
 import (
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/keywords"
 	"github.com/rajnandan1/smaraka/logger"
 	"github.com/rajnandan1/smaraka/mddls"
 	"github.com/rajnandan1/smaraka/models"
-	"github.com/rajnandan1/smaraka/utils"
+	"github.com/rajnandan1/smaraka/services"
 )
 
 func (h *HandlersImplementation) SearchBookmarks(c echo.Context) error {
@@ -22,7 +23,7 @@ func (h *HandlersImplementation) SearchBookmarks(c echo.Context) error {
 	}
 	orgUser := mddls.GetOrgUserFromEchoContext(c)
 
-	searchResult, err := h.db.SearchURLs(ctx, orgUser.OrganizationID, req.Needle, "")
+	searchResult, err := h.db.SearchURLs(ctx, orgUser.OrganizationID, req.Needle, "", req.Language, keywords.Normalize(req.Tag))
 	if err != nil {
 		logger.LogError("Error searching bookmarks", err)
 		return c.JSON(http.StatusOK, make([]models.URLResponses, 0))
@@ -42,38 +43,21 @@ func (h *HandlersImplementation) SearchBookmarks(c echo.Context) error {
 			filteredResults = append(filteredResults, result)
 		}
 	}
+	services.ProxyImages(filteredResults...)
 
 	return c.JSON(http.StatusOK, filteredResults)
 
 }
 
+// JobQueueStatus counts the organization's url ingest jobs by their river state
 func (h *HandlersImplementation) JobQueueStatus(c echo.Context) error {
 	ctx := c.Request().Context()
 	orgUser := mddls.GetOrgUserFromEchoContext(c)
-	status, err := h.db.GetJobQueueStatusCount(ctx, orgUser.OrganizationID)
+	status, err := h.db.GetIngestJobStateCounts(ctx, orgUser.OrganizationID)
 	if err != nil {
 		logger.LogError("Error getting job queue status", err)
 		return c.JSON(http.StatusOK, make(map[string]int))
 	}
-
-	pendingJobs, err := h.db.GetJobQueuePendingOlderThan1Hour(ctx, orgUser.OrganizationID)
-	if err == nil && len(pendingJobs) > 0 {
-
-		urls := make([]string, 0)
-		for _, job := range pendingJobs {
-			urls = append(urls, job.JobData)
-			//update status to processing
-			h.db.UpdateJobQueueStatus(ctx, orgUser.OrganizationID, job.JobData, constants.JobQueueStatusPending)
-		}
-		if len(urls) > 0 {
-			validURLChunks := utils.ChunkStringArray(urls, h.config.MaxWorkers)
-			for _, validURLsChunked := range validURLChunks {
-
-				h.bg.SubmitURLs(ctx, validURLsChunked, orgUser.OrganizationID)
-			}
-		}
-	}
-
 	return c.JSON(http.StatusOK, status)
 }
 
@@ -96,7 +80,7 @@ func (h *HandlersImplementation) GetAllBookmarks(c echo.Context) error {
 	}
 
 	//get all url orgs
-	data, err := h.db.GetURLsForOrganization(ctx, orgUser.OrganizationID, req.NextID, req.Limit)
+	data, err := h.db.GetURLsForOrganization(ctx, orgUser.OrganizationID, req.NextID, req.Limit, req.LinkState)
 	if err != nil || data == nil {
 		logger.LogError("Error getting url orgs", err)
 		resp.IsLast = true
@@ -104,8 +88,9 @@ func (h *HandlersImplementation) GetAllBookmarks(c echo.Context) error {
 	}
 	resp.NextID = data[len(data)-1].OrganizationRelationID
 	resp.Data = data
+	services.ProxyImages(data...)
 
-	isLastData, err := h.db.GetURLsForOrganization(ctx, orgUser.OrganizationID, resp.NextID, 1)
+	isLastData, err := h.db.GetURLsForOrganization(ctx, orgUser.OrganizationID, resp.NextID, 1, req.LinkState)
 
 	if err != nil || isLastData == nil || len(isLastData) == 0 {
 		resp.IsLast = true
@@ -128,8 +113,91 @@ func (h *HandlersImplementation) GetBookmarkByID(c echo.Context) error {
 			Code:    constants.ERRORCODE_BOOKMARK_NOT_FOUND,
 		})
 	}
+	services.ProxyStoreImages(bookmark)
 	return c.JSON(http.StatusOK, bookmark)
 }
+func (h *HandlersImplementation) GetBookmarkDiff(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	diff, err := h.svc.DiffSinceSaved(ctx, id, orgUser.OrganizationID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.Error{
+			Message: constants.ERRORMSG_BOOKMARK_NOT_FOUND,
+			Code:    constants.ERRORCODE_BOOKMARK_NOT_FOUND,
+		})
+	}
+	return c.JSON(http.StatusOK, diff)
+}
+
+func (h *HandlersImplementation) GetReaderView(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	article, err := h.svc.ReaderView(ctx, id, orgUser.OrganizationID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.Error{
+			Message: constants.ERRORMSG_BOOKMARK_NOT_FOUND,
+			Code:    constants.ERRORCODE_BOOKMARK_NOT_FOUND,
+		})
+	}
+	return c.JSON(http.StatusOK, article)
+}
+
+// RegenerateSummary summarizes a bookmark again in the background
+func (h *HandlersImplementation) RegenerateSummary(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	urlStore, err := h.db.GetURLStoreByURLOrgIDOrgID(ctx, id, orgUser.OrganizationID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.Error{
+			Message: constants.ERRORMSG_BOOKMARK_NOT_FOUND,
+			Code:    constants.ERRORCODE_BOOKMARK_NOT_FOUND,
+		})
+	}
+	if urlStore.Status == constants.BookmarkStatusPending {
+		return c.JSON(http.StatusBadRequest, models.Error{
+			Message: constants.ERRORMSG_BOOKMARK_PENDING,
+			Code:    constants.ERRORCODE_BOOKMARK_PENDING,
+		})
+	}
+	if _, err := h.bg.SubmitSummary(ctx, urlStore.ID); err != nil {
+		logger.LogError("Error submitting summary", err)
+		return c.JSON(http.StatusInternalServerError, models.Error{
+			Message: constants.ERRORMSG_UNKNOWN_ERROR,
+			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
+		})
+	}
+	return c.JSON(http.StatusAccepted, nil)
+}
+
+func (h *HandlersImplementation) GetBrokenLinks(c echo.Context) error {
+	ctx := c.Request().Context()
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	resp := models.BrokenLinksReport{
+		Counts: make(map[string]int),
+		Data:   make([]*models.BrokenLinkResponse, 0),
+	}
+
+	brokenLinks, err := h.db.GetBrokenLinksForOrganization(ctx, orgUser.OrganizationID)
+	if err != nil {
+		logger.LogError("Error getting broken links", err)
+		return c.JSON(http.StatusOK, resp)
+	}
+	for _, brokenLink := range brokenLinks {
+		resp.Counts[brokenLink.LinkState]++
+		services.ProxyImages(&brokenLink.URLResponses)
+	}
+	resp.Data = brokenLinks
+
+	return c.JSON(http.StatusOK, resp)
+}
+
 func (h *HandlersImplementation) IndexBookmarkByID(c echo.Context) error {
 	ctx := c.Request().Context()
 	var req models.PostIndexingRequest
@@ -151,14 +219,15 @@ func (h *HandlersImplementation) IndexBookmarkByID(c echo.Context) error {
 			Code:    constants.ERRORCODE_BOOKMARK_NOT_FOUND,
 		})
 	}
-	urlStore, err := h.svc.GetContentEasy(bookmark.URL)
-	if err != nil {
+	// the url is fetched again by its ingest job on the interactive lane, not while the request waits.
+	// The url store is shared, it keeps its status and content unless the new fetch succeeds
+	if _, err := h.bg.ReindexURL(ctx, bookmark.URL, orgUser.OrganizationID); err != nil {
+		logger.LogError("Error queueing bookmark", err)
 		return c.JSON(http.StatusInternalServerError, models.Error{
 			Message: constants.ERRORMSG_UNKNOWN_ERROR,
 			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
 		})
 	}
-	h.svc.DoContentCompleteByID(urlStore.URL)
 	return c.JSON(http.StatusOK, bookmark)
 }
 
@@ -179,6 +248,39 @@ func (h *HandlersImplementation) BulkDelete(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, nil)
 }
+
+// AcceptSuggestedTags turns suggested tags of many bookmarks into tags at once
+func (h *HandlersImplementation) AcceptSuggestedTags(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req models.AcceptTagsRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Error{
+			Message: err.Error(),
+			Code:    constants.ERRORCODE_INVALID_DETAIL,
+		})
+	}
+	tags := keywords.Merge(len(req.Tags), req.Tags)
+	// tags that normalise to nothing would accept every suggestion instead of the chosen ones
+	if len(req.Tags) > 0 && len(tags) == 0 {
+		return c.JSON(http.StatusBadRequest, models.Error{
+			Message: constants.ERRORMSG_INVALID_DETAIL,
+			Code:    constants.ERRORCODE_INVALID_DETAIL,
+		})
+	}
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+	if err := h.db.AcceptSuggestedTags(ctx, orgUser.OrganizationID, req.IDs, tags); err != nil {
+		logger.LogError("Error accepting suggested tags", err)
+		return c.JSON(http.StatusInternalServerError, models.Error{
+			Message: constants.ERRORMSG_UNKNOWN_ERROR,
+			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
+		})
+	}
+	return c.JSON(http.StatusOK, nil)
+}
+
 func (h *HandlersImplementation) DeleteBookmarkByID(c echo.Context) error {
 	ctx := c.Request().Context()
 	id := c.Param("id")
@@ -241,25 +343,7 @@ func (h *HandlersImplementation) ExportBookmarks(c echo.Context) error {
 
 	completeHTML := htmlStart + htmlMiddle + htmlEnd
 
-	//create file in /tmp with html content like firefox export bookmarks
-	folderLocation := "/tmp"
-	fileLocation := folderLocation + "/" + fileName
-	//create file
-	file, err := os.Create(fileLocation)
-	if err != nil {
-		logger.LogError("Error creating file", err)
-		return c.HTML(http.StatusInternalServerError, constants.ERRORMSG_UNKNOWN_ERROR)
-	}
-	defer file.Close()
-
-	_, err = file.WriteString(completeHTML)
-	if err != nil {
-		logger.LogError("Error writing to file", err)
-		return c.HTML(http.StatusInternalServerError, constants.ERRORMSG_UNKNOWN_ERROR)
-	}
-
-	//write to file
-	//send file
-
-	return c.Attachment(fileLocation, fileName)
+	// the export is streamed as it is built, it is not stored and does not count against the quota
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+fileName+`"`)
+	return c.Stream(http.StatusOK, "text/html; charset=utf-8", strings.NewReader(completeHTML))
 }
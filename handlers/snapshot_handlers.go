@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/mddls"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/services"
+)
+
+// snapshotCSP only lets the archived page use what was inlined into it, nothing can run or load from elsewhere
+const snapshotCSP = "default-src 'none'; img-src data:; style-src 'unsafe-inline' data:; font-src data:; media-src data:; frame-ancestors 'self'; form-action 'none'; base-uri 'none'; sandbox"
+
+func (h *HandlersImplementation) GetSnapshot(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	snapshot, body, err := h.svc.OpenSnapshot(ctx, id, orgUser.OrganizationID)
+	if errors.Is(err, services.ErrBookmarkNotFound) {
+		return c.JSON(http.StatusNotFound, models.Error{
+			Message: constants.ERRORMSG_BOOKMARK_NOT_FOUND,
+			Code:    constants.ERRORCODE_BOOKMARK_NOT_FOUND,
+		})
+	}
+	if err != nil {
+		logger.LogError("Error opening snapshot", err)
+		return c.JSON(http.StatusNotFound, models.Error{
+			Message: constants.ERRORMSG_SNAPSHOT_NOT_FOUND,
+			Code:    constants.ERRORCODE_SNAPSHOT_NOT_FOUND,
+		})
+	}
+	defer body.Close()
+
+	header := c.Response().Header()
+	header.Set("Content-Security-Policy", snapshotCSP)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Referrer-Policy", "no-referrer")
+	header.Set("Cache-Control", "private, max-age=3600")
+	return c.Stream(http.StatusOK, snapshot.ContentType, body)
+}
+
+// GetDocument serves the original file of a document bookmark, like a pdf
+func (h *HandlersImplementation) GetDocument(c echo.Context) error {
+	ctx := c.Request().Context()
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	urlStore, body, err := h.svc.OpenDocument(ctx, c.Param("id"), orgUser.OrganizationID)
+	if errors.Is(err, services.ErrBookmarkNotFound) {
+		return c.JSON(http.StatusNotFound, models.Error{
+			Message: constants.ERRORMSG_BOOKMARK_NOT_FOUND,
+			Code:    constants.ERRORCODE_BOOKMARK_NOT_FOUND,
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusNotFound, models.Error{
+			Message: constants.ERRORMSG_DOCUMENT_NOT_FOUND,
+			Code:    constants.ERRORCODE_DOCUMENT_NOT_FOUND,
+		})
+	}
+	defer body.Close()
+
+	header := c.Response().Header()
+	// pdfs can carry scripts, the browser's viewer must not run them with our origin
+	header.Set("Content-Security-Policy", "sandbox")
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": urlStore.Title + ".pdf"}))
+	header.Set("Cache-Control", "private, max-age=3600")
+	return c.Stream(http.StatusOK, urlStore.ContentType, body)
+}
+
+func (h *HandlersImplementation) GetThumbnail(c echo.Context) error {
+	ctx := c.Request().Context()
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	contentType, body, err := h.svc.OpenThumbnail(ctx, c.Param("id"), c.QueryParam("size"), orgUser.OrganizationID)
+	if err != nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+	defer body.Close()
+
+	header := c.Response().Header()
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Cache-Control", "private, max-age=86400")
+	return c.Stream(http.StatusOK, contentType, body)
+}
+
+func (h *HandlersImplementation) GetImage(c echo.Context) error {
+	ctx := c.Request().Context()
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	contentType, body, err := h.svc.OpenImage(ctx, c.Param("id"), c.Param("kind"), orgUser.OrganizationID)
+	if err != nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+	defer body.Close()
+
+	header := c.Response().Header()
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Referrer-Policy", "no-referrer")
+	header.Set("Cache-Control", "private, max-age=86400")
+	// svg favicons are documents, they must not run scripts when opened directly
+	if contentType == "image/svg+xml" {
+		header.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	}
+	return c.Stream(http.StatusOK, contentType, body)
+}
+
+func (h *HandlersImplementation) GetStorageUsage(c echo.Context) error {
+	ctx := c.Request().Context()
+	orgUser := mddls.GetOrgUserFromEchoContext(c)
+
+	resp := models.StorageUsageResponse{
+		QuotaBytes: int64(h.config.OrgBlobQuotaMB) << 20,
+	}
+	used, err := h.db.GetOrgBlobUsage(ctx, orgUser.OrganizationID, "")
+	if err != nil {
+		logger.LogError("Error getting storage usage", err)
+		return c.JSON(http.StatusInternalServerError, models.Error{
+			Message: constants.ERRORMSG_UNKNOWN_ERROR,
+			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
+		})
+	}
+	resp.UsedBytes = used
+	return c.JSON(http.StatusOK, resp)
+}
@@ -1,18 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/rajnandan1/smaraka/bg"
 	"github.com/rajnandan1/smaraka/constants"
 	"github.com/rajnandan1/smaraka/logger"
 	"github.com/rajnandan1/smaraka/mddls"
 	"github.com/rajnandan1/smaraka/models"
-	"github.com/rajnandan1/smaraka/utils"
+	"github.com/rajnandan1/smaraka/services"
 	"github.com/rajnandan1/smaraka/validators"
 )
 
+// idempotencyKeyHeader is sent by clients that retry saves, the browser extension does
+const idempotencyKeyHeader = "Idempotency-Key"
+
 func (h *HandlersImplementation) BookmarkPresent(c echo.Context) error {
 	ctx := c.Request().Context()
 	var req models.CreateBookmarkRequest
@@ -38,10 +43,14 @@ func (h *HandlersImplementation) BookmarkPresent(c echo.Context) error {
 			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
 		})
 	}
+	services.ProxyImages(oldURL)
 	return c.JSON(http.StatusOK, oldURL)
 
 }
 
+// AddNewBookmark saves a url for the organization and returns at once, a url never seen before is returned pending
+// and fetched by its ingest job. Clients retrying a save send the same Idempotency-Key header, a key given before
+// returns the bookmark its first save ended in
 func (h *HandlersImplementation) AddNewBookmark(c echo.Context) error {
 	ctx := c.Request().Context()
 	var req models.CreateBookmarkRequest
@@ -60,64 +69,105 @@ func (h *HandlersImplementation) AddNewBookmark(c echo.Context) error {
 
 	orgUser := mddls.GetOrgUserFromEchoContext(c)
 
-	_, err := h.db.GetURLStoreByURL(ctx, req.URL)
-
-	if err != nil { //meaning new url, not present in db
-		urlStore, err := h.svc.GetContentEasy(req.URL)
+	key := c.Request().Header.Get(idempotencyKeyHeader)
+	if key != "" {
+		seen, claimed, err := h.db.ClaimIdempotencyKey(ctx, orgUser.OrganizationID, key, req.URL)
 		if err != nil {
-			logger.LogError("Error getting content", err)
+			logger.LogError("Error claiming idempotency key", err)
 			return c.JSON(http.StatusInternalServerError, models.Error{
 				Message: constants.ERRORMSG_UNKNOWN_ERROR,
 				Code:    constants.ERRORCODE_UNKNOWN_ERROR,
 			})
 		}
-		urlStore.ID = h.db.NewID("url")
-		if _, err := h.db.InsertNewURLStore(ctx, *urlStore); err != nil {
-			logger.LogError("Error inserting url store", err)
-			return c.JSON(http.StatusInternalServerError, models.Error{
-				Message: constants.ERRORMSG_UNKNOWN_ERROR,
-				Code:    constants.ERRORCODE_UNKNOWN_ERROR,
-			})
+		if !claimed {
+			return h.replayBookmark(c, orgUser.OrganizationID, req.URL, seen)
 		}
-
 	}
 
-	urlStore, err := h.db.GetURLStoreByURL(ctx, req.URL)
+	resp, err := h.addBookmark(ctx, orgUser.OrganizationID, req.URL)
 	if err != nil {
-		logger.LogError("Error getting url store", err)
+		logger.LogError("Error adding bookmark", req.URL, err)
+		if key != "" {
+			// the client may have given up already, the key is released regardless so its retry can go on
+			if err := h.db.ReleaseIdempotencyKey(context.WithoutCancel(ctx), orgUser.OrganizationID, key); err != nil {
+				logger.LogError("Error releasing idempotency key", err)
+			}
+		}
 		return c.JSON(http.StatusInternalServerError, models.Error{
 			Message: constants.ERRORMSG_UNKNOWN_ERROR,
 			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
 		})
 	}
+	if key != "" {
+		// the bookmark is written, a client gone by now still finds it when it retries with the key
+		if err := h.db.CompleteIdempotencyKey(context.WithoutCancel(ctx), orgUser.OrganizationID, key, resp.OrganizationRelationID); err != nil {
+			logger.LogError("Error completing idempotency key", err)
+		}
+	}
+
+	services.ProxyImages(resp)
+	return c.JSON(http.StatusOK, resp)
+}
+
+// addBookmark relates a url to the organization and queues its full fetch on the interactive lane until it is complete,
+// a url the organization saved before is returned as it is
+func (h *HandlersImplementation) addBookmark(ctx context.Context, orgID, url string) (*models.URLResponses, error) {
+	urlStore, err := h.svc.GetOrCreatePendingURLStore(ctx, url)
+	if err != nil {
+		return nil, err
+	}
 	//create new entry in url org
 	urlOrg := models.URLOrganizations{
 		ID:             h.db.NewID("url_org"),
 		URLID:          urlStore.ID,
-		OrganizationID: orgUser.OrganizationID,
+		OrganizationID: orgID,
 		Status:         constants.URLStatusActive,
 	}
-
+	var resp *models.URLResponses
 	if _, err := h.db.InsertNewURLOrganization(ctx, urlOrg); err != nil {
-		logger.LogError("Error inserting url org", err)
-		if strings.Contains(err.Error(), "violates unique constraint") {
-			oldURL, err := h.db.GetSingleURLForOrganizationURL(ctx, orgUser.OrganizationID, req.URL)
-			if err != nil {
-				logger.LogError("Error getting single url for org", err)
-				return c.JSON(http.StatusInternalServerError, models.Error{
-					Message: constants.ERRORMSG_UNKNOWN_ERROR,
-					Code:    constants.ERRORCODE_UNKNOWN_ERROR,
-				})
-			}
-			return c.JSON(http.StatusOK, oldURL)
+		if !strings.Contains(err.Error(), "violates unique constraint") {
+			return nil, err
 		}
-		return c.JSON(http.StatusInternalServerError, models.Error{
-			Message: constants.ERRORMSG_UNKNOWN_ERROR,
-			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
-		})
+		resp, err = h.db.GetSingleURLForOrganizationURL(ctx, orgID, url)
+		if err != nil {
+			return nil, err
+		}
+	} else if resp, err = h.db.GetSingleURLForOrganization(ctx, orgID, urlOrg.ID); err != nil {
+		return nil, err
+	}
+	if urlStore.Status != constants.BookmarkStatusPending {
+		return resp, nil
 	}
+	// imports of any size do not hold the interactive lane back, river skips the url when it is queued already.
+	// A save that could not be queued fails, its retry queues the url again
+	if _, err := h.bg.SubmitURLs(ctx, []string{urlStore.URL}, orgID, bg.LaneInteractive, ""); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
 
-	resp, err := h.db.GetSingleURLForOrganization(ctx, orgUser.OrganizationID, urlOrg.ID)
+// replayBookmark answers a save with an idempotency key that was given before
+func (h *HandlersImplementation) replayBookmark(c echo.Context, orgID, url string, seen *models.IdempotencyKey) error {
+	if seen.URL != url {
+		return c.JSON(http.StatusUnprocessableEntity, models.Error{
+			Message: constants.ERRORMSG_IDEMPOTENCY_KEY_REUSED,
+			Code:    constants.ERRORCODE_IDEMPOTENCY_KEY_REUSED,
+		})
+	}
+	ctx := c.Request().Context()
+	if seen.URLOrgID == "" {
+		// the first save is still running, or saved the bookmark but could not complete the key
+		resp, err := h.db.GetSingleURLForOrganizationURL(ctx, orgID, url)
+		if err != nil {
+			return c.JSON(http.StatusConflict, models.Error{
+				Message: constants.ERRORMSG_IDEMPOTENCY_KEY_IN_FLIGHT,
+				Code:    constants.ERRORCODE_IDEMPOTENCY_KEY_IN_FLIGHT,
+			})
+		}
+		services.ProxyImages(resp)
+		return c.JSON(http.StatusOK, resp)
+	}
+	resp, err := h.db.GetSingleURLForOrganization(ctx, orgID, seen.URLOrgID)
 	if err != nil {
 		logger.LogError("Error getting single url for org", err)
 		return c.JSON(http.StatusInternalServerError, models.Error{
@@ -125,11 +175,10 @@ func (h *HandlersImplementation) AddNewBookmark(c echo.Context) error {
 			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
 		})
 	}
-
-	go h.svc.DoContentCompleteByID(urlStore.URL)
-
+	services.ProxyImages(resp)
 	return c.JSON(http.StatusOK, resp)
 }
+
 func (h *HandlersImplementation) AddBulkNewBookmarks(c echo.Context) error {
 	ctx := c.Request().Context()
 	var req models.CreateBulkBookmarkRequest
@@ -146,24 +195,52 @@ func (h *HandlersImplementation) AddBulkNewBookmarks(c echo.Context) error {
 		}
 	}
 	orgUser := mddls.GetOrgUserFromEchoContext(c)
-	//create a new job
-	validURLs := make([]string, 0)
-	for _, url := range req.URLs {
-		if validators.IsValidURL(url) {
-			validURLs = append(validURLs, url)
-		}
+
+	batch := models.ImportBatch{
+		ID:     h.db.NewID("import"),
+		OrgID:  orgUser.OrganizationID,
+		UserID: orgUser.UserID,
+		Source: importSource(req.Source),
+	}
+	urls, pending, err := h.importBatchURLs(ctx, orgUser.OrganizationID, req.URLs)
+	if err != nil {
+		logger.LogError("Error checking imported urls", orgUser.OrganizationID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{
+			Message: constants.ERRORMSG_UNKNOWN_ERROR,
+			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
+		})
+	}
+	if err := h.db.InsertImportBatch(ctx, batch, urls); err != nil {
+		logger.LogError("Error inserting import batch", orgUser.OrganizationID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{
+			Message: constants.ERRORMSG_UNKNOWN_ERROR,
+			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
+		})
 	}
-	if len(validURLs) > 0 {
-		validURLChunks := utils.ChunkStringArray(validURLs, h.config.MaxWorkers)
 
-		for _, validURLsChunked := range validURLChunks {
-			h.bg.SubmitURLs(ctx, validURLsChunked, orgUser.OrganizationID)
+	if len(pending) > 0 {
+		results, err := h.bg.SubmitURLs(ctx, pending, orgUser.OrganizationID, bg.LaneBulk, batch.ID)
+		if err != nil {
+			logger.LogError("Error submitting urls", orgUser.OrganizationID, err)
+			for _, url := range pending {
+				h.db.UpdateImportBatchURLOutcome(ctx, batch.ID, url, constants.ImportOutcomeFailed, "could not be queued")
+			}
+		}
+		for i, result := range results {
+			if result.UniqueSkippedAsDuplicate {
+				h.db.UpdateImportBatchURLOutcome(ctx, batch.ID, pending[i], constants.ImportOutcomeAlreadyPresent, "already being saved")
+			}
 		}
-
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"ok": "ok",
-	})
+	created, err := h.db.GetImportBatch(ctx, orgUser.OrganizationID, batch.ID)
+	if err != nil {
+		logger.LogError("Error getting import batch", batch.ID, err)
+		return c.JSON(http.StatusInternalServerError, models.Error{
+			Message: constants.ERRORMSG_UNKNOWN_ERROR,
+			Code:    constants.ERRORCODE_UNKNOWN_ERROR,
+		})
+	}
+	return c.JSON(http.StatusOK, created)
 
 }
@@ -0,0 +1,145 @@
+package keywords
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxPhraseWords keeps RAKE from suggesting half sentences
+	maxPhraseWords = 3
+	// maxTagLength is the longest tag kept, site hints included
+	maxTagLength = 40
+	// repeatedPhraseWords is the length of text from which a keyphrase has to appear at least twice
+	repeatedPhraseWords = 300
+)
+
+// Normalize turns a keyword into a tag, lowercase words joined by hyphens like GitHub topics.
+// Empty means the keyword does not make a tag
+func Normalize(keyword string) string {
+	words := strings.FieldsFunc(strings.ToLower(keyword), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#' && r != '.'
+	})
+	for i, word := range words {
+		words[i] = strings.Trim(word, ".")
+	}
+	tag := strings.Trim(strings.Join(words, "-"), "-")
+	if tag == "" || len(tag) > maxTagLength {
+		return ""
+	}
+	return tag
+}
+
+// Merge normalizes keywords from several sources into at most limit tags, earlier lists and keywords win
+func Merge(limit int, lists ...[]string) []string {
+	tags := make([]string, 0, limit)
+	seen := map[string]bool{}
+	for _, list := range lists {
+		for _, keyword := range list {
+			if len(tags) == limit {
+				return tags
+			}
+			if tag := Normalize(keyword); tag != "" && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// isPhraseBreak tells if a rune ends a sentence or clause, keyphrases never span one
+func isPhraseBreak(r rune) bool {
+	return unicode.IsPunct(r) && r != '-' && r != '\'' && r != '#' || unicode.IsSymbol(r) && r != '+'
+}
+
+// candidates splits text into runs of words between stopwords and punctuation
+func candidates(text string, stop map[string]bool) [][]string {
+	phrases := make([][]string, 0)
+	for _, clause := range strings.FieldsFunc(strings.ToLower(text), isPhraseBreak) {
+		phrase := make([]string, 0, maxPhraseWords)
+		flush := func() {
+			if len(phrase) > 0 && len(phrase) <= maxPhraseWords {
+				phrases = append(phrases, phrase)
+			}
+			phrase = make([]string, 0, maxPhraseWords)
+		}
+		for _, word := range strings.Fields(clause) {
+			word = strings.Trim(word, "-'")
+			if stop[word] || !keywordLike(word) {
+				flush()
+				continue
+			}
+			phrase = append(phrase, word)
+		}
+		flush()
+	}
+	return phrases
+}
+
+// keywordLike leaves out numbers and words too short to mean anything alone
+func keywordLike(word string) bool {
+	letters := 0
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters >= 2 && len([]rune(word)) >= 3
+}
+
+// Extract returns the top keyphrases of a text by RAKE, words are scored by how many other words
+// they appear next to over how often they appear, phrases by the sum of their words.
+// Languages without a stopword list get none
+func Extract(text, language string, limit int) []string {
+	stop, ok := stopwords[language]
+	if !ok || limit <= 0 {
+		return nil
+	}
+
+	phrases := candidates(text, stop)
+	frequency := map[string]int{}
+	degree := map[string]int{}
+	phraseCount := map[string]int{}
+	for _, phrase := range phrases {
+		for _, word := range phrase {
+			frequency[word]++
+			degree[word] += len(phrase)
+		}
+		phraseCount[strings.Join(phrase, " ")]++
+	}
+
+	// in longer texts a phrase said once is more likely noise than a topic
+	minCount := 1
+	if len(strings.Fields(text)) >= repeatedPhraseWords {
+		minCount = 2
+	}
+
+	scores := map[string]float64{}
+	for phrase, count := range phraseCount {
+		if count < minCount {
+			continue
+		}
+		score := 0.0
+		for _, word := range strings.Fields(phrase) {
+			score += float64(degree[word]) / float64(frequency[word])
+		}
+		scores[phrase] = score
+	}
+
+	ranked := make([]string, 0, len(scores))
+	for phrase := range scores {
+		ranked = append(ranked, phrase)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if scores[ranked[i]] != scores[ranked[j]] {
+			return scores[ranked[i]] > scores[ranked[j]]
+		}
+		if phraseCount[ranked[i]] != phraseCount[ranked[j]] {
+			return phraseCount[ranked[i]] > phraseCount[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+	return Merge(limit, ranked)
+}
@@ -0,0 +1,62 @@
+package keywords
+
+import "strings"
+
+// stopwords split sentences into candidate keyphrases, languages without a list get no keyphrases.
+// Besides the function words they hold the verbs and adverbs that fill every page but never make a good tag
+var stopwords = map[string]map[string]bool{
+	"en": wordSet(`a about above after again against all almost also although always am an and another any are around as at
+		back be because been before being below between both but by can could did do does doing done down during each either
+		else enough even ever every few first for from further get gets getting go goes going got had has have having he her
+		here hers herself him himself his how however i if in into is it its itself just last later least less let like made
+		make makes many may me might more most much must my myself need needs new next no nor not now of off often old on once
+		one only or other others our ours ourselves out over own per please quite rather really same see seen several shall
+		she should since so some something still such than that the their theirs them themselves then there these they this
+		those though through thus to too under until up upon us use used uses using very via want was way we well were what
+		when where whether which while who whom whose why will with within without would yet you your yours yourself yourselves`),
+	"de": wordSet(`aber alle allem allen aller alles als also am an ander andere anderen auch auf aus bei beim bin bis bist da
+		dabei damit dann das dass dazu dein deine dem den denn der des dich die dies diese diesem diesen dieser dieses dir doch
+		dort du durch ein eine einem einen einer eines er es etwa etwas euch euer für gegen gibt hat hatte habe haben hier hin
+		hinter ich ihm ihn ihnen ihr ihre im immer in ins ist ja jede jedem jeden jeder jedes jetzt kann kein keine können man
+		mehr mein meine mich mir mit muss nach nicht nichts noch nun nur ob oder ohne schon sehr sein seine sich sie sind so
+		soll sollte sondern sowie über um und uns unser unter viel vom von vor war waren was weil welche wenn wer werden wie
+		wieder wir wird wo wurde würde zu zum zur zwischen`),
+	"fr": wordSet(`à afin ai aient ainsi alors au aucun aussi autre autres aux avec avez avoir avons bien ce ceci cela celle
+		celles celui ces cet cette chaque chez comme comment dans de des donc dont du elle elles en encore entre est et été
+		être eu fait faire font ici il ils je jusqu la le les leur leurs lui ma mais me même mes moi mon ne ni nos notre nous
+		on ont ou où par parce pas peu peut plus pour pourquoi qu quand que quel quelle quelles quels qui sa sans se selon ses
+		si son sont sous sur ta te tes toi ton tous tout toute toutes très tu un une vers vos votre vous`),
+	"es": wordSet(`a al algo algunos ante antes aquí así aun aunque cada como con contra cual cuando de del desde donde dos el
+		ella ellas ellos en entre era es esa ese eso esta está están este esto estos fue fueron ha han hasta hay la las le les
+		lo los más me mi mientras muy nada ni no nos nosotros o otra otro otros para pero poco por porque puede qué que quien
+		se sea ser si sí sin sobre solo son su sus también tan te tiene todo todos tu un una unas uno unos y ya yo`),
+	"pt": wordSet(`a à ao aos as às até com como da das de dela dele deles depois do dos e é ela elas ele eles em entre era
+		essa esse esta está este eu foi foram há isso isto já la lhe mais mas me mesmo meu minha muito na nas não nem no nos
+		nós o os ou para pela pelo pode por porque quando que quem se sem ser seu seus só sua suas também te tem ter um uma
+		umas uns você vocês`),
+	"it": wordSet(`a ad al alla alle allo agli anche ancora avere c che chi ci come con cosa cui da dal dalla dei del della
+		delle dello di dove e è ed era essere fa gli ha hanno i il in io la le lei li lo loro lui ma mi mio molto ne nei nel
+		nella nelle no noi non nostro o ogni per però più poi può quale quando quello questa questo se sei si sia sono su sua
+		suo sul sulla tra tu tutti tutto un una uno voi`),
+	"nl": wordSet(`aan al alles als altijd ander andere ben bij daar dan dat de der deze die dit doch doen door dus een eens
+		en er ge geen geweest haar had heb hebben heeft hem het hier hij hoe hun iets ik in is ja je jouw kan kon kunnen maar
+		me meer men met mij mijn moet na naar niet niets nog nu of om omdat ons ook op over reeds te tegen toch toen tot u uit
+		uw van veel voor want waren was wat we wel werd wezen wie wij wil worden zal ze zei zelf zich zij zijn zo zonder zou`),
+	"sv": wordSet(`alla allt att av blev bli blir de dem den denna deras dess det detta dig din dina ditt du där efter ej
+		eller en er ett från för ha hade han hans har henne hennes hon honom hur här i icke ingen inom inte jag ju kan kunde
+		man med mellan men mig min mina mitt mot mycket ni nu när någon något några och om oss på samma sedan sig sin sina
+		sitt själv skulle som så till under upp ut utan vad var vara varför varit vars vem vi vid vilken än är åt över`),
+}
+
+// IsStopword tells if a lowercase word is a stopword of a language
+func IsStopword(language, word string) bool {
+	return stopwords[language][word]
+}
+
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
@@ -0,0 +1,229 @@
+package lang
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Undetermined is stored for texts too short or too mixed to tell the language of
+const Undetermined = "und"
+
+const (
+	// trigramProfileSize is how many of the most frequent trigrams make up a profile
+	trigramProfileSize = 300
+	// maxDetectRunes is how much of a text is looked at, the start of a page says enough
+	maxDetectRunes = 10000
+	// minDetectLetters is the least number of letters a text needs before we guess
+	minDetectLetters = 20
+)
+
+// Language is a language we can detect and how the search index treats it
+type Language struct {
+	Code string
+	Name string
+	// Stemmer is the name of the stemmer of the search index for the language, empty when it has none
+	Stemmer string
+}
+
+// Languages are the languages Detect returns, besides Undetermined
+var Languages = []Language{
+	{Code: "en", Name: "English", Stemmer: "English"},
+	{Code: "de", Name: "German", Stemmer: "German"},
+	{Code: "fr", Name: "French", Stemmer: "French"},
+	{Code: "es", Name: "Spanish", Stemmer: "Spanish"},
+	{Code: "pt", Name: "Portuguese", Stemmer: "Portuguese"},
+	{Code: "it", Name: "Italian", Stemmer: "Italian"},
+	{Code: "nl", Name: "Dutch", Stemmer: "Dutch"},
+	{Code: "sv", Name: "Swedish", Stemmer: "Swedish"},
+	{Code: "ru", Name: "Russian", Stemmer: "Russian"},
+	{Code: "uk", Name: "Ukrainian"},
+	{Code: "el", Name: "Greek", Stemmer: "Greek"},
+	{Code: "ar", Name: "Arabic", Stemmer: "Arabic"},
+	{Code: "he", Name: "Hebrew"},
+	{Code: "hi", Name: "Hindi"},
+	{Code: "th", Name: "Thai"},
+	{Code: "zh", Name: "Chinese"},
+	{Code: "ja", Name: "Japanese"},
+	{Code: "ko", Name: "Korean"},
+}
+
+// Known tells if code is one of Languages or Undetermined
+func Known(code string) bool {
+	if code == Undetermined {
+		return true
+	}
+	for _, language := range Languages {
+		if language.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCJK tells if a language is written without spaces between words
+func IsCJK(code string) bool {
+	return code == "zh" || code == "ja" || code == "ko"
+}
+
+// profile ranks the trigrams of a text, the most frequent first
+type profile map[string]int
+
+// profiles of the latin script languages, built from the samples when the package loads
+var profiles = map[string]profile{}
+
+func init() {
+	for code, sample := range samples {
+		profiles[code] = newProfile(sample)
+	}
+}
+
+// newProfile counts the trigrams of the words of a text, words are padded with spaces
+// so beginnings and endings count as trigrams of their own
+func newProfile(text string) profile {
+	counts := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+
+	trigrams := make([]string, 0, len(counts))
+	for trigram := range counts {
+		trigrams = append(trigrams, trigram)
+	}
+	sort.Slice(trigrams, func(i, j int) bool {
+		if counts[trigrams[i]] != counts[trigrams[j]] {
+			return counts[trigrams[i]] > counts[trigrams[j]]
+		}
+		return trigrams[i] < trigrams[j]
+	})
+	if len(trigrams) > trigramProfileSize {
+		trigrams = trigrams[:trigramProfileSize]
+	}
+
+	p := make(profile, len(trigrams))
+	for rank, trigram := range trigrams {
+		p[trigram] = rank
+	}
+	return p
+}
+
+// distance is the out-of-place measure of Cavnar and Trenkle, how far the ranks of the
+// trigrams of a text are from their ranks in a language, missing trigrams cost the most
+func (p profile) distance(language profile) int {
+	d := 0
+	for trigram, rank := range p {
+		if languageRank, ok := language[trigram]; ok {
+			if rank > languageRank {
+				d += rank - languageRank
+			} else {
+				d += languageRank - rank
+			}
+		} else {
+			d += trigramProfileSize
+		}
+	}
+	return d
+}
+
+// scriptCounts counts the letters of a text per writing system
+type scriptCounts struct {
+	letters, latin, han, kana, hangul, cyrillic, ukrainian, greek, arabic, hebrew, devanagari, thai int
+}
+
+func countScripts(runes []rune) scriptCounts {
+	var c scriptCounts
+	for _, r := range runes {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		c.letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			c.latin++
+		case unicode.Is(unicode.Han, r):
+			c.han++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			c.kana++
+		case unicode.Is(unicode.Hangul, r):
+			c.hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			c.cyrillic++
+			// letters russian does not have
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				c.ukrainian++
+			}
+		case unicode.Is(unicode.Greek, r):
+			c.greek++
+		case unicode.Is(unicode.Arabic, r):
+			c.arabic++
+		case unicode.Is(unicode.Hebrew, r):
+			c.hebrew++
+		case unicode.Is(unicode.Devanagari, r):
+			c.devanagari++
+		case unicode.Is(unicode.Thai, r):
+			c.thai++
+		}
+	}
+	return c
+}
+
+// Detect returns the code of the language a text is written in, Undetermined when it cannot tell.
+// Scripts used by a single language decide by themselves, latin text is told apart by trigrams
+func Detect(text string) string {
+	runes := []rune(text)
+	if len(runes) > maxDetectRunes {
+		runes = runes[:maxDetectRunes]
+	}
+	c := countScripts(runes)
+	if c.letters < minDetectLetters {
+		return Undetermined
+	}
+
+	// japanese mixes kana into han, a few kana are enough to tell it from chinese
+	cjk := c.han + c.kana + c.hangul
+	switch {
+	case cjk*2 > c.letters || cjk > c.latin:
+		switch {
+		case c.hangul > c.han+c.kana:
+			return "ko"
+		case c.kana*10 > cjk:
+			return "ja"
+		case c.han > 0:
+			return "zh"
+		}
+	case c.cyrillic*2 > c.letters:
+		if c.ukrainian*50 > c.cyrillic {
+			return "uk"
+		}
+		return "ru"
+	case c.greek*2 > c.letters:
+		return "el"
+	case c.arabic*2 > c.letters:
+		return "ar"
+	case c.hebrew*2 > c.letters:
+		return "he"
+	case c.devanagari*2 > c.letters:
+		return "hi"
+	case c.thai*2 > c.letters:
+		return "th"
+	}
+	if c.latin*2 <= c.letters {
+		return Undetermined
+	}
+
+	textProfile := newProfile(string(runes))
+	best, bestDistance := Undetermined, -1
+	for _, language := range Languages {
+		languageProfile, ok := profiles[language.Code]
+		if !ok {
+			continue
+		}
+		if d := textProfile.distance(languageProfile); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = language.Code, d
+		}
+	}
+	return best
+}
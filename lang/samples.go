@@ -0,0 +1,98 @@
+package lang
+
+// samples are ordinary prose of each latin script language, their trigrams are what Detect compares texts with.
+// Function words matter most, they are what every page of a language has in common
+var samples = map[string]string{
+	"en": `The first thing you should know about this project is that it was never meant to be used by other people.
+It started as a small tool that I wrote for myself on a rainy weekend, because I was tired of losing the links
+that I had saved in different places. Over the years it has grown into something that many teams depend on every
+day, and with that came the responsibility to make it reliable, fast and easy to understand. In this article we
+will look at how the system works, which decisions we made along the way and what we would do differently if we
+had to start again today. There are no perfect answers here, only tradeoffs that made sense at the time. If you
+have any questions about the way things are done, please open an issue and we will be happy to talk about it.
+Most of the work happens in the background, where pages are fetched, their text is extracted and everything is
+written to the database so that it can be searched later. When something goes wrong, the error is recorded and
+the job is retried after a while, which is usually enough for pages that were only temporarily unavailable.`,
+
+	"de": `Das erste, was man über dieses Projekt wissen sollte, ist, dass es nie für andere Menschen gedacht war.
+Es begann als kleines Werkzeug, das ich an einem verregneten Wochenende für mich selbst geschrieben habe, weil ich
+es leid war, die Links zu verlieren, die ich an verschiedenen Stellen gespeichert hatte. Im Laufe der Jahre ist
+daraus etwas geworden, auf das sich viele Teams jeden Tag verlassen, und damit kam auch die Verantwortung, es
+zuverlässig, schnell und leicht verständlich zu machen. In diesem Artikel sehen wir uns an, wie das System
+funktioniert, welche Entscheidungen wir auf dem Weg getroffen haben und was wir heute anders machen würden, wenn
+wir noch einmal von vorne anfangen müssten. Es gibt hier keine perfekten Antworten, nur Abwägungen, die damals
+sinnvoll waren. Wenn Sie Fragen zu der Art und Weise haben, wie die Dinge gemacht werden, öffnen Sie bitte ein
+Ticket und wir sprechen gerne darüber. Die meiste Arbeit geschieht im Hintergrund, wo Seiten abgerufen werden,
+ihr Text extrahiert wird und alles in die Datenbank geschrieben wird, damit es später durchsucht werden kann.
+Wenn etwas schiefgeht, wird der Fehler festgehalten und die Aufgabe nach einer Weile noch einmal versucht.`,
+
+	"fr": `La première chose à savoir sur ce projet, c'est qu'il n'a jamais été conçu pour être utilisé par d'autres
+personnes. Il a commencé comme un petit outil que j'ai écrit pour moi-même pendant un week-end pluvieux, parce que
+j'en avais assez de perdre les liens que j'avais enregistrés à différents endroits. Au fil des années, il est
+devenu quelque chose dont de nombreuses équipes dépendent chaque jour, et avec cela est venue la responsabilité de
+le rendre fiable, rapide et facile à comprendre. Dans cet article, nous allons voir comment le système fonctionne,
+quelles décisions nous avons prises en chemin et ce que nous ferions autrement si nous devions recommencer
+aujourd'hui. Il n'y a pas de réponses parfaites ici, seulement des compromis qui avaient du sens à l'époque. Si
+vous avez des questions sur la manière dont les choses sont faites, n'hésitez pas à ouvrir un ticket et nous serons
+heureux d'en parler. La plupart du travail se fait en arrière-plan, où les pages sont récupérées, leur texte est
+extrait et tout est écrit dans la base de données afin de pouvoir être recherché plus tard. Lorsque quelque chose
+ne va pas, l'erreur est enregistrée et la tâche est relancée après un certain temps.`,
+
+	"es": `Lo primero que debes saber sobre este proyecto es que nunca fue pensado para que lo usaran otras personas.
+Empezó como una pequeña herramienta que escribí para mí mismo durante un fin de semana lluvioso, porque estaba
+cansado de perder los enlaces que había guardado en diferentes lugares. Con los años se ha convertido en algo de lo
+que muchos equipos dependen todos los días, y con eso llegó la responsabilidad de hacerlo fiable, rápido y fácil de
+entender. En este artículo veremos cómo funciona el sistema, qué decisiones tomamos por el camino y qué haríamos de
+otra manera si tuviéramos que empezar de nuevo hoy. Aquí no hay respuestas perfectas, solo compromisos que tenían
+sentido en su momento. Si tienes alguna pregunta sobre la forma en que se hacen las cosas, por favor abre una
+incidencia y estaremos encantados de hablar de ello. La mayor parte del trabajo ocurre en segundo plano, donde se
+descargan las páginas, se extrae su texto y todo se escribe en la base de datos para que se pueda buscar más
+tarde. Cuando algo sale mal, el error se registra y la tarea se vuelve a intentar después de un tiempo, lo que
+normalmente es suficiente para las páginas que solo estaban caídas temporalmente.`,
+
+	"pt": `A primeira coisa que você deve saber sobre este projeto é que ele nunca foi pensado para ser usado por outras
+pessoas. Começou como uma pequena ferramenta que eu escrevi para mim mesmo num fim de semana chuvoso, porque estava
+cansado de perder os links que tinha guardado em lugares diferentes. Ao longo dos anos, ele se tornou algo de que
+muitas equipes dependem todos os dias, e com isso veio a responsabilidade de torná-lo confiável, rápido e fácil de
+entender. Neste artigo vamos ver como o sistema funciona, quais decisões tomamos pelo caminho e o que faríamos de
+forma diferente se tivéssemos que começar de novo hoje. Não há respostas perfeitas aqui, apenas escolhas que faziam
+sentido na época. Se você tiver alguma dúvida sobre a maneira como as coisas são feitas, por favor abra um chamado
+e teremos prazer em conversar sobre isso. A maior parte do trabalho acontece em segundo plano, onde as páginas são
+buscadas, o seu texto é extraído e tudo é gravado no banco de dados para que possa ser pesquisado mais tarde.
+Quando algo dá errado, o erro é registrado e a tarefa é tentada novamente depois de um tempo, o que normalmente
+basta para as páginas que estavam fora do ar apenas temporariamente.`,
+
+	"it": `La prima cosa che dovresti sapere su questo progetto è che non è mai stato pensato per essere usato da altre
+persone. È nato come un piccolo strumento che ho scritto per me stesso durante un fine settimana piovoso, perché
+ero stanco di perdere i collegamenti che avevo salvato in posti diversi. Nel corso degli anni è diventato qualcosa
+da cui molte squadre dipendono ogni giorno, e con questo è arrivata la responsabilità di renderlo affidabile, veloce
+e facile da capire. In questo articolo vedremo come funziona il sistema, quali decisioni abbiamo preso lungo la
+strada e che cosa faremmo in modo diverso se dovessimo ricominciare oggi. Non ci sono risposte perfette, solo
+compromessi che avevano senso in quel momento. Se hai domande sul modo in cui le cose vengono fatte, apri una
+segnalazione e saremo felici di parlarne. La maggior parte del lavoro avviene in background, dove le pagine vengono
+scaricate, il loro testo viene estratto e tutto viene scritto nella base di dati in modo che possa essere cercato
+più tardi. Quando qualcosa va storto, l'errore viene registrato e il lavoro viene riprovato dopo un po' di tempo,
+il che di solito basta per le pagine che erano irraggiungibili solo per poco.`,
+
+	"nl": `Het eerste wat je over dit project moet weten, is dat het nooit bedoeld was om door andere mensen gebruikt te
+worden. Het begon als een klein hulpmiddel dat ik op een regenachtig weekend voor mezelf heb geschreven, omdat ik
+het zat was om de links te verliezen die ik op verschillende plekken had opgeslagen. In de loop van de jaren is het
+uitgegroeid tot iets waar veel teams elke dag van afhankelijk zijn, en daarmee kwam ook de verantwoordelijkheid om
+het betrouwbaar, snel en makkelijk te begrijpen te maken. In dit artikel kijken we hoe het systeem werkt, welke
+beslissingen we onderweg hebben genomen en wat we anders zouden doen als we vandaag opnieuw moesten beginnen. Er
+zijn hier geen perfecte antwoorden, alleen afwegingen die destijds zinvol waren. Als je vragen hebt over de manier
+waarop dingen worden gedaan, open dan een melding en we praten er graag over. Het meeste werk gebeurt op de
+achtergrond, waar pagina's worden opgehaald, hun tekst wordt uitgelezen en alles in de database wordt geschreven
+zodat het later doorzocht kan worden. Als er iets misgaat, wordt de fout vastgelegd en wordt de taak na een tijdje
+opnieuw geprobeerd, wat meestal genoeg is voor pagina's die maar even onbereikbaar waren.`,
+
+	"sv": `Det första du bör veta om det här projektet är att det aldrig var tänkt att användas av andra människor. Det
+började som ett litet verktyg som jag skrev åt mig själv under en regnig helg, eftersom jag var trött på att tappa
+bort länkarna som jag hade sparat på olika ställen. Med åren har det vuxit till något som många team är beroende av
+varje dag, och med det kom ansvaret att göra det pålitligt, snabbt och lätt att förstå. I den här artikeln tittar
+vi på hur systemet fungerar, vilka beslut vi tog längs vägen och vad vi skulle göra annorlunda om vi var tvungna att
+börja om i dag. Det finns inga perfekta svar här, bara avvägningar som var rimliga då. Om du har frågor om hur saker
+och ting görs, öppna gärna ett ärende så pratar vi gärna om det. Det mesta av arbetet sker i bakgrunden, där sidor
+hämtas, deras text plockas ut och allt skrivs till databasen så att det går att söka i senare. När något går fel
+sparas felet och jobbet körs igen efter en stund, vilket oftast räcker för sidor som bara var nere tillfälligt.`,
+}
@@ -19,6 +19,15 @@ func StartLogger(env string) {
 	sugar.Info("Logger started")
 }
 
+// log debug, development builds only print it
+func LogDebug(message string, fields ...interface{}) {
+	if fields == nil {
+		sugar.Debug(message)
+		return
+	}
+	sugar.Debug(message, fields)
+}
+
 // log info
 func LogInfo(message string, fields ...interface{}) {
 	if fields == nil {
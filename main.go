@@ -7,6 +7,8 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/common-nighthawk/go-figure"
@@ -14,6 +16,7 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/rajnandan1/smaraka/bg"
+	"github.com/rajnandan1/smaraka/blobstore"
 	"github.com/rajnandan1/smaraka/config"
 	"github.com/rajnandan1/smaraka/constants"
 	"github.com/rajnandan1/smaraka/crypt"
@@ -28,7 +31,7 @@ import (
 
 func main() {
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	//load config
@@ -36,6 +39,9 @@ func main() {
 	if configErr != nil {
 		log.Fatalf("error loading config: %v", configErr)
 	}
+	if err := config.SetModeFromArgs(os.Args[1:]); err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
 
 	postgresConnectionString := config.GetPostgresURL()
 	if config.Environment == constants.EnvDevelopment {
@@ -49,6 +55,7 @@ func main() {
 	htmlPolicy := bluemonday.UGCPolicy()
 	// htmlPolicy.AllowElements("b", "strong", "p", "i", "em", "h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol", "li", "blockquote", "code", "pre", "figure", "figcaption", "div", "span", "br", "hr", "td", "th", "tr", "table", "thead", "tbody", "tfoot", "caption")
 
+	// every mode migrates, a process started alongside another waits on the migration lock
 	migrations.DoPostgresMigrationsUp(postgresConnectionString)
 
 	logger.StartLogger(config.Environment)
@@ -79,17 +86,35 @@ func main() {
 
 	migrations.DoRiverMigrationUp(postgresDb)
 
-	services, err := services.ConfigureServices(postgresDb, crypto, htmlPolicy)
+	browserPool := services.NewBrowserPool(config.BrowserTabs, config.BrowserReservedTabs)
+	crawlScheduler := services.NewCrawlScheduler(config.CrawlHostConcurrency, time.Duration(config.CrawlMinDelayMs)*time.Millisecond)
+	fetcher, err := services.ConfigureFetcher(config.FetchStrategy, config.FetchRules, browserPool, time.Duration(config.PageTimeout)*time.Second, int64(config.MaxFetchMB)<<20, crawlScheduler)
+	if err != nil {
+		log.Fatalf("error configuring fetcher: %v", err)
+	}
+
+	blobs, err := blobstore.ConfigureStore(*config, postgresDb)
+	if err != nil {
+		log.Fatalf("error configuring blob store: %v", err)
+	}
+
+	// progress reaches the browser through the server, a worker only publishes it
+	progressHub := services.NewProgressHub(postgresDb)
+	if config.RunsServer() {
+		go progressHub.Run(ctx)
+	}
+
+	services, err := services.ConfigureServices(postgresDb, crypto, htmlPolicy, fetcher, blobs)
 	if err != nil {
 		panic(err)
 	}
 	//configure bg
-	bgjb, bgjbErr := bg.ConfigureBackground(ctx, postgresDb, services, config.MaxWorkers)
+	bgjb, bgjbErr := bg.ConfigureBackground(ctx, postgresDb, services, browserPool, *config)
 	if bgjbErr != nil {
 		log.Fatalf("error configuring background: %v", bgjbErr)
 	}
 
-	handlers, err := handlers.ConfigureHandlers(postgresDb, bgjb, services, *config, crypto)
+	handlers, err := handlers.ConfigureHandlers(postgresDb, bgjb, services, *config, crypto, blobs, progressHub)
 	if err != nil {
 		panic(err)
 	}
@@ -109,6 +134,10 @@ func main() {
 	e.GET("/api/ui/url/all-bookmarks", handlers.GetAllBookmarks, authMdl, orgMdl)
 
 	e.GET("/api/ui/url/get-bookmark/:id", handlers.GetBookmarkByID, authMdl, orgMdl)
+	e.GET("/api/ui/url/bookmark-diff/:id", handlers.GetBookmarkDiff, authMdl, orgMdl)
+	e.GET("/api/ui/url/reader/:id", handlers.GetReaderView, authMdl, orgMdl)
+	e.POST("/api/ui/url/summary/:id", handlers.RegenerateSummary, authMdl, orgMdl)
+	e.GET("/api/ui/url/broken-links", handlers.GetBrokenLinks, authMdl, orgMdl)
 	e.DELETE("/api/ui/url/delete-bookmark/:id", handlers.DeleteBookmarkByID, authMdl, orgMdl)
 	e.GET("/api/ui/url/get-bookmark-count", handlers.GetBookmarkCount, authMdl, orgMdl)
 	e.PATCH("/api/ui/url/index-bookmark/:id", handlers.IndexBookmarkByID, authMdl, orgMdl)
@@ -116,6 +145,7 @@ func main() {
 	e.POST("/api/ui/url/import-browsers", handlers.FileUploadBrowsers, authMdl, orgMdl)
 	e.POST("/api/ui/url/import-bulk", handlers.AddBulkNewBookmarks, authMdl, orgMdl)
 	e.POST("/api/ui/url/delete-bulk", handlers.BulkDelete, authMdl, orgMdl)
+	e.POST("/api/ui/url/accept-tags", handlers.AcceptSuggestedTags, authMdl, orgMdl)
 	e.PATCH("/api/ui/url/bookmark-update/:id", handlers.PatchTextDataByID, authMdl, orgMdl)
 	e.GET("/api/ui/url/view-schedules", handlers.GetOrgSchedules, authMdl, orgMdl)
 	e.PATCH("/api/ui/url/update-schedules", handlers.UpdateOrgSchedule, authMdl, orgMdl)
@@ -124,29 +154,54 @@ func main() {
 	e.POST("/api/ui/url/run-schedules", handlers.RunOrgSchedules, authMdl, orgMdl)
 
 	e.GET("/api/ui/url/bookmarks-queue", handlers.JobQueueStatus, authMdl, orgMdl)
+	e.GET("/api/ui/jobs", handlers.GetIngestJobs, authMdl, orgMdl)
+	e.GET("/api/ui/jobs/events", handlers.StreamIngestProgress, authMdl, orgMdl)
+	e.POST("/api/ui/jobs/imports/pause", handlers.PauseImports, authMdl, orgMdl)
+	e.POST("/api/ui/jobs/imports/resume", handlers.ResumeImports, authMdl, orgMdl)
+	e.POST("/api/ui/jobs/imports/cancel", handlers.CancelImports, authMdl, orgMdl)
+	e.GET("/api/ui/imports", handlers.GetImportBatches, authMdl, orgMdl)
+	e.GET("/api/ui/imports/:id", handlers.GetImportBatch, authMdl, orgMdl)
+	e.GET("/api/ui/imports/:id/report.csv", handlers.GetImportBatchReport, authMdl, orgMdl)
+	e.GET("/api/ui/dead-letters", handlers.GetDeadLetters, authMdl, orgMdl)
+	e.POST("/api/ui/dead-letters/retry", handlers.RetryDeadLetters, authMdl, orgMdl)
+	e.POST("/api/ui/dead-letters/keep", handlers.KeepDeadLetters, authMdl, orgMdl)
 	e.GET("/api/ui/url/bookmarks-export", handlers.ExportBookmarks, authMdl, orgMdl)
 
+	e.GET("/snapshot/:id", handlers.GetSnapshot, authMdl, orgMdl)
+	e.GET("/document/:id", handlers.GetDocument, authMdl, orgMdl)
+	e.GET("/thumbnail/:id", handlers.GetThumbnail, authMdl, orgMdl)
+	e.GET("/image/:id/:kind", handlers.GetImage, authMdl, orgMdl)
+	e.GET("/api/ui/org/storage", handlers.GetStorageUsage, authMdl, orgMdl)
+	//signed urls of the local blob store carry their own authorization
+	if localStore, ok := blobs.Unwrap().(*blobstore.LocalStore); ok {
+		e.GET(blobstore.LocalBlobPath+"*", echo.WrapHandler(http.StripPrefix(strings.TrimSuffix(blobstore.LocalBlobPath, "/"), localStore)))
+	}
+
 	myFigure := figure.NewColorFigure("OkBookmarks", "doom", "yellow", true)
 	myFigure.Print()
 
-	go func() {
-		if err := e.Start(":" + strconv.Itoa(config.Port)); err != nil && err != http.ErrServerClosed {
-			e.Logger.Fatal("shutting down the server")
-		}
-	}()
+	log.Printf("running in %s mode", config.Mode)
+	if config.RunsServer() {
+		go func() {
+			if err := e.Start(":" + strconv.Itoa(config.Port)); err != nil && err != http.ErrServerClosed {
+				e.Logger.Fatal("shutting down the server")
+			}
+		}()
+	}
 
 	<-ctx.Done()
+	// requests are drained first since they queue jobs, jobs then finish with the browser and the database still up
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GracefulShutDownTimeout)*time.Second)
 	defer cancel()
-	postgresDb.Close()
-	if config.Environment == constants.EnvProduction {
-		if err := bgjb.Close(ctx); err != nil {
-			log.Fatalf("error closing background: %v", err)
-		}
-	}
-
 	if err := e.Shutdown(ctx); err != nil {
-		e.Logger.Fatal(err)
+		log.Printf("error shutting down the server: %v", err)
+	}
+	if err := bgjb.Close(ctx); err != nil {
+		log.Printf("error closing background: %v", err)
 	}
+	if config.RunsServer() {
+		<-progressHub.Done()
+	}
+	postgresDb.Close()
 	//e.Logger.Fatal(e.Start(":1323"))
 }
@@ -0,0 +1,2 @@
+ALTER TABLE job_queue
+DROP COLUMN status_reason;
@@ -0,0 +1,2 @@
+ALTER TABLE job_queue
+ADD COLUMN status_reason TEXT;
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS url_store_versions;
+
+ALTER TABLE url_store
+DROP COLUMN etag,
+DROP COLUMN last_modified,
+DROP COLUMN content_hash,
+DROP COLUMN last_crawled_at,
+DROP COLUMN content_changed_at;
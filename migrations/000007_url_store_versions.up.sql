@@ -0,0 +1,18 @@
+ALTER TABLE url_store
+ADD COLUMN etag TEXT NOT NULL DEFAULT '',
+ADD COLUMN last_modified TEXT NOT NULL DEFAULT '',
+ADD COLUMN content_hash TEXT NOT NULL DEFAULT '',
+ADD COLUMN last_crawled_at TIMESTAMP,
+ADD COLUMN content_changed_at TIMESTAMP;
+
+CREATE TABLE
+	url_store_versions (
+		id TEXT PRIMARY KEY,
+		url_id TEXT NOT NULL,
+		full_content TEXT,
+		content_hash TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (url_id) REFERENCES url_store (id)
+	);
+
+CREATE INDEX url_store_versions_url_id_created_at_idx ON url_store_versions (url_id, created_at);
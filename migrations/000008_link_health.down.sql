@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS url_store_link_checked_at_idx;
+
+ALTER TABLE url_store
+DROP COLUMN link_state,
+DROP COLUMN link_status_code,
+DROP COLUMN link_final_url,
+DROP COLUMN link_checked_at;
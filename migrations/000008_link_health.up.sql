@@ -0,0 +1,7 @@
+ALTER TABLE url_store
+ADD COLUMN link_state TEXT NOT NULL DEFAULT '',
+ADD COLUMN link_status_code INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN link_final_url TEXT NOT NULL DEFAULT '',
+ADD COLUMN link_checked_at TIMESTAMP;
+
+CREATE INDEX url_store_link_checked_at_idx ON url_store (link_checked_at NULLS FIRST);
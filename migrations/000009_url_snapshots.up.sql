@@ -0,0 +1,12 @@
+CREATE TABLE
+	url_snapshots (
+		id TEXT PRIMARY KEY,
+		url_id TEXT NOT NULL,
+		blob_key TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		size_bytes BIGINT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (url_id) REFERENCES url_store (id)
+	);
+
+CREATE INDEX url_snapshots_url_id_created_at_idx ON url_snapshots (url_id, created_at);
@@ -0,0 +1,11 @@
+CREATE TABLE
+	blob_objects (
+		key TEXT PRIMARY KEY,
+		organization_id TEXT NOT NULL,
+		size_bytes BIGINT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (organization_id) REFERENCES organizations (id)
+	);
+
+CREATE INDEX blob_objects_organization_id_idx ON blob_objects (organization_id);
@@ -0,0 +1,5 @@
+ALTER TABLE url_store
+DROP COLUMN author,
+DROP COLUMN content_type,
+DROP COLUMN document_key,
+DROP COLUMN page_count;
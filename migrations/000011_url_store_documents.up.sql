@@ -0,0 +1,5 @@
+ALTER TABLE url_store
+ADD COLUMN author TEXT NOT NULL DEFAULT '',
+ADD COLUMN content_type TEXT NOT NULL DEFAULT '',
+ADD COLUMN document_key TEXT NOT NULL DEFAULT '',
+ADD COLUMN page_count INTEGER NOT NULL DEFAULT 0;
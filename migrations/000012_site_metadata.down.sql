@@ -0,0 +1,2 @@
+ALTER TABLE url_store
+DROP COLUMN site_metadata;
@@ -0,0 +1,2 @@
+ALTER TABLE url_store
+ADD COLUMN site_metadata JSONB;
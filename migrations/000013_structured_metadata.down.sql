@@ -0,0 +1,5 @@
+ALTER TABLE url_store
+DROP COLUMN published_at,
+DROP COLUMN reading_time,
+DROP COLUMN site_name,
+DROP COLUMN canonical_image;
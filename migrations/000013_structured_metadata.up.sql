@@ -0,0 +1,5 @@
+ALTER TABLE url_store
+ADD COLUMN published_at TIMESTAMP,
+ADD COLUMN reading_time INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN site_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN canonical_image TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE url_store
+DROP COLUMN article_html,
+DROP COLUMN word_count;
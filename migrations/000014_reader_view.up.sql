@@ -0,0 +1,3 @@
+ALTER TABLE url_store
+ADD COLUMN article_html TEXT NOT NULL DEFAULT '',
+ADD COLUMN word_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,49 @@
+DROP INDEX IF EXISTS url_store_idx;
+
+DROP INDEX IF EXISTS url_store_language_idx;
+
+ALTER TABLE url_store
+DROP COLUMN search_en,
+DROP COLUMN search_de,
+DROP COLUMN search_fr,
+DROP COLUMN search_es,
+DROP COLUMN search_pt,
+DROP COLUMN search_it,
+DROP COLUMN search_nl,
+DROP COLUMN search_sv,
+DROP COLUMN search_ru,
+DROP COLUMN search_el,
+DROP COLUMN search_ar,
+DROP COLUMN search_cjk,
+DROP COLUMN language;
+
+CREATE INDEX url_store_idx ON url_store USING bm25 (
+	id,
+	url,
+	domain,
+	title,
+	excerpt,
+	full_content,
+	created_at
+)
+WITH
+	(
+		key_field = 'id',
+		datetime_fields = '{
+      "created_at": {"fast": true}
+		}',
+		text_fields = '{
+        "title": {
+          "tokenizer": {"type": "whitespace"}
+        },
+				"excerpt": {
+          "tokenizer": {"type": "whitespace"}
+        },
+				"full_content": {
+          "tokenizer": {"type": "whitespace"}
+        },
+				"domain": {
+          "tokenizer": {"type": "raw"}
+        }
+    }'
+	);
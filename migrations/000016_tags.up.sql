@@ -0,0 +1,94 @@
+-- suggested tags are found in the page and shared by every organization saving it,
+-- tags are the suggestions an organization accepted
+ALTER TABLE url_store
+ADD COLUMN suggested_tags TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE url_organizations
+ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX url_organizations_tags_idx ON url_organizations USING GIN (tags);
+
+DROP INDEX IF EXISTS url_store_idx;
+
+CREATE INDEX url_store_idx ON url_store USING bm25 (
+	id,
+	url,
+	domain,
+	title,
+	excerpt,
+	full_content,
+	search_en,
+	search_de,
+	search_fr,
+	search_es,
+	search_pt,
+	search_it,
+	search_nl,
+	search_sv,
+	search_ru,
+	search_el,
+	search_ar,
+	search_cjk,
+	suggested_tags,
+	created_at
+)
+WITH
+	(
+		key_field = 'id',
+		datetime_fields = '{
+      "created_at": {"fast": true}
+		}',
+		text_fields = '{
+        "title": {
+          "tokenizer": {"type": "whitespace"}
+        },
+        "excerpt": {
+          "tokenizer": {"type": "whitespace"}
+        },
+        "full_content": {
+          "tokenizer": {"type": "whitespace"}
+        },
+        "domain": {
+          "tokenizer": {"type": "raw"}
+        },
+        "search_en": {
+          "tokenizer": {"type": "default", "stemmer": "English"}
+        },
+        "search_de": {
+          "tokenizer": {"type": "default", "stemmer": "German"}
+        },
+        "search_fr": {
+          "tokenizer": {"type": "default", "stemmer": "French"}
+        },
+        "search_es": {
+          "tokenizer": {"type": "default", "stemmer": "Spanish"}
+        },
+        "search_pt": {
+          "tokenizer": {"type": "default", "stemmer": "Portuguese"}
+        },
+        "search_it": {
+          "tokenizer": {"type": "default", "stemmer": "Italian"}
+        },
+        "search_nl": {
+          "tokenizer": {"type": "default", "stemmer": "Dutch"}
+        },
+        "search_sv": {
+          "tokenizer": {"type": "default", "stemmer": "Swedish"}
+        },
+        "search_ru": {
+          "tokenizer": {"type": "default", "stemmer": "Russian"}
+        },
+        "search_el": {
+          "tokenizer": {"type": "default", "stemmer": "Greek"}
+        },
+        "search_ar": {
+          "tokenizer": {"type": "default", "stemmer": "Arabic"}
+        },
+        "search_cjk": {
+          "tokenizer": {"type": "chinese_compatible"}
+        },
+        "suggested_tags": {
+          "tokenizer": {"type": "default"}
+        }
+    }'
+	);
@@ -0,0 +1,3 @@
+ALTER TABLE url_store
+DROP COLUMN summary,
+DROP COLUMN summarized_at;
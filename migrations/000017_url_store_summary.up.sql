@@ -0,0 +1,3 @@
+ALTER TABLE url_store
+ADD COLUMN summary TEXT NOT NULL DEFAULT '',
+ADD COLUMN summarized_at TIMESTAMP;
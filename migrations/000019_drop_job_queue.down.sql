@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS
+	job_queue (
+		id TEXT PRIMARY KEY,
+		org_id TEXT,
+		job_id TEXT,
+		job_data TEXT,
+		status TEXT,
+		created_at TIMESTAMP,
+		updated_at TIMESTAMP,
+		status_reason TEXT,
+		failure_class TEXT,
+		FOREIGN KEY (org_id) REFERENCES organizations (id),
+		CONSTRAINT unique_job_data_org_id UNIQUE (job_data, org_id)
+	);
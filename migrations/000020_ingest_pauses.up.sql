@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS
+	ingest_pauses (
+		org_id TEXT PRIMARY KEY,
+		paused_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		FOREIGN KEY (org_id) REFERENCES organizations (id)
+	);
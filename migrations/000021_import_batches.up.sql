@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS
+	import_batches (
+		id TEXT PRIMARY KEY,
+		org_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		source TEXT NOT NULL,
+		total INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		FOREIGN KEY (org_id) REFERENCES organizations (id),
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	);
+
+CREATE INDEX IF NOT EXISTS import_batches_org_id_idx ON import_batches (org_id, id);
+
+CREATE TABLE IF NOT EXISTS
+	import_batch_urls (
+		batch_id TEXT NOT NULL,
+		position INT NOT NULL,
+		url TEXT NOT NULL,
+		outcome TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (batch_id, position),
+		FOREIGN KEY (batch_id) REFERENCES import_batches (id) ON DELETE CASCADE
+	);
+
+CREATE INDEX IF NOT EXISTS import_batch_urls_url_idx ON import_batch_urls (batch_id, url);
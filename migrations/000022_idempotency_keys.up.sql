@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS
+	idempotency_keys (
+		org_id TEXT NOT NULL,
+		key TEXT NOT NULL,
+		url TEXT NOT NULL,
+		url_org_id TEXT,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (org_id, key),
+		FOREIGN KEY (org_id) REFERENCES organizations (id)
+	);
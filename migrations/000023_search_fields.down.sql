@@ -0,0 +1,98 @@
+DROP INDEX IF EXISTS url_store_idx;
+
+ALTER TABLE url_store
+ADD COLUMN search_en TEXT GENERATED ALWAYS AS (CASE WHEN language = 'en' THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED,
+ADD COLUMN search_de TEXT GENERATED ALWAYS AS (CASE WHEN language = 'de' THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED,
+ADD COLUMN search_fr TEXT GENERATED ALWAYS AS (CASE WHEN language = 'fr' THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED,
+ADD COLUMN search_es TEXT GENERATED ALWAYS AS (CASE WHEN language = 'es' THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED,
+ADD COLUMN search_pt TEXT GENERATED ALWAYS AS (CASE WHEN language = 'pt' THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED,
+ADD COLUMN search_it TEXT GENERATED ALWAYS AS (CASE WHEN language = 'it' THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED,
+ADD COLUMN search_nl TEXT GENERATED ALWAYS AS (CASE WHEN language = 'nl' THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED,
+ADD COLUMN search_sv TEXT GENERATED ALWAYS AS (CASE WHEN language = 'sv' THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED,
+ADD COLUMN search_ru TEXT GENERATED ALWAYS AS (CASE WHEN language = 'ru' THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED,
+ADD COLUMN search_el TEXT GENERATED ALWAYS AS (CASE WHEN language = 'el' THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED,
+ADD COLUMN search_ar TEXT GENERATED ALWAYS AS (CASE WHEN language = 'ar' THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED,
+ADD COLUMN search_cjk TEXT GENERATED ALWAYS AS (CASE WHEN language IN ('zh', 'ja', 'ko') THEN coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(full_content, '') END) STORED;
+
+CREATE INDEX url_store_idx ON url_store USING bm25 (
+	id,
+	url,
+	domain,
+	title,
+	excerpt,
+	full_content,
+	search_en,
+	search_de,
+	search_fr,
+	search_es,
+	search_pt,
+	search_it,
+	search_nl,
+	search_sv,
+	search_ru,
+	search_el,
+	search_ar,
+	search_cjk,
+	suggested_tags,
+	created_at
+)
+WITH
+	(
+		key_field = 'id',
+		datetime_fields = '{
+      "created_at": {"fast": true}
+		}',
+		text_fields = '{
+        "title": {
+          "tokenizer": {"type": "whitespace"}
+        },
+        "excerpt": {
+          "tokenizer": {"type": "whitespace"}
+        },
+        "full_content": {
+          "tokenizer": {"type": "whitespace"}
+        },
+        "domain": {
+          "tokenizer": {"type": "raw"}
+        },
+        "search_en": {
+          "tokenizer": {"type": "default", "stemmer": "English"}
+        },
+        "search_de": {
+          "tokenizer": {"type": "default", "stemmer": "German"}
+        },
+        "search_fr": {
+          "tokenizer": {"type": "default", "stemmer": "French"}
+        },
+        "search_es": {
+          "tokenizer": {"type": "default", "stemmer": "Spanish"}
+        },
+        "search_pt": {
+          "tokenizer": {"type": "default", "stemmer": "Portuguese"}
+        },
+        "search_it": {
+          "tokenizer": {"type": "default", "stemmer": "Italian"}
+        },
+        "search_nl": {
+          "tokenizer": {"type": "default", "stemmer": "Dutch"}
+        },
+        "search_sv": {
+          "tokenizer": {"type": "default", "stemmer": "Swedish"}
+        },
+        "search_ru": {
+          "tokenizer": {"type": "default", "stemmer": "Russian"}
+        },
+        "search_el": {
+          "tokenizer": {"type": "default", "stemmer": "Greek"}
+        },
+        "search_ar": {
+          "tokenizer": {"type": "default", "stemmer": "Arabic"}
+        },
+        "search_cjk": {
+          "tokenizer": {"type": "chinese_compatible"}
+        },
+        "suggested_tags": {
+          "tokenizer": {"type": "default"}
+        }
+    }'
+	);
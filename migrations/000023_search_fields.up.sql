@@ -0,0 +1,113 @@
+-- the search_ columns held a second copy of the text of every page. The index now reads full_content
+-- once per language with that language's tokenizer and stemmer, search only matches a page in the
+-- field of its own language. Term frequencies are enough for matching, positions stay with full_content
+DROP INDEX IF EXISTS url_store_idx;
+
+ALTER TABLE url_store
+DROP COLUMN search_en,
+DROP COLUMN search_de,
+DROP COLUMN search_fr,
+DROP COLUMN search_es,
+DROP COLUMN search_pt,
+DROP COLUMN search_it,
+DROP COLUMN search_nl,
+DROP COLUMN search_sv,
+DROP COLUMN search_ru,
+DROP COLUMN search_el,
+DROP COLUMN search_ar,
+DROP COLUMN search_cjk;
+
+CREATE INDEX url_store_idx ON url_store USING bm25 (
+	id,
+	url,
+	domain,
+	title,
+	excerpt,
+	full_content,
+	suggested_tags,
+	created_at
+)
+WITH
+	(
+		key_field = 'id',
+		datetime_fields = '{
+      "created_at": {"fast": true}
+		}',
+		text_fields = '{
+        "title": {
+          "tokenizer": {"type": "whitespace"}
+        },
+        "excerpt": {
+          "tokenizer": {"type": "whitespace"}
+        },
+        "full_content": {
+          "tokenizer": {"type": "whitespace"}
+        },
+        "domain": {
+          "tokenizer": {"type": "raw"}
+        },
+        "search_en": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "default", "stemmer": "English"}
+        },
+        "search_de": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "default", "stemmer": "German"}
+        },
+        "search_fr": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "default", "stemmer": "French"}
+        },
+        "search_es": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "default", "stemmer": "Spanish"}
+        },
+        "search_pt": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "default", "stemmer": "Portuguese"}
+        },
+        "search_it": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "default", "stemmer": "Italian"}
+        },
+        "search_nl": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "default", "stemmer": "Dutch"}
+        },
+        "search_sv": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "default", "stemmer": "Swedish"}
+        },
+        "search_ru": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "default", "stemmer": "Russian"}
+        },
+        "search_el": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "default", "stemmer": "Greek"}
+        },
+        "search_ar": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "default", "stemmer": "Arabic"}
+        },
+        "search_cjk": {
+          "column": "full_content",
+          "record": "freq",
+          "tokenizer": {"type": "chinese_compatible"}
+        },
+        "suggested_tags": {
+          "tokenizer": {"type": "default"}
+        }
+    }'
+	);
@@ -1,11 +1,27 @@
 package models
 
+import "time"
+
 type CreateBookmarkRequest struct {
 	URL string `json:"url"`
 }
 type CreateBulkBookmarkRequest struct {
 	URLs      []string `json:"urls"`
 	Direction string   `json:"direction"`
+	// Source is where the urls were imported from, shown with the import
+	Source string `json:"source"`
+}
+
+type GetImportBatchesRequest struct {
+	Limit  int    `query:"limit"`
+	NextID string `query:"next_id"`
+}
+
+type GetImportBatchRequest struct {
+	ID      string `param:"id"`
+	Outcome string `query:"outcome"`
+	After   int    `query:"after"`
+	Limit   int    `query:"limit"`
 }
 
 type PatchBookmarkRequest struct {
@@ -13,6 +29,10 @@ type PatchBookmarkRequest struct {
 }
 type SearchBookmarkRequest struct {
 	Needle string `json:"needle"`
+	// Language limits results to pages in a language, empty searches all of them
+	Language string `json:"language"`
+	// Tag limits results to bookmarks the organization tagged with it
+	Tag string `json:"tag"`
 }
 
 type GetBookmarkRequest struct {
@@ -22,6 +42,7 @@ type GetBookmarkRequest struct {
 	Offset    int    `query:"offset"`
 	NextID    string `query:"next_id"`
 	FetchType string `query:"fetch_type"`
+	LinkState string `query:"link_state"`
 }
 
 type PostIndexingRequest struct {
@@ -45,23 +66,85 @@ type URLListResponse struct {
 }
 
 type URLResponses struct {
-	URLID                  string  `json:"url_id"`
-	Title                  string  `json:"title"`
-	URL                    string  `json:"url"`
-	Excerpt                string  `json:"excerpt"`
-	ImageSmall             string  `json:"image_small"`
-	ImageLarge             string  `json:"image_large"`
-	AccentColor            string  `json:"accent_color"`
-	OrganizationRelationID string  `json:"organization_relation_id"`
-	OrganizationURLStatus  string  `json:"organization_url_status"`
-	Checked                bool    `json:"checked"`
-	Score                  float64 `json:"score"`
+	URLID                  string        `json:"url_id"`
+	Title                  string        `json:"title"`
+	URL                    string        `json:"url"`
+	Excerpt                string        `json:"excerpt"`
+	Summary                string        `json:"summary"`
+	ImageSmall             string        `json:"image_small"`
+	ImageLarge             string        `json:"image_large"`
+	AccentColor            string        `json:"accent_color"`
+	OrganizationRelationID string        `json:"organization_relation_id"`
+	OrganizationURLStatus  string        `json:"organization_url_status"`
+	Checked                bool          `json:"checked"`
+	Score                  float64       `json:"score"`
+	ChangedSinceSaved      bool          `json:"changed_since_saved"`
+	LinkState              string        `json:"link_state"`
+	LinkStatusCode         int           `json:"link_status_code"`
+	ContentType            string        `json:"content_type"`
+	SiteMetadata           *SiteMetadata `json:"site_metadata,omitempty"`
+	Author                 string        `json:"author"`
+	PublishedAt            *time.Time    `json:"published_at,omitempty"`
+	ReadingTime            int           `json:"reading_time"`
+	SiteName               string        `json:"site_name"`
+	Language               string        `json:"language"`
+	Tags                   []string      `json:"tags"`
+	SuggestedTags          []string      `json:"suggested_tags"`
+}
+
+type BrokenLinkResponse struct {
+	URLResponses
+	LinkFinalURL  string     `json:"link_final_url"`
+	LinkCheckedAt *time.Time `json:"link_checked_at"`
+}
+
+type BrokenLinksReport struct {
+	Counts map[string]int        `json:"counts"`
+	Data   []*BrokenLinkResponse `json:"data"`
+}
+
+type BookmarkDiffChunk struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type BookmarkDiffResponse struct {
+	URLID             string              `json:"url_id"`
+	SavedAt           time.Time           `json:"saved_at"`
+	FromVersionAt     time.Time           `json:"from_version_at"`
+	ChangedAt         *time.Time          `json:"changed_at"`
+	ChangedSinceSaved bool                `json:"changed_since_saved"`
+	Diff              []BookmarkDiffChunk `json:"diff"`
+}
+
+type ReaderViewResponse struct {
+	URLID       string     `json:"url_id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Author      string     `json:"author"`
+	SiteName    string     `json:"site_name"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Summary     string     `json:"summary"`
+	Content     string     `json:"content"`
+	WordCount   int        `json:"word_count"`
+	ReadingTime int        `json:"reading_time"`
+}
+
+type StorageUsageResponse struct {
+	UsedBytes  int64 `json:"used_bytes"`
+	QuotaBytes int64 `json:"quota_bytes"`
 }
 
 type BulkDeleteRequest struct {
 	IDs []string `json:"organization_relation_ids"`
 }
 
+type AcceptTagsRequest struct {
+	IDs []string `json:"organization_relation_ids" validate:"required,min=1"`
+	// Tags are the suggestions to accept, empty accepts all of them
+	Tags []string `json:"tags"`
+}
+
 type EmailRequest struct {
 	Email string `json:"email"`
 }
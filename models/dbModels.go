@@ -16,6 +16,58 @@ type URLStore struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Score       float64   `json:"score"`
+
+	ETag             string     `json:"-"`
+	LastModified     string     `json:"-"`
+	ContentHash      string     `json:"-"`
+	LastCrawledAt    *time.Time `json:"last_crawled_at,omitempty"`
+	ContentChangedAt *time.Time `json:"content_changed_at,omitempty"`
+
+	LinkState      string     `json:"link_state"`
+	LinkStatusCode int        `json:"link_status_code"`
+	LinkFinalURL   string     `json:"link_final_url"`
+	LinkCheckedAt  *time.Time `json:"link_checked_at,omitempty"`
+
+	// Author, ContentType and PageCount describe documents like PDFs, DocumentKey is the blob of the original file
+	Author      string `json:"author"`
+	ContentType string `json:"content_type"`
+	DocumentKey string `json:"-"`
+	PageCount   int    `json:"page_count"`
+
+	SiteMetadata *SiteMetadata `json:"site_metadata,omitempty"`
+
+	PublishedAt    *time.Time `json:"published_at,omitempty"`
+	ReadingTime    int        `json:"reading_time"`
+	SiteName       string     `json:"site_name"`
+	CanonicalImage string     `json:"canonical_image"`
+	// OEmbedURL is the oEmbed endpoint the page links to, it is only used while parsing
+	OEmbedURL string `json:"-"`
+
+	// ArticleHTML is the sanitized readability article served by the reader view
+	ArticleHTML string `json:"-"`
+	WordCount   int    `json:"word_count"`
+
+	// Summary is a few sentences of the page picked by TextRank, unlike the excerpt it always comes from the content
+	Summary string `json:"summary"`
+
+	// Language is the detected language code of the page, "und" when it could not be told
+	Language string `json:"language"`
+}
+
+type URLStoreVersion struct {
+	ID          string    `json:"id"`
+	URLID       string    `json:"url_id"`
+	FullText    string    `json:"full_text"`
+	ContentHash string    `json:"content_hash"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+type URLSnapshot struct {
+	ID          string    `json:"id"`
+	URLID       string    `json:"url_id"`
+	BlobKey     string    `json:"-"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 type Users struct {
 	ID           string    `json:"id"`
@@ -53,16 +105,6 @@ type URLOrganizations struct {
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
-type JobQueue struct {
-	ID        string    `json:"id"`
-	OrgID     string    `json:"org_id"`
-	JobID     string    `json:"job_id"`
-	JobData   string    `json:"job_data"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-}
-
 type DbSecret struct {
 	ID             string    `json:"id"`
 	OrganizationID string    `json:"organization_id"`
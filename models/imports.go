@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// ImportBatch is a set of urls an organization imported at once, Counts has the number of urls for each outcome
+type ImportBatch struct {
+	ID        string         `json:"id"`
+	OrgID     string         `json:"org_id"`
+	UserID    string         `json:"user_id"`
+	Source    string         `json:"source"`
+	Total     int            `json:"total"`
+	Counts    map[string]int `json:"counts"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// ImportBatchURL is what became of one url of an import, in the order it was submitted
+type ImportBatchURL struct {
+	Position  int       `json:"position"`
+	URL       string    `json:"url"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ImportBatchListResponse struct {
+	Data   []*ImportBatch `json:"data"`
+	NextID string         `json:"next_id"`
+	IsLast bool           `json:"is_last"`
+}
+
+type ImportBatchResponse struct {
+	*ImportBatch
+	URLs []ImportBatchURL `json:"urls"`
+	// NextPosition fetches the urls after this page, zero on the last page
+	NextPosition int `json:"next_position"`
+}
+
+// IdempotencyKey is a key a client sent with a save, URLOrgID is empty while the save is in flight
+type IdempotencyKey struct {
+	OrgID     string    `json:"org_id"`
+	Key       string    `json:"key"`
+	URL       string    `json:"url"`
+	URLOrgID  string    `json:"url_org_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
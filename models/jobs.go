@@ -0,0 +1,106 @@
+package models
+
+import "time"
+
+// IngestJobsRequest pages through an organization's url ingest jobs, newest first
+type IngestJobsRequest struct {
+	// State limits jobs to one river state like available, running, retryable, completed or discarded
+	State  string `query:"state"`
+	Cursor string `query:"cursor"`
+	Limit  int    `query:"limit"`
+}
+
+// IngestJobError is one failed attempt of an ingest job
+type IngestJobError struct {
+	Attempt      int       `json:"attempt"`
+	At           time.Time `json:"at"`
+	Error        string    `json:"error"`
+	FailureClass string    `json:"failure_class"`
+}
+
+// IngestJob is a url being saved for an organization as river tracks it
+type IngestJob struct {
+	ID          int64            `json:"id"`
+	URL         string           `json:"url"`
+	State       string           `json:"state"`
+	Queue       string           `json:"queue"`
+	Attempt     int              `json:"attempt"`
+	MaxAttempts int              `json:"max_attempts"`
+	Errors      []IngestJobError `json:"errors"`
+	CreatedAt   time.Time        `json:"created_at"`
+	ScheduledAt time.Time        `json:"scheduled_at"`
+	AttemptedAt *time.Time       `json:"attempted_at"`
+	FinalizedAt *time.Time       `json:"finalized_at"`
+	// DeferredReason is why the job last had to wait, empty when it never did
+	DeferredReason string     `json:"deferred_reason,omitempty"`
+	DeferredAt     *time.Time `json:"deferred_at,omitempty"`
+}
+
+type IngestJobsResponse struct {
+	Jobs []IngestJob `json:"jobs"`
+	// NextCursor fetches the page after this one, empty on the last page
+	NextCursor string `json:"next_cursor"`
+	// Paused is true while the organization's imports are paused
+	Paused bool `json:"paused"`
+}
+
+// IngestBatchActionResponse is the result of pausing, resuming or cancelling an organization's imports
+type IngestBatchActionResponse struct {
+	Jobs   int64 `json:"jobs"`
+	Paused bool  `json:"paused"`
+}
+
+// IngestProgress is a url of an organization reaching an ingest stage
+type IngestProgress struct {
+	OrgID string `json:"org_id"`
+	URL   string `json:"url"`
+	Stage string `json:"stage"`
+	// Attempt, Final, Error and FailureClass describe failures, Final is false when the url will be tried again
+	Attempt      int       `json:"attempt,omitempty"`
+	Final        bool      `json:"final,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	FailureClass string    `json:"failure_class,omitempty"`
+	At           time.Time `json:"at"`
+}
+
+// DeadLetter is a url ingest job that failed for good, with the error of its last attempt
+type DeadLetter struct {
+	ID           int64      `json:"id"`
+	URL          string     `json:"url"`
+	BatchID      string     `json:"batch_id"`
+	FailureClass string     `json:"failure_class"`
+	Error        string     `json:"error"`
+	Attempts     int        `json:"attempts"`
+	FinalizedAt  *time.Time `json:"finalized_at"`
+}
+
+// DeadLetterGroup is the number of dead letters of a failure class
+type DeadLetterGroup struct {
+	FailureClass string `json:"failure_class"`
+	Count        int    `json:"count"`
+}
+
+type DeadLettersRequest struct {
+	FailureClass string `query:"failure_class"`
+	After        int64  `query:"after"`
+	Limit        int    `query:"limit"`
+}
+
+type DeadLettersResponse struct {
+	Groups []DeadLetterGroup `json:"groups"`
+	Data   []DeadLetter      `json:"data"`
+	// NextAfter fetches the dead letters after this page, zero on the last page
+	NextAfter int64 `json:"next_after"`
+}
+
+// DeadLetterActionRequest picks dead letters by id or all of a failure class
+type DeadLetterActionRequest struct {
+	IDs          []int64 `json:"ids"`
+	FailureClass string  `json:"failure_class"`
+	// Strategy is the fetch strategy a retry uses, HTTP or CHROME
+	Strategy string `json:"strategy"`
+}
+
+type DeadLetterActionResponse struct {
+	Count int `json:"count"`
+}
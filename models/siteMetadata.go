@@ -0,0 +1,60 @@
+package models
+
+// SiteMetadata is what a site specific extractor found on a page, Kind tells which of the fields is set
+type SiteMetadata struct {
+	Kind          string                 `json:"kind"`
+	GitHub        *GitHubMetadata        `json:"github,omitempty"`
+	YouTube       *YouTubeMetadata       `json:"youtube,omitempty"`
+	ArXiv         *ArXivMetadata         `json:"arxiv,omitempty"`
+	StackOverflow *StackOverflowMetadata `json:"stackoverflow,omitempty"`
+	Wikipedia     *WikipediaMetadata     `json:"wikipedia,omitempty"`
+}
+
+type GitHubMetadata struct {
+	Owner       string   `json:"owner"`
+	Repo        string   `json:"repo"`
+	Description string   `json:"description"`
+	Language    string   `json:"language"`
+	Stars       int      `json:"stars"`
+	Forks       int      `json:"forks"`
+	Topics      []string `json:"topics"`
+	License     string   `json:"license"`
+	Readme      string   `json:"readme"`
+}
+
+type YouTubeMetadata struct {
+	VideoID         string `json:"video_id"`
+	Channel         string `json:"channel"`
+	ChannelURL      string `json:"channel_url"`
+	DurationSeconds int    `json:"duration_seconds"`
+	UploadDate      string `json:"upload_date"`
+	Views           int    `json:"views"`
+	HasTranscript   bool   `json:"has_transcript"`
+}
+
+type ArXivMetadata struct {
+	ID             string   `json:"id"`
+	Authors        []string `json:"authors"`
+	Abstract       string   `json:"abstract"`
+	Published      string   `json:"published"`
+	PrimarySubject string   `json:"primary_subject"`
+	PDFURL         string   `json:"pdf_url"`
+}
+
+type StackOverflowMetadata struct {
+	QuestionID          string   `json:"question_id"`
+	Score               int      `json:"score"`
+	Tags                []string `json:"tags"`
+	AnswerCount         int      `json:"answer_count"`
+	Answered            bool     `json:"answered"`
+	AcceptedAnswer      string   `json:"accepted_answer"`
+	AcceptedAnswerScore int      `json:"accepted_answer_score"`
+}
+
+type WikipediaMetadata struct {
+	Language   string   `json:"language"`
+	Article    string   `json:"article"`
+	Summary    string   `json:"summary"`
+	Categories []string `json:"categories"`
+	LastEdited string   `json:"last_edited"`
+}
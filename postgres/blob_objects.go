@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetOrgBlobUsage sums the size of the blobs of an organization, leaving out exceptKey
+func (p *PostgresImplementation) GetOrgBlobUsage(ctx context.Context, orgID, exceptKey string) (int64, error) {
+	var used int64
+
+	query := `
+		SELECT COALESCE(SUM(size_bytes), 0)
+		FROM blob_objects
+		WHERE organization_id = $1 AND key <> $2;`
+
+	if err := p.Pool.QueryRow(ctx, query, orgID, exceptKey).Scan(&used); err != nil {
+		return 0, fmt.Errorf("failed to retrieve blob usage: %v", err)
+	}
+
+	return used, nil
+}
+
+// ChargeBlobObject records the size of a blob unless it takes the organization over limit, a limit of 0 is none.
+// Charges of one organization are taken one at a time so concurrent writes cannot all fit the same room.
+// A blob stays charged to the organization that first stored it, snapshots and thumbnails of a url store
+// are shared by every organization that saved the url
+func (p *PostgresImplementation) ChargeBlobObject(ctx context.Context, key, orgID string, size, limit int64) (bool, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('blob_objects:' || $1))`, orgID); err != nil {
+		return false, fmt.Errorf("failed to lock blob usage: %v", err)
+	}
+	if limit > 0 {
+		var used int64
+		query := `
+			SELECT COALESCE(SUM(size_bytes), 0)
+			FROM blob_objects
+			WHERE organization_id = $1 AND key <> $2;`
+		if err := tx.QueryRow(ctx, query, orgID, key).Scan(&used); err != nil {
+			return false, fmt.Errorf("failed to retrieve blob usage: %v", err)
+		}
+		if used+size > limit {
+			return false, nil
+		}
+	}
+
+	query := `
+		INSERT INTO blob_objects (key, organization_id, size_bytes, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		ON CONFLICT (key) DO UPDATE SET size_bytes = $3, updated_at = NOW();`
+	if _, err := tx.Exec(ctx, query, key, orgID, size); err != nil {
+		return false, fmt.Errorf("failed to upsert blob object: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return true, nil
+}
+
+func (p *PostgresImplementation) DeleteBlobObject(ctx context.Context, key string) error {
+	query := `DELETE FROM blob_objects WHERE key = $1;`
+
+	if _, err := p.Pool.Exec(ctx, query, key); err != nil {
+		return fmt.Errorf("failed to delete blob object: %v", err)
+	}
+
+	return nil
+}
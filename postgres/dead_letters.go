@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// a dead letter is an organization's url ingest job that was discarded or cancelled after failing,
+// jobs cancelled before they ran have no errors and are left out. Its failure class starts the message of its last error.
+// River keeps failed jobs for SMARAKA_FAILED_JOB_RETENTION_DAYS, dead letters are retried or kept well within that
+const (
+	deadLetterWhere = `kind = 'url_ingest' AND metadata @> jsonb_build_object('org_id', $1::text)
+		AND state IN ('discarded', 'cancelled') AND COALESCE(array_length(errors, 1), 0) > 0`
+	deadLetterClass = `COALESCE(substring(errors[array_length(errors, 1)]->>'error' from '^([A-Z][A-Z_]*): '), 'INTERNAL')`
+
+	deleteDeadLetters           = `DELETE FROM river_job WHERE ` + deadLetterWhere + ` AND id = ANY($2)`
+	updateFailedImportBatchURLs = `UPDATE import_batch_urls SET outcome = $3, reason = $4, updated_at = NOW()
+		WHERE batch_id = $1 AND url = ANY($2) AND outcome = $5`
+)
+
+// GetDeadLetterGroups counts an organization's dead letters by failure class, most common first
+func (p *PostgresImplementation) GetDeadLetterGroups(ctx context.Context, orgID string) ([]models.DeadLetterGroup, error) {
+	query := `SELECT ` + deadLetterClass + ` AS failure_class, count(*) FROM river_job WHERE ` + deadLetterWhere + `
+		GROUP BY failure_class ORDER BY count(*) DESC, failure_class`
+	rows, err := p.Pool.Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter groups: %v", err)
+	}
+	defer rows.Close()
+
+	groups := make([]models.DeadLetterGroup, 0)
+	for rows.Next() {
+		var group models.DeadLetterGroup
+		if err := rows.Scan(&group.FailureClass, &group.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter group: %v", err)
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// GetDeadLetters returns an organization's dead letters after a job id, optionally of one failure class
+// or with the given ids only
+func (p *PostgresImplementation) GetDeadLetters(ctx context.Context, orgID, failureClass string, ids []int64, after int64, limit int) ([]models.DeadLetter, error) {
+	query := `SELECT id, args->>'url', COALESCE(metadata->>'batch_id', ''), ` + deadLetterClass + `,
+		errors[array_length(errors, 1)]->>'error', attempt, finalized_at
+		FROM river_job WHERE ` + deadLetterWhere + `
+		AND ($2 = '' OR ` + deadLetterClass + ` = $2) AND (COALESCE(cardinality($3::bigint[]), 0) = 0 OR id = ANY($3))
+		AND id > $4 ORDER BY id LIMIT $5`
+	rows, err := p.Pool.Query(ctx, query, orgID, failureClass, ids, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letters: %v", err)
+	}
+	defer rows.Close()
+
+	letters := make([]models.DeadLetter, 0)
+	for rows.Next() {
+		var letter models.DeadLetter
+		if err := rows.Scan(&letter.ID, &letter.URL, &letter.BatchID, &letter.FailureClass, &letter.Error, &letter.Attempts, &letter.FinalizedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %v", err)
+		}
+		letters = append(letters, letter)
+	}
+	return letters, rows.Err()
+}
+
+// DeleteDeadLetters removes dead letters of an organization once they were retried or kept
+func (p *PostgresImplementation) DeleteDeadLetters(ctx context.Context, orgID string, ids []int64) error {
+	_, err := p.Pool.Exec(ctx, deleteDeadLetters, orgID, ids)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letters: %v", err)
+	}
+	return nil
+}
+
+// UpdateFailedImportBatchURLs gives failed urls of a batch a new outcome, a retry makes them pending again
+func (p *PostgresImplementation) UpdateFailedImportBatchURLs(ctx context.Context, batchID string, urls []string, outcome, reason string) error {
+	_, err := p.Pool.Exec(ctx, updateFailedImportBatchURLs, batchID, urls, outcome, reason, constants.ImportOutcomeFailed)
+	if err != nil {
+		return fmt.Errorf("failed to update failed import batch urls: %v", err)
+	}
+	return nil
+}
+
+// RetryDeadLetters makes the failed urls of dead letters pending again in their import batches, has queue insert
+// their jobs batch by batch and deletes the dead letters, all in one transaction. A failure anywhere leaves every
+// dead letter as it was, none is queued without leaving the view
+func (p *PostgresImplementation) RetryDeadLetters(ctx context.Context, orgID string, letters []models.DeadLetter, queue func(tx pgx.Tx, batchID string, urls []string) error) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// urls are queued with the import batch they came with, so the batch reports the retry's outcome
+	byBatch := make(map[string][]string)
+	ids := make([]int64, 0, len(letters))
+	for _, letter := range letters {
+		byBatch[letter.BatchID] = append(byBatch[letter.BatchID], letter.URL)
+		ids = append(ids, letter.ID)
+	}
+	for batchID, urls := range byBatch {
+		if batchID != "" {
+			_, err := tx.Exec(ctx, updateFailedImportBatchURLs, batchID, urls, constants.ImportOutcomePending, "", constants.ImportOutcomeFailed)
+			if err != nil {
+				return fmt.Errorf("failed to update failed import batch urls: %v", err)
+			}
+		}
+		if err := queue(tx, batchID, urls); err != nil {
+			return fmt.Errorf("failed to queue dead letters: %v", err)
+		}
+	}
+	if _, err := tx.Exec(ctx, deleteDeadLetters, orgID, ids); err != nil {
+		return fmt.Errorf("failed to delete dead letters: %v", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// IdempotencyKeyTTL is how long a key given to a save is remembered, a key used again after that is a new save
+const IdempotencyKeyTTL = "24 hours"
+
+// ClaimIdempotencyKey remembers a key for the save of a url, it returns false and the save the key was first
+// given to when the key was seen before
+func (p *PostgresImplementation) ClaimIdempotencyKey(ctx context.Context, orgID, key, url string) (*models.IdempotencyKey, bool, error) {
+	_, err := p.Pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE org_id = $1 AND key = $2 AND created_at < NOW() - $3::interval`,
+		orgID, key, IdempotencyKeyTTL)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to expire idempotency key: %v", err)
+	}
+	tag, err := p.Pool.Exec(ctx, `INSERT INTO idempotency_keys (org_id, key, url, created_at) VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (org_id, key) DO NOTHING`, orgID, key, url)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to insert idempotency key: %v", err)
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, true, nil
+	}
+
+	var seen models.IdempotencyKey
+	err = p.Pool.QueryRow(ctx, `SELECT org_id, key, url, COALESCE(url_org_id, ''), created_at FROM idempotency_keys WHERE org_id = $1 AND key = $2`,
+		orgID, key).Scan(&seen.OrgID, &seen.Key, &seen.URL, &seen.URLOrgID, &seen.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// the key expired in between, the save may go on without it
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency key: %v", err)
+	}
+	return &seen, false, nil
+}
+
+// CompleteIdempotencyKey records the bookmark a save with the key ended in
+func (p *PostgresImplementation) CompleteIdempotencyKey(ctx context.Context, orgID, key, urlOrgID string) error {
+	_, err := p.Pool.Exec(ctx, `UPDATE idempotency_keys SET url_org_id = $3 WHERE org_id = $1 AND key = $2`, orgID, key, urlOrgID)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %v", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets a key whose save failed, so the client can try again with it
+func (p *PostgresImplementation) ReleaseIdempotencyKey(ctx context.Context, orgID, key string) error {
+	_, err := p.Pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE org_id = $1 AND key = $2 AND url_org_id IS NULL`, orgID, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %v", err)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes keys given more than IdempotencyKeyTTL ago
+func (p *PostgresImplementation) DeleteExpiredIdempotencyKeys(ctx context.Context) error {
+	_, err := p.Pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE created_at < NOW() - $1::interval`, IdempotencyKeyTTL)
+	if err != nil {
+		return fmt.Errorf("failed to delete expired idempotency keys: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// importBatchColumns selects a batch with the number of its urls for each outcome
+const importBatchColumns = `b.id, b.org_id, b.user_id, b.source, b.total, b.created_at,
+	(SELECT COALESCE(jsonb_object_agg(c.outcome, c.n), '{}'::jsonb) FROM
+		(SELECT outcome, count(*) AS n FROM import_batch_urls WHERE batch_id = b.id GROUP BY outcome) c) AS counts`
+
+// InsertImportBatch stores a batch and its urls in the order they were submitted
+func (p *PostgresImplementation) InsertImportBatch(ctx context.Context, batch models.ImportBatch, urls []models.ImportBatchURL) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO import_batches (id, org_id, user_id, source, total, created_at) VALUES ($1, $2, $3, $4, $5, NOW())`,
+		batch.ID, batch.OrgID, batch.UserID, batch.Source, len(urls))
+	if err != nil {
+		return fmt.Errorf("failed to insert import batch: %v", err)
+	}
+
+	positions := make([]int, len(urls))
+	links := make([]string, len(urls))
+	outcomes := make([]string, len(urls))
+	reasons := make([]string, len(urls))
+	for i, url := range urls {
+		positions[i], links[i], outcomes[i], reasons[i] = url.Position, url.URL, url.Outcome, url.Reason
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO import_batch_urls (batch_id, position, url, outcome, reason, updated_at)
+		SELECT $1, u.position, u.url, u.outcome, u.reason, NOW() FROM unnest($2::int[], $3::text[], $4::text[], $5::text[]) AS u(position, url, outcome, reason)`,
+		batch.ID, positions, links, outcomes, reasons)
+	if err != nil {
+		return fmt.Errorf("failed to insert import batch urls: %v", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// UpdateImportBatchURLOutcome records what became of a url of a batch, only urls still pending are updated
+func (p *PostgresImplementation) UpdateImportBatchURLOutcome(ctx context.Context, batchID, url, outcome, reason string) error {
+	_, err := p.Pool.Exec(ctx, `UPDATE import_batch_urls SET outcome = $3, reason = $4, updated_at = NOW() WHERE batch_id = $1 AND url = $2 AND outcome = $5`,
+		batchID, url, outcome, reason, constants.ImportOutcomePending)
+	if err != nil {
+		return fmt.Errorf("failed to update import batch url: %v", err)
+	}
+	return nil
+}
+
+// GetOrganizationURLsPresent returns which of urls an organization has already saved
+func (p *PostgresImplementation) GetOrganizationURLsPresent(ctx context.Context, orgID string, urls []string) (map[string]bool, error) {
+	present := make(map[string]bool)
+	rows, err := p.Pool.Query(ctx, `SELECT us.url FROM url_organizations uo JOIN url_store us ON uo.url_id = us.id
+		WHERE uo.organization_id = $1 AND uo.status = $2 AND us.url = ANY($3)`, orgID, constants.URLStatusActive, urls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved urls: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, fmt.Errorf("failed to scan saved url: %v", err)
+		}
+		present[url] = true
+	}
+	return present, rows.Err()
+}
+
+// GetImportBatches pages through an organization's batches newest first, lastID is the last batch of the previous page
+func (p *PostgresImplementation) GetImportBatches(ctx context.Context, orgID, lastID string, limit int) ([]*models.ImportBatch, error) {
+	query := `SELECT ` + importBatchColumns + ` FROM import_batches b
+		WHERE b.org_id = $1 AND ($2 = '' OR b.id < $2) ORDER BY b.id DESC LIMIT $3`
+	rows, err := p.Pool.Query(ctx, query, orgID, lastID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import batches: %v", err)
+	}
+	defer rows.Close()
+
+	batches := make([]*models.ImportBatch, 0)
+	for rows.Next() {
+		batch := &models.ImportBatch{}
+		if err := rows.Scan(&batch.ID, &batch.OrgID, &batch.UserID, &batch.Source, &batch.Total, &batch.CreatedAt, &batch.Counts); err != nil {
+			return nil, fmt.Errorf("failed to scan import batch: %v", err)
+		}
+		batches = append(batches, batch)
+	}
+	return batches, rows.Err()
+}
+
+// GetImportBatch returns a batch of an organization
+func (p *PostgresImplementation) GetImportBatch(ctx context.Context, orgID, id string) (*models.ImportBatch, error) {
+	batch := &models.ImportBatch{}
+	err := p.Pool.QueryRow(ctx, `SELECT `+importBatchColumns+` FROM import_batches b WHERE b.org_id = $1 AND b.id = $2`, orgID, id).
+		Scan(&batch.ID, &batch.OrgID, &batch.UserID, &batch.Source, &batch.Total, &batch.CreatedAt, &batch.Counts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import batch: %v", err)
+	}
+	return batch, nil
+}
+
+// GetImportBatchURLs returns the urls of a batch after a position, all of them when limit is zero.
+// An empty outcome returns urls of every outcome
+func (p *PostgresImplementation) GetImportBatchURLs(ctx context.Context, batchID, outcome string, after, limit int) ([]models.ImportBatchURL, error) {
+	query := `SELECT position, url, outcome, reason, updated_at FROM import_batch_urls
+		WHERE batch_id = $1 AND ($2 = '' OR outcome = $2) AND position > $3
+		ORDER BY position LIMIT NULLIF($4, 0)`
+	rows, err := p.Pool.Query(ctx, query, batchID, outcome, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import batch urls: %v", err)
+	}
+	defer rows.Close()
+
+	urls := make([]models.ImportBatchURL, 0)
+	for rows.Next() {
+		var url models.ImportBatchURL
+		if err := rows.Scan(&url.Position, &url.URL, &url.Outcome, &url.Reason, &url.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan import batch url: %v", err)
+		}
+		urls = append(urls, url)
+	}
+	return urls, rows.Err()
+}
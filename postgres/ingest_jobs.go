@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rajnandan1/smaraka/constants"
+)
+
+// GetIngestJobStateCounts counts an organization's url ingest jobs by their river state
+func (p *PostgresImplementation) GetIngestJobStateCounts(ctx context.Context, orgID string) (map[string]int, error) {
+	query := `SELECT state, count(*) FROM river_job WHERE kind = 'url_ingest' AND metadata @> jsonb_build_object('org_id', $1::text) GROUP BY state`
+	rows, err := p.Pool.Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count ingest jobs: %v", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var state string
+		var count int
+		if err := rows.Scan(&state, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan ingest job count: %v", err)
+		}
+		counts[state] = count
+	}
+	return counts, rows.Err()
+}
+
+// paused ingest jobs are scheduled this far out, resuming makes every job scheduled beyond pausedIngestCutoff available again
+const (
+	pausedIngestDelay  = "100 years"
+	pausedIngestCutoff = "50 years"
+)
+
+// PausedIngestDelay is how far out a worker snoozes a job of a paused organization, the same as a pause schedules them
+const PausedIngestDelay = 100 * 365 * 24 * time.Hour
+
+// IsIngestPaused tells if an organization paused its imports
+func (p *PostgresImplementation) IsIngestPaused(ctx context.Context, orgID string) (bool, error) {
+	var paused bool
+	err := p.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM ingest_pauses WHERE org_id = $1)`, orgID).Scan(&paused)
+	if err != nil {
+		return false, fmt.Errorf("failed to get ingest pause: %v", err)
+	}
+	return paused, nil
+}
+
+// PauseIngestJobs pauses an organization's ingest jobs waiting on a queue, jobs already running finish
+func (p *PostgresImplementation) PauseIngestJobs(ctx context.Context, orgID, queue string) (int64, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `INSERT INTO ingest_pauses (org_id) VALUES ($1) ON CONFLICT (org_id) DO NOTHING`, orgID); err != nil {
+		return 0, fmt.Errorf("failed to pause ingest: %v", err)
+	}
+	tag, err := tx.Exec(ctx, `UPDATE river_job SET state = 'scheduled', scheduled_at = now() + interval '`+pausedIngestDelay+`'
+		WHERE kind = 'url_ingest' AND queue = $2 AND metadata @> jsonb_build_object('org_id', $1::text)
+		AND state IN ('available', 'retryable', 'scheduled')`, orgID, queue)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pause ingest jobs: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ResumeIngestJobs makes an organization's paused ingest jobs on a queue available again
+func (p *PostgresImplementation) ResumeIngestJobs(ctx context.Context, orgID, queue string) (int64, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM ingest_pauses WHERE org_id = $1`, orgID); err != nil {
+		return 0, fmt.Errorf("failed to resume ingest: %v", err)
+	}
+	tag, err := tx.Exec(ctx, `UPDATE river_job SET state = 'available', scheduled_at = now()
+		WHERE kind = 'url_ingest' AND queue = $2 AND metadata @> jsonb_build_object('org_id', $1::text)
+		AND state = 'scheduled' AND scheduled_at > now() + interval '`+pausedIngestCutoff+`'`, orgID, queue)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resume ingest jobs: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CancelIngestJobs cancels an organization's ingest jobs waiting on a queue, paused ones included, and lifts the pause
+func (p *PostgresImplementation) CancelIngestJobs(ctx context.Context, orgID, queue string) (int64, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM ingest_pauses WHERE org_id = $1`, orgID); err != nil {
+		return 0, fmt.Errorf("failed to lift ingest pause: %v", err)
+	}
+	// the urls of cancelled jobs are marked cancelled in their import batches as well
+	var cancelled int64
+	err = tx.QueryRow(ctx, `WITH cancelled AS (
+			UPDATE river_job SET state = 'cancelled', finalized_at = now()
+			WHERE kind = 'url_ingest' AND queue = $2 AND metadata @> jsonb_build_object('org_id', $1::text)
+			AND state IN ('available', 'pending', 'retryable', 'scheduled')
+			RETURNING metadata->>'batch_id' AS batch_id, args->>'url' AS url
+		), marked AS (
+			UPDATE import_batch_urls b SET outcome = $3, updated_at = now() FROM cancelled c
+			WHERE b.batch_id = c.batch_id AND b.url = c.url AND b.outcome = $4
+			RETURNING 1
+		)
+		SELECT count(*) FROM cancelled`, orgID, queue, constants.ImportOutcomeCancelled, constants.ImportOutcomePending).Scan(&cancelled)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel ingest jobs: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return cancelled, nil
+}
+
+// CountRunningIngestJobs counts an organization's running ingest jobs on a queue, there are never more
+// than the queue has workers
+func (p *PostgresImplementation) CountRunningIngestJobs(ctx context.Context, orgID, queue string) (int, error) {
+	var running int
+	query := `SELECT count(*) FROM river_job WHERE state = 'running' AND queue = $2 AND kind = 'url_ingest'
+		AND metadata @> jsonb_build_object('org_id', $1::text)`
+	if err := p.Pool.QueryRow(ctx, query, orgID, queue).Scan(&running); err != nil {
+		return 0, fmt.Errorf("failed to count running ingest jobs: %v", err)
+	}
+	return running, nil
+}
+
+// CountIngestOrgs counts the organizations with ingest jobs running or waiting on a queue, the workers of a queue
+// are shared evenly between them. It reads every waiting job, callers cache it
+func (p *PostgresImplementation) CountIngestOrgs(ctx context.Context, queue string) (int, error) {
+	var orgs int
+	query := `SELECT count(DISTINCT metadata->>'org_id') FROM river_job
+		WHERE state IN ('available', 'running') AND queue = $1 AND kind = 'url_ingest'`
+	if err := p.Pool.QueryRow(ctx, query, queue).Scan(&orgs); err != nil {
+		return 0, fmt.Errorf("failed to count ingest organizations: %v", err)
+	}
+	return orgs, nil
+}
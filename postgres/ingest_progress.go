@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// IngestProgressChannel is the notification channel ingest progress events travel on between instances
+const IngestProgressChannel = "ingest_progress"
+
+// NotifyIngestProgress sends an ingest progress event to every instance listening
+func (p *PostgresImplementation) NotifyIngestProgress(ctx context.Context, payload string) error {
+	if _, err := p.Pool.Exec(ctx, `SELECT pg_notify($1, $2)`, IngestProgressChannel, payload); err != nil {
+		return fmt.Errorf("failed to notify ingest progress: %v", err)
+	}
+	return nil
+}
+
+// ListenIngestProgress holds a connection listening for ingest progress events and calls handle with each payload.
+// It blocks until ctx is done or the connection fails
+func (p *PostgresImplementation) ListenIngestProgress(ctx context.Context, handle func(payload string)) error {
+	conn, err := p.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire listen connection: %v", err)
+	}
+	// a connection that was listening is not handed back to the pool
+	listenConn := conn.Hijack()
+	defer listenConn.Close(context.Background())
+
+	if _, err := listenConn.Exec(ctx, "LISTEN "+IngestProgressChannel); err != nil {
+		return fmt.Errorf("failed to listen for ingest progress: %v", err)
+	}
+	for {
+		notification, err := listenConn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for ingest progress: %v", err)
+		}
+		handle(notification.Payload)
+	}
+}
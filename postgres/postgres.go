@@ -3,7 +3,9 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rajnandan1/smaraka/models"
 	"go.jetify.com/typeid"
@@ -39,19 +41,88 @@ type Postgres interface {
 	GetURLStoreByID(ctx context.Context, id string) (*models.URLStore, error)
 	UpdateURLStoreByURL(ctx context.Context, url string, urlData models.URLStore) (*models.URLStore, error)
 	UpdateURLStoreByID(ctx context.Context, id string, urlData models.URLStore) (*models.URLStore, error)
+	UpdateURLStoreStatus(ctx context.Context, id, status string) error
 	GetURLStoreByIDs(ctx context.Context, ids []string) ([]models.URLStore, error)
 	GetURLsByIDs(ctx context.Context, ids []string) ([]models.URLStore, error)
 
-	//jobqueue
-	InsertJobQueues(ctx context.Context, org_id, job_id string, job_data []string) error
-	UpdateJobQueueStatus(ctx context.Context, job_id, url_id, status string) error
-	GetJobQueueStatusCount(ctx context.Context, org_id string) (map[string]int, error)
-	InsertJobQueue(ctx context.Context, org_id, job_data string) error
-	GetJobQueuePendingOlderThan1Hour(ctx context.Context, org_id string) ([]models.JobQueue, error)
+	//urlstore versions
+	GetURLStoresDueForRecrawl(ctx context.Context, before time.Time, limit int) ([]models.URLStore, error)
+	UpdateURLStoreCrawlState(ctx context.Context, id, etag, lastModified, contentHash string) error
+	UpdateURLStoreChangedContent(ctx context.Context, id, fullText, contentHash, etag, lastModified string, version models.URLStoreVersion) error
+	InsertURLStoreVersion(ctx context.Context, version models.URLStoreVersion) error
+	GetLatestURLStoreVersion(ctx context.Context, urlID string) (*models.URLStoreVersion, error)
+	GetURLStoreVersionAsOf(ctx context.Context, urlID string, at time.Time) (*models.URLStoreVersion, error)
+
+	//snapshots
+	InsertURLSnapshot(ctx context.Context, snapshot models.URLSnapshot) error
+	GetLatestURLSnapshot(ctx context.Context, urlID string) (*models.URLSnapshot, error)
+
+	//blob objects
+	GetOrgBlobUsage(ctx context.Context, orgID, exceptKey string) (int64, error)
+	ChargeBlobObject(ctx context.Context, key, orgID string, size, limit int64) (bool, error)
+	DeleteBlobObject(ctx context.Context, key string) error
+
+	//documents
+	UpdateURLStoreDocument(ctx context.Context, id, author, contentType, documentKey string, pageCount int) error
+
+	//site metadata
+	UpdateURLStoreSiteMetadata(ctx context.Context, id string, metadata *models.SiteMetadata) error
+	UpdateURLStoreStructuredData(ctx context.Context, id string, urlData models.URLStore) error
+
+	//reader view
+	UpdateURLStoreReaderView(ctx context.Context, id, articleHTML string, wordCount int) error
+
+	//language
+	UpdateURLStoreLanguage(ctx context.Context, id, language string) error
+	GetURLStoresWithoutLanguage(ctx context.Context, limit int) ([]models.URLStore, error)
+
+	//tags
+	UpdateURLStoreSuggestedTags(ctx context.Context, id string, tags []string) error
+	AcceptSuggestedTags(ctx context.Context, orgID string, urlOrgIDs, tags []string) error
+
+	//summaries
+	UpdateURLStoreSummary(ctx context.Context, id, summary string) error
+	GetURLStoresDueForSummary(ctx context.Context, limit int) ([]models.URLStore, error)
+
+	//link health
+	GetURLStoresDueForLinkCheck(ctx context.Context, before time.Time, limit int) ([]models.URLStore, error)
+	UpdateURLStoreLinkHealth(ctx context.Context, id, state string, statusCode int, finalURL string) error
+
+	//ingest jobs
+	GetIngestJobStateCounts(ctx context.Context, orgID string) (map[string]int, error)
+	NotifyIngestProgress(ctx context.Context, payload string) error
+	ListenIngestProgress(ctx context.Context, handle func(payload string)) error
+	IsIngestPaused(ctx context.Context, orgID string) (bool, error)
+	PauseIngestJobs(ctx context.Context, orgID, queue string) (int64, error)
+	ResumeIngestJobs(ctx context.Context, orgID, queue string) (int64, error)
+	CancelIngestJobs(ctx context.Context, orgID, queue string) (int64, error)
+	CountRunningIngestJobs(ctx context.Context, orgID, queue string) (int, error)
+	CountIngestOrgs(ctx context.Context, queue string) (int, error)
+
+	//import batches
+	InsertImportBatch(ctx context.Context, batch models.ImportBatch, urls []models.ImportBatchURL) error
+	UpdateImportBatchURLOutcome(ctx context.Context, batchID, url, outcome, reason string) error
+	GetOrganizationURLsPresent(ctx context.Context, orgID string, urls []string) (map[string]bool, error)
+	GetImportBatches(ctx context.Context, orgID, lastID string, limit int) ([]*models.ImportBatch, error)
+	GetImportBatch(ctx context.Context, orgID, id string) (*models.ImportBatch, error)
+	GetImportBatchURLs(ctx context.Context, batchID, outcome string, after, limit int) ([]models.ImportBatchURL, error)
+	UpdateFailedImportBatchURLs(ctx context.Context, batchID string, urls []string, outcome, reason string) error
+
+	//dead letters
+	GetDeadLetterGroups(ctx context.Context, orgID string) ([]models.DeadLetterGroup, error)
+	GetDeadLetters(ctx context.Context, orgID, failureClass string, ids []int64, after int64, limit int) ([]models.DeadLetter, error)
+	DeleteDeadLetters(ctx context.Context, orgID string, ids []int64) error
+	RetryDeadLetters(ctx context.Context, orgID string, letters []models.DeadLetter, queue func(tx pgx.Tx, batchID string, urls []string) error) error
+
+	//idempotency keys
+	ClaimIdempotencyKey(ctx context.Context, orgID, key, url string) (*models.IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, orgID, key, urlOrgID string) error
+	ReleaseIdempotencyKey(ctx context.Context, orgID, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) error
 
 	//urlorganizations
 	InsertNewURLOrganization(ctx context.Context, urlOrganization models.URLOrganizations) (*models.URLOrganizations, error)
-	GetURLsForOrganization(ctx context.Context, organizationID string, lastID string, pageSize int) ([]*models.URLResponses, error)
+	GetURLsForOrganization(ctx context.Context, organizationID string, lastID string, pageSize int, linkState string) ([]*models.URLResponses, error)
 	GetURLOrganizationByID(ctx context.Context, id string) (*models.URLOrganizations, error)
 	GetURLCountForOrganization(ctx context.Context, organizationID string) (int, error)
 	GetURLOrganizationsByURLIDOrgID(ctx context.Context, urlID string, organizationID string) (*models.URLOrganizations, error)
@@ -62,10 +133,11 @@ type Postgres interface {
 
 	//urlstore and urlorganizations
 	GetAllURLsForORG(ctx context.Context, orgID string) (*[]models.URLStore, *[]models.URLOrganizations, error)
-	SearchURLs(ctx context.Context, orgID, query, domain string) ([]*models.URLResponses, error)
+	SearchURLs(ctx context.Context, orgID, query, domain, language, tag string) ([]*models.URLResponses, error)
 	GetURLStoreByURLOrgIDOrgID(ctx context.Context, urlOrgID, orgID string) (*models.URLStore, error)
 	GetSingleURLForOrganization(ctx context.Context, organizationID string, urlOrgID string) (*models.URLResponses, error)
 	GetSingleURLForOrganizationURL(ctx context.Context, organizationID string, url string) (*models.URLResponses, error)
+	GetBrokenLinksForOrganization(ctx context.Context, organizationID string) ([]*models.BrokenLinkResponse, error)
 
 	//secrets
 	InsertNewSecret(ctx context.Context, secret models.DbSecret) error
@@ -9,8 +9,9 @@ import (
 
 func (p *PostgresImplementation) InsertNewURLStore(ctx context.Context, urlStore models.URLStore) (*models.URLStore, error) {
 	query := `
-		INSERT INTO url_store (id, url, domain, title, image_sm, image_lg, excerpt, color, status, full_content, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW());`
+		INSERT INTO url_store (id, url, domain, title, image_sm, image_lg, excerpt, color, status, full_content,
+			author, published_at, reading_time, site_name, canonical_image, article_html, word_count, language, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, NOW(), NOW());`
 
 	_, err := p.Pool.Exec(ctx, query,
 		urlStore.ID,
@@ -23,6 +24,14 @@ func (p *PostgresImplementation) InsertNewURLStore(ctx context.Context, urlStore
 		urlStore.AccentColor,
 		urlStore.Status,
 		urlStore.FullText,
+		urlStore.Author,
+		urlStore.PublishedAt,
+		urlStore.ReadingTime,
+		urlStore.SiteName,
+		urlStore.CanonicalImage,
+		urlStore.ArticleHTML,
+		urlStore.WordCount,
+		urlStore.Language,
 	)
 
 	if err != nil {
@@ -66,7 +75,9 @@ func (p *PostgresImplementation) GetURLStoreByID(ctx context.Context, id string)
 	var urlStore models.URLStore
 
 	query := `
-		SELECT id, url, domain, title, image_sm, image_lg, excerpt, color, status, full_content, created_at, updated_at
+		SELECT id, url, domain, title, image_sm, image_lg, excerpt, summary, color, status, full_content, created_at, updated_at, content_changed_at,
+		author, content_type, document_key, page_count, site_metadata,
+		published_at, reading_time, site_name, canonical_image, article_html, word_count, language
 		FROM url_store
 		WHERE id = $1;`
 
@@ -78,11 +89,25 @@ func (p *PostgresImplementation) GetURLStoreByID(ctx context.Context, id string)
 		&urlStore.ImageSmall,
 		&urlStore.ImageLarge,
 		&urlStore.Excerpt,
+		&urlStore.Summary,
 		&urlStore.AccentColor,
 		&urlStore.Status,
 		&urlStore.FullText,
 		&urlStore.CreatedAt,
 		&urlStore.UpdatedAt,
+		&urlStore.ContentChangedAt,
+		&urlStore.Author,
+		&urlStore.ContentType,
+		&urlStore.DocumentKey,
+		&urlStore.PageCount,
+		&urlStore.SiteMetadata,
+		&urlStore.PublishedAt,
+		&urlStore.ReadingTime,
+		&urlStore.SiteName,
+		&urlStore.CanonicalImage,
+		&urlStore.ArticleHTML,
+		&urlStore.WordCount,
+		&urlStore.Language,
 	)
 
 	if err != nil {
@@ -106,6 +131,15 @@ func (p *PostgresImplementation) UpdateURLStoreByID(ctx context.Context, id stri
 	return p.GetURLStoreByID(ctx, urlData.ID)
 }
 
+// UpdateURLStoreStatus sets the status of a url store alone, a fetch marks it complete once all of it is stored
+func (p *PostgresImplementation) UpdateURLStoreStatus(ctx context.Context, id, status string) error {
+	_, err := p.Pool.Exec(ctx, `UPDATE url_store SET status = $1, updated_at = NOW() WHERE id = $2`, status, id)
+	if err != nil {
+		return fmt.Errorf("failed to update url store status: %v", err)
+	}
+	return nil
+}
+
 func (p *PostgresImplementation) UpdateURLStoreByURL(ctx context.Context, url string, urlData models.URLStore) (*models.URLStore, error) {
 	query := `
 		UPDATE url_store
@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// UpdateURLStoreDocument records what is known about a url that is a document rather than a web page
+func (p *PostgresImplementation) UpdateURLStoreDocument(ctx context.Context, id, author, contentType, documentKey string, pageCount int) error {
+	query := `
+		UPDATE url_store
+		SET author = $1, content_type = $2, document_key = $3, page_count = $4
+		WHERE id = $5;`
+
+	_, err := p.Pool.Exec(ctx, query, author, contentType, documentKey, pageCount, id)
+	if err != nil {
+		return fmt.Errorf("failed to update url store document: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/lang"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// languageSearchField is a field of the search index reading full_content with the tokenizer of some languages
+type languageSearchField struct {
+	name      string
+	languages []string
+}
+
+// languageSearchFields are the index fields of the languages with a stemmer,
+// chinese, japanese and korean share one field split into characters
+func languageSearchFields() []languageSearchField {
+	fields := make([]languageSearchField, 0, len(lang.Languages))
+	for _, language := range lang.Languages {
+		if language.Stemmer != "" {
+			fields = append(fields, languageSearchField{name: "search_" + language.Code, languages: []string{language.Code}})
+		}
+	}
+	return append(fields, languageSearchField{name: "search_cjk", languages: []string{"zh", "ja", "ko"}})
+}
+
+// UpdateURLStoreLanguage stores the detected language, which decides the search field the url store is matched in
+func (p *PostgresImplementation) UpdateURLStoreLanguage(ctx context.Context, id, language string) error {
+	query := `
+		UPDATE url_store
+		SET language = $1
+		WHERE id = $2;`
+
+	_, err := p.Pool.Exec(ctx, query, language, id)
+	if err != nil {
+		return fmt.Errorf("failed to update url store language: %v", err)
+	}
+
+	return nil
+}
+
+// GetURLStoresWithoutLanguage returns complete url stores saved before languages were detected
+func (p *PostgresImplementation) GetURLStoresWithoutLanguage(ctx context.Context, limit int) ([]models.URLStore, error) {
+	var urlStores []models.URLStore
+
+	query := `
+		SELECT id, url, title, excerpt, full_content
+		FROM url_store
+		WHERE language = '' AND status = $1
+		LIMIT $2;`
+
+	rows, err := p.Pool.Query(ctx, query, constants.BookmarkStatusComplete, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve url stores without language: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var urlStore models.URLStore
+		err := rows.Scan(
+			&urlStore.ID,
+			&urlStore.URL,
+			&urlStore.Title,
+			&urlStore.Excerpt,
+			&urlStore.FullText,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan url store: %v", err)
+		}
+		urlStores = append(urlStores, urlStore)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over url stores: %v", err)
+	}
+
+	return urlStores, nil
+}
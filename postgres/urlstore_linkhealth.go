@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// GetURLStoresDueForLinkCheck returns url stores whose link was not checked since before, oldest first
+func (p *PostgresImplementation) GetURLStoresDueForLinkCheck(ctx context.Context, before time.Time, limit int) ([]models.URLStore, error) {
+	var urlStores []models.URLStore
+
+	query := `
+		SELECT id, url, link_state, link_status_code, link_final_url, link_checked_at
+		FROM url_store
+		WHERE link_checked_at IS NULL OR link_checked_at < $1
+		ORDER BY link_checked_at ASC NULLS FIRST
+		LIMIT $2;`
+
+	rows, err := p.Pool.Query(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve url stores due for link check: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var urlStore models.URLStore
+		err := rows.Scan(
+			&urlStore.ID,
+			&urlStore.URL,
+			&urlStore.LinkState,
+			&urlStore.LinkStatusCode,
+			&urlStore.LinkFinalURL,
+			&urlStore.LinkCheckedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan url store: %v", err)
+		}
+		urlStores = append(urlStores, urlStore)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over url stores: %v", err)
+	}
+
+	return urlStores, nil
+}
+
+// UpdateURLStoreLinkHealth records the outcome of a link check
+func (p *PostgresImplementation) UpdateURLStoreLinkHealth(ctx context.Context, id, state string, statusCode int, finalURL string) error {
+	query := `
+		UPDATE url_store
+		SET link_state = $1, link_status_code = $2, link_final_url = $3, link_checked_at = NOW()
+		WHERE id = $4;`
+
+	_, err := p.Pool.Exec(ctx, query, state, statusCode, finalURL, id)
+	if err != nil {
+		return fmt.Errorf("failed to update url store link health: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// UpdateURLStoreReaderView stores the cleaned article html served by the reader view
+func (p *PostgresImplementation) UpdateURLStoreReaderView(ctx context.Context, id, articleHTML string, wordCount int) error {
+	query := `
+		UPDATE url_store
+		SET article_html = $1, word_count = $2
+		WHERE id = $3;`
+
+	_, err := p.Pool.Exec(ctx, query, articleHTML, wordCount, id)
+	if err != nil {
+		return fmt.Errorf("failed to update url store reader view: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// UpdateURLStoreSiteMetadata stores what a site specific extractor found, nil clears it
+func (p *PostgresImplementation) UpdateURLStoreSiteMetadata(ctx context.Context, id string, metadata *models.SiteMetadata) error {
+	query := `
+		UPDATE url_store
+		SET site_metadata = $1
+		WHERE id = $2;`
+
+	_, err := p.Pool.Exec(ctx, query, metadata, id)
+	if err != nil {
+		return fmt.Errorf("failed to update url store site metadata: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateURLStoreStructuredData stores the metadata a page declares about itself, author, dates and the like
+func (p *PostgresImplementation) UpdateURLStoreStructuredData(ctx context.Context, id string, urlData models.URLStore) error {
+	query := `
+		UPDATE url_store
+		SET author = $1, published_at = $2, reading_time = $3, site_name = $4, canonical_image = $5
+		WHERE id = $6;`
+
+	_, err := p.Pool.Exec(ctx, query, urlData.Author, urlData.PublishedAt, urlData.ReadingTime, urlData.SiteName, urlData.CanonicalImage, id)
+	if err != nil {
+		return fmt.Errorf("failed to update url store structured data: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rajnandan1/smaraka/models"
+)
+
+func (p *PostgresImplementation) InsertURLSnapshot(ctx context.Context, snapshot models.URLSnapshot) error {
+	query := `
+		INSERT INTO url_snapshots (id, url_id, blob_key, content_type, size_bytes, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW());`
+
+	_, err := p.Pool.Exec(ctx, query, snapshot.ID, snapshot.URLID, snapshot.BlobKey, snapshot.ContentType, snapshot.SizeBytes)
+	if err != nil {
+		return fmt.Errorf("failed to insert url snapshot: %v", err)
+	}
+
+	return nil
+}
+
+// GetLatestURLSnapshot returns the newest snapshot of a url store
+func (p *PostgresImplementation) GetLatestURLSnapshot(ctx context.Context, urlID string) (*models.URLSnapshot, error) {
+	var snapshot models.URLSnapshot
+
+	query := `
+		SELECT id, url_id, blob_key, content_type, size_bytes, created_at
+		FROM url_snapshots
+		WHERE url_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1;`
+
+	err := p.Pool.QueryRow(ctx, query, urlID).Scan(
+		&snapshot.ID,
+		&snapshot.URLID,
+		&snapshot.BlobKey,
+		&snapshot.ContentType,
+		&snapshot.SizeBytes,
+		&snapshot.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve url snapshot: %v", err)
+	}
+
+	return &snapshot, nil
+}
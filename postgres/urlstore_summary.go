@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// UpdateURLStoreSummary stores the summary of a url store and when it was made
+func (p *PostgresImplementation) UpdateURLStoreSummary(ctx context.Context, id, summary string) error {
+	query := `
+		UPDATE url_store
+		SET summary = $1, summarized_at = NOW()
+		WHERE id = $2;`
+
+	_, err := p.Pool.Exec(ctx, query, summary, id)
+	if err != nil {
+		return fmt.Errorf("failed to update url store summary: %v", err)
+	}
+
+	return nil
+}
+
+// GetURLStoresDueForSummary returns complete url stores never summarized or whose content changed since
+func (p *PostgresImplementation) GetURLStoresDueForSummary(ctx context.Context, limit int) ([]models.URLStore, error) {
+	var urlStores []models.URLStore
+
+	query := `
+		SELECT id, url, full_content, language
+		FROM url_store
+		WHERE status = $1 AND (summarized_at IS NULL OR summarized_at < content_changed_at)
+		ORDER BY updated_at DESC
+		LIMIT $2;`
+
+	rows, err := p.Pool.Query(ctx, query, constants.BookmarkStatusComplete, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve url stores due for summary: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var urlStore models.URLStore
+		err := rows.Scan(
+			&urlStore.ID,
+			&urlStore.URL,
+			&urlStore.FullText,
+			&urlStore.Language,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan url store: %v", err)
+		}
+		urlStores = append(urlStores, urlStore)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over url stores: %v", err)
+	}
+
+	return urlStores, nil
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// UpdateURLStoreSuggestedTags stores the tags suggested from the content of a url store
+func (p *PostgresImplementation) UpdateURLStoreSuggestedTags(ctx context.Context, id string, tags []string) error {
+	query := `
+		UPDATE url_store
+		SET suggested_tags = $1
+		WHERE id = $2;`
+
+	_, err := p.Pool.Exec(ctx, query, tags, id)
+	if err != nil {
+		return fmt.Errorf("failed to update url store suggested tags: %v", err)
+	}
+
+	return nil
+}
+
+// AcceptSuggestedTags adds suggested tags to the tags of bookmarks of the organization, all suggestions when tags is empty.
+// Tags that were not suggested for a bookmark are left out
+func (p *PostgresImplementation) AcceptSuggestedTags(ctx context.Context, orgID string, urlOrgIDs, tags []string) error {
+	query := `
+		UPDATE url_organizations uo
+		SET tags = uo.tags || ARRAY(
+			SELECT t FROM unnest(us.suggested_tags) AS t
+			WHERE NOT t = ANY(uo.tags) AND (COALESCE(cardinality($3::text[]), 0) = 0 OR t = ANY($3::text[]))
+		), updated_at = NOW()
+		FROM url_store us
+		WHERE us.id = uo.url_id AND uo.organization_id = $1 AND uo.id = ANY($2);`
+
+	_, err := p.Pool.Exec(ctx, query, orgID, urlOrgIDs, tags)
+	if err != nil {
+		return fmt.Errorf("failed to accept suggested tags: %v", err)
+	}
+
+	return nil
+}
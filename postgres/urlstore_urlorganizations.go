@@ -46,13 +46,22 @@ func (p *PostgresImplementation) GetAllURLsForORG(ctx context.Context, orgID str
 	return &urlStores, &urlOrganizations, nil
 }
 
-func (p *PostgresImplementation) SearchURLs(ctx context.Context, orgID, query, domain string) ([]*models.URLResponses, error) {
+func (p *PostgresImplementation) SearchURLs(ctx context.Context, orgID, query, domain, language, tag string) ([]*models.URLResponses, error) {
 	// Prepare SQL statement
 
 	terms := strings.Fields(query) // Split the query by whitespace
+
+	// the query is also matched against the language fields, each tokenizes and stems it the way its documents were.
+	// Every page is indexed in every language field, a page only counts in the field of its own language
+	languageMatches := ""
+	for _, field := range languageSearchFields() {
+		languageMatches += fmt.Sprintf(`
+				or
+				(us.language IN ('%s') and us.id @@@ paradedb.match('%s', $4, conjunction_mode => true))`, strings.Join(field.languages, "', '"), field.name)
+	}
 	queryStr := `
-			SELECT us.id as url_id, us.title, us.url, us.excerpt, us.image_sm, us.image_lg, us.color,
-			uo.id as organization_relation_id, uo.status as organization_url_status, paradedb.score(us.id)
+			SELECT us.id as url_id, us.title, us.url, us.excerpt, us.summary, us.image_sm, us.image_lg, us.color,
+			uo.id as organization_relation_id, uo.status as organization_url_status, COALESCE(us.content_changed_at > uo.created_at, false) as changed_since_saved, us.link_state, us.link_status_code, us.content_type, us.site_metadata, us.author, us.published_at, us.reading_time, us.site_name, us.language, uo.tags, ARRAY(SELECT t FROM unnest(us.suggested_tags) AS t WHERE NOT t = ANY(uo.tags)) as suggested_tags, paradedb.score(us.id)
 			FROM url_organizations uo
 			JOIN url_store us ON uo.url_id = us.id
 			WHERE uo.organization_id = $1 AND (
@@ -63,13 +72,15 @@ func (p *PostgresImplementation) SearchURLs(ctx context.Context, orgID, query, d
 				us.id @@@ paradedb.term('domain', $4)
 				or
 				us.id @@@ paradedb.phrase_prefix('title', $3::text[])
-			) and uo.status = $2
+				or
+				us.id @@@ paradedb.match('suggested_tags', $4, conjunction_mode => true)` + languageMatches + `
+			) and uo.status = $2 and ($5 = '' or us.language = $5) and ($6 = '' or $6 = ANY(uo.tags))
 		`
 
 	queryStr += " ORDER BY paradedb.score(us.id) DESC limit 100;"
 
 	var rows pgx.Rows
-	rows, err := p.Pool.Query(ctx, queryStr, orgID, constants.URLStatusActive, terms, query)
+	rows, err := p.Pool.Query(ctx, queryStr, orgID, constants.URLStatusActive, terms, query, language, tag)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to search urls: %v", err)
@@ -84,11 +95,24 @@ func (p *PostgresImplementation) SearchURLs(ctx context.Context, orgID, query, d
 			&urlStore.Title,
 			&urlStore.URL,
 			&urlStore.Excerpt,
+			&urlStore.Summary,
 			&urlStore.ImageSmall,
 			&urlStore.ImageLarge,
 			&urlStore.AccentColor,
 			&urlStore.OrganizationRelationID,
 			&urlStore.OrganizationURLStatus,
+			&urlStore.ChangedSinceSaved,
+			&urlStore.LinkState,
+			&urlStore.LinkStatusCode,
+			&urlStore.ContentType,
+			&urlStore.SiteMetadata,
+			&urlStore.Author,
+			&urlStore.PublishedAt,
+			&urlStore.ReadingTime,
+			&urlStore.SiteName,
+			&urlStore.Language,
+			&urlStore.Tags,
+			&urlStore.SuggestedTags,
 			&urlStore.Score,
 		)
 		if err != nil {
@@ -100,19 +124,20 @@ func (p *PostgresImplementation) SearchURLs(ctx context.Context, orgID, query, d
 	return urlStores, nil
 }
 
-func (p *PostgresImplementation) GetURLsForOrganization(ctx context.Context, organizationID string, lastID string, pageSize int) ([]*models.URLResponses, error) {
+func (p *PostgresImplementation) GetURLsForOrganization(ctx context.Context, organizationID string, lastID string, pageSize int, linkState string) ([]*models.URLResponses, error) {
 	var urlOrganizations []*models.URLResponses
 
 	query := `
-        SELECT us.id as url_id, us.title, us.url, us.excerpt, us.image_sm, us.image_lg, us.color, 
-        uo.id as organization_relation_id, uo.status as organization_url_status
+        SELECT us.id as url_id, us.title, us.url, us.excerpt, us.summary, us.image_sm, us.image_lg, us.color, 
+        uo.id as organization_relation_id, uo.status as organization_url_status, COALESCE(us.content_changed_at > uo.created_at, false) as changed_since_saved, us.link_state, us.link_status_code, us.content_type, us.site_metadata, us.author, us.published_at, us.reading_time, us.site_name, us.language, uo.tags, ARRAY(SELECT t FROM unnest(us.suggested_tags) AS t WHERE NOT t = ANY(uo.tags)) as suggested_tags
         FROM url_organizations uo
         JOIN url_store us ON uo.url_id = us.id
         WHERE uo.organization_id = $1 AND uo.id < $2 and uo.status = $3
+        AND ($5 = '' OR us.link_state = $5)
         ORDER BY uo.id DESC
         LIMIT $4`
 
-	rows, err := p.Pool.Query(ctx, query, organizationID, lastID, constants.URLStatusActive, pageSize)
+	rows, err := p.Pool.Query(ctx, query, organizationID, lastID, constants.URLStatusActive, pageSize, linkState)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve urls for organization: %v", err)
 	}
@@ -127,11 +152,24 @@ func (p *PostgresImplementation) GetURLsForOrganization(ctx context.Context, org
 			&urlOrganization.Title,
 			&urlOrganization.URL,
 			&urlOrganization.Excerpt,
+			&urlOrganization.Summary,
 			&urlOrganization.ImageSmall,
 			&urlOrganization.ImageLarge,
 			&urlOrganization.AccentColor,
 			&urlOrganization.OrganizationRelationID,
 			&urlOrganization.OrganizationURLStatus,
+			&urlOrganization.ChangedSinceSaved,
+			&urlOrganization.LinkState,
+			&urlOrganization.LinkStatusCode,
+			&urlOrganization.ContentType,
+			&urlOrganization.SiteMetadata,
+			&urlOrganization.Author,
+			&urlOrganization.PublishedAt,
+			&urlOrganization.ReadingTime,
+			&urlOrganization.SiteName,
+			&urlOrganization.Language,
+			&urlOrganization.Tags,
+			&urlOrganization.SuggestedTags,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan url organization: %v", err)
@@ -151,7 +189,9 @@ func (p *PostgresImplementation) GetURLsForOrganization(ctx context.Context, org
 // GetURLStoreByURLOrgIDOrgID
 func (p *PostgresImplementation) GetURLStoreByURLOrgIDOrgID(ctx context.Context, urlOrgID, orgID string) (*models.URLStore, error) {
 	query := `
-		SELECT us.id, us.url, us.domain, us.title, us.image_sm, us.image_lg, us.excerpt, us.color, us.status, us.full_content, us.created_at, us.updated_at
+		SELECT us.id, us.url, us.domain, us.title, us.image_sm, us.image_lg, us.excerpt, us.summary, us.color, us.status, us.full_content, us.created_at, us.updated_at, us.content_changed_at,
+		us.author, us.content_type, us.document_key, us.page_count, us.site_metadata,
+		us.published_at, us.reading_time, us.site_name, us.canonical_image, us.article_html, us.word_count, us.language
 		FROM url_organizations uo
 		JOIN url_store us ON uo.url_id = us.id
 		WHERE uo.id = $1 AND uo.organization_id = $2;`
@@ -167,11 +207,25 @@ func (p *PostgresImplementation) GetURLStoreByURLOrgIDOrgID(ctx context.Context,
 		&urlStore.ImageSmall,
 		&urlStore.ImageLarge,
 		&urlStore.Excerpt,
+		&urlStore.Summary,
 		&urlStore.AccentColor,
 		&urlStore.Status,
 		&urlStore.FullText,
 		&urlStore.CreatedAt,
 		&urlStore.UpdatedAt,
+		&urlStore.ContentChangedAt,
+		&urlStore.Author,
+		&urlStore.ContentType,
+		&urlStore.DocumentKey,
+		&urlStore.PageCount,
+		&urlStore.SiteMetadata,
+		&urlStore.PublishedAt,
+		&urlStore.ReadingTime,
+		&urlStore.SiteName,
+		&urlStore.CanonicalImage,
+		&urlStore.ArticleHTML,
+		&urlStore.WordCount,
+		&urlStore.Language,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve url store: %v", err)
@@ -182,8 +236,8 @@ func (p *PostgresImplementation) GetURLStoreByURLOrgIDOrgID(ctx context.Context,
 
 func (p *PostgresImplementation) GetSingleURLForOrganization(ctx context.Context, organizationID string, urlOrgID string) (*models.URLResponses, error) {
 	query := `
-		SELECT us.id as url_id, us.title, us.url, us.excerpt, us.image_sm, us.image_lg, us.color, 
-		uo.id as organization_relation_id, uo.status as organization_url_status
+		SELECT us.id as url_id, us.title, us.url, us.excerpt, us.summary, us.image_sm, us.image_lg, us.color, 
+		uo.id as organization_relation_id, uo.status as organization_url_status, COALESCE(us.content_changed_at > uo.created_at, false) as changed_since_saved, us.link_state, us.link_status_code, us.content_type, us.site_metadata, us.author, us.published_at, us.reading_time, us.site_name, us.language, uo.tags, ARRAY(SELECT t FROM unnest(us.suggested_tags) AS t WHERE NOT t = ANY(uo.tags)) as suggested_tags
 		FROM url_organizations uo
 		JOIN url_store us ON uo.url_id = us.id
 		WHERE uo.organization_id = $1 AND uo.id = $2`
@@ -196,11 +250,24 @@ func (p *PostgresImplementation) GetSingleURLForOrganization(ctx context.Context
 		&urlOrganization.Title,
 		&urlOrganization.URL,
 		&urlOrganization.Excerpt,
+		&urlOrganization.Summary,
 		&urlOrganization.ImageSmall,
 		&urlOrganization.ImageLarge,
 		&urlOrganization.AccentColor,
 		&urlOrganization.OrganizationRelationID,
 		&urlOrganization.OrganizationURLStatus,
+		&urlOrganization.ChangedSinceSaved,
+		&urlOrganization.LinkState,
+		&urlOrganization.LinkStatusCode,
+		&urlOrganization.ContentType,
+		&urlOrganization.SiteMetadata,
+		&urlOrganization.Author,
+		&urlOrganization.PublishedAt,
+		&urlOrganization.ReadingTime,
+		&urlOrganization.SiteName,
+		&urlOrganization.Language,
+		&urlOrganization.Tags,
+		&urlOrganization.SuggestedTags,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve url organization: %v", err)
@@ -211,8 +278,8 @@ func (p *PostgresImplementation) GetSingleURLForOrganization(ctx context.Context
 
 func (p *PostgresImplementation) GetSingleURLForOrganizationURL(ctx context.Context, organizationID string, url string) (*models.URLResponses, error) {
 	query := `
-		SELECT us.id as url_id, us.title, us.url, us.excerpt, us.image_sm, us.image_lg, us.color, 
-		uo.id as organization_relation_id, uo.status as organization_url_status
+		SELECT us.id as url_id, us.title, us.url, us.excerpt, us.summary, us.image_sm, us.image_lg, us.color, 
+		uo.id as organization_relation_id, uo.status as organization_url_status, COALESCE(us.content_changed_at > uo.created_at, false) as changed_since_saved, us.link_state, us.link_status_code, us.content_type, us.site_metadata, us.author, us.published_at, us.reading_time, us.site_name, us.language, uo.tags, ARRAY(SELECT t FROM unnest(us.suggested_tags) AS t WHERE NOT t = ANY(uo.tags)) as suggested_tags
 		FROM url_organizations uo
 		JOIN url_store us ON uo.url_id = us.id
 		WHERE uo.organization_id = $1 AND us.url = $2`
@@ -225,11 +292,24 @@ func (p *PostgresImplementation) GetSingleURLForOrganizationURL(ctx context.Cont
 		&urlOrganization.Title,
 		&urlOrganization.URL,
 		&urlOrganization.Excerpt,
+		&urlOrganization.Summary,
 		&urlOrganization.ImageSmall,
 		&urlOrganization.ImageLarge,
 		&urlOrganization.AccentColor,
 		&urlOrganization.OrganizationRelationID,
 		&urlOrganization.OrganizationURLStatus,
+		&urlOrganization.ChangedSinceSaved,
+		&urlOrganization.LinkState,
+		&urlOrganization.LinkStatusCode,
+		&urlOrganization.ContentType,
+		&urlOrganization.SiteMetadata,
+		&urlOrganization.Author,
+		&urlOrganization.PublishedAt,
+		&urlOrganization.ReadingTime,
+		&urlOrganization.SiteName,
+		&urlOrganization.Language,
+		&urlOrganization.Tags,
+		&urlOrganization.SuggestedTags,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve url organization: %v", err)
@@ -237,3 +317,63 @@ func (p *PostgresImplementation) GetSingleURLForOrganizationURL(ctx context.Cont
 
 	return &urlOrganization, nil
 }
+
+// GetBrokenLinksForOrganization returns the active bookmarks of an organization whose last link check was not OK
+func (p *PostgresImplementation) GetBrokenLinksForOrganization(ctx context.Context, organizationID string) ([]*models.BrokenLinkResponse, error) {
+	brokenLinks := make([]*models.BrokenLinkResponse, 0)
+
+	query := `
+		SELECT us.id as url_id, us.title, us.url, us.excerpt, us.summary, us.image_sm, us.image_lg, us.color,
+		uo.id as organization_relation_id, uo.status as organization_url_status, COALESCE(us.content_changed_at > uo.created_at, false) as changed_since_saved, us.link_state, us.link_status_code, us.content_type, us.site_metadata, us.author, us.published_at, us.reading_time, us.site_name, us.language, uo.tags, ARRAY(SELECT t FROM unnest(us.suggested_tags) AS t WHERE NOT t = ANY(uo.tags)) as suggested_tags,
+		us.link_final_url, us.link_checked_at
+		FROM url_organizations uo
+		JOIN url_store us ON uo.url_id = us.id
+		WHERE uo.organization_id = $1 AND uo.status = $2 AND us.link_state NOT IN ('', $3)
+		ORDER BY us.link_checked_at DESC`
+
+	rows, err := p.Pool.Query(ctx, query, organizationID, constants.URLStatusActive, constants.LinkStateOK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve broken links for organization: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		brokenLink := &models.BrokenLinkResponse{}
+		err := rows.Scan(
+			&brokenLink.URLID,
+			&brokenLink.Title,
+			&brokenLink.URL,
+			&brokenLink.Excerpt,
+			&brokenLink.Summary,
+			&brokenLink.ImageSmall,
+			&brokenLink.ImageLarge,
+			&brokenLink.AccentColor,
+			&brokenLink.OrganizationRelationID,
+			&brokenLink.OrganizationURLStatus,
+			&brokenLink.ChangedSinceSaved,
+			&brokenLink.LinkState,
+			&brokenLink.LinkStatusCode,
+			&brokenLink.ContentType,
+			&brokenLink.SiteMetadata,
+			&brokenLink.Author,
+			&brokenLink.PublishedAt,
+			&brokenLink.ReadingTime,
+			&brokenLink.SiteName,
+			&brokenLink.Language,
+			&brokenLink.Tags,
+			&brokenLink.SuggestedTags,
+			&brokenLink.LinkFinalURL,
+			&brokenLink.LinkCheckedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan broken link: %v", err)
+		}
+		brokenLinks = append(brokenLinks, brokenLink)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+
+	return brokenLinks, nil
+}
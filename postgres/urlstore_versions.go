@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// GetURLStoresDueForRecrawl returns complete url stores not crawled since before, oldest first
+func (p *PostgresImplementation) GetURLStoresDueForRecrawl(ctx context.Context, before time.Time, limit int) ([]models.URLStore, error) {
+	var urlStores []models.URLStore
+
+	query := `
+		SELECT id, url, full_content, etag, last_modified, content_hash, last_crawled_at
+		FROM url_store
+		WHERE status = $1 AND (last_crawled_at IS NULL OR last_crawled_at < $2)
+		ORDER BY last_crawled_at ASC NULLS FIRST
+		LIMIT $3;`
+
+	rows, err := p.Pool.Query(ctx, query, constants.BookmarkStatusComplete, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve url stores due for recrawl: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var urlStore models.URLStore
+		err := rows.Scan(
+			&urlStore.ID,
+			&urlStore.URL,
+			&urlStore.FullText,
+			&urlStore.ETag,
+			&urlStore.LastModified,
+			&urlStore.ContentHash,
+			&urlStore.LastCrawledAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan url store: %v", err)
+		}
+		urlStores = append(urlStores, urlStore)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over url stores: %v", err)
+	}
+
+	return urlStores, nil
+}
+
+// UpdateURLStoreCrawlState records a crawl that did not change the content
+func (p *PostgresImplementation) UpdateURLStoreCrawlState(ctx context.Context, id, etag, lastModified, contentHash string) error {
+	query := `
+		UPDATE url_store
+		SET etag = $1, last_modified = $2, content_hash = $3, last_crawled_at = NOW()
+		WHERE id = $4;`
+
+	_, err := p.Pool.Exec(ctx, query, etag, lastModified, contentHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update url store crawl state: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateURLStoreChangedContent stores new content found by a recrawl along with its version
+func (p *PostgresImplementation) UpdateURLStoreChangedContent(ctx context.Context, id, fullText, contentHash, etag, lastModified string, version models.URLStoreVersion) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO url_store_versions (id, url_id, full_content, content_hash, created_at)
+		VALUES ($1, $2, $3, $4, NOW());`,
+		version.ID, version.URLID, version.FullText, version.ContentHash)
+	if err != nil {
+		return fmt.Errorf("failed to insert url store version: %v", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE url_store
+		SET full_content = $1, content_hash = $2, etag = $3, last_modified = $4,
+		last_crawled_at = NOW(), content_changed_at = NOW(), updated_at = NOW()
+		WHERE id = $5;`,
+		fullText, contentHash, etag, lastModified, id)
+	if err != nil {
+		return fmt.Errorf("failed to update url store content: %v", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresImplementation) InsertURLStoreVersion(ctx context.Context, version models.URLStoreVersion) error {
+	query := `
+		INSERT INTO url_store_versions (id, url_id, full_content, content_hash, created_at)
+		VALUES ($1, $2, $3, $4, NOW());`
+
+	_, err := p.Pool.Exec(ctx, query, version.ID, version.URLID, version.FullText, version.ContentHash)
+	if err != nil {
+		return fmt.Errorf("failed to insert url store version: %v", err)
+	}
+
+	return nil
+}
+
+// GetLatestURLStoreVersion returns the newest version of a url store
+func (p *PostgresImplementation) GetLatestURLStoreVersion(ctx context.Context, urlID string) (*models.URLStoreVersion, error) {
+	var version models.URLStoreVersion
+
+	query := `
+		SELECT id, url_id, full_content, content_hash, created_at
+		FROM url_store_versions
+		WHERE url_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1;`
+
+	err := p.Pool.QueryRow(ctx, query, urlID).Scan(
+		&version.ID,
+		&version.URLID,
+		&version.FullText,
+		&version.ContentHash,
+		&version.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve url store version: %v", err)
+	}
+
+	return &version, nil
+}
+
+// GetURLStoreVersionAsOf returns the version that was current at the given time, or the oldest one
+func (p *PostgresImplementation) GetURLStoreVersionAsOf(ctx context.Context, urlID string, at time.Time) (*models.URLStoreVersion, error) {
+	var version models.URLStoreVersion
+
+	query := `
+		SELECT id, url_id, full_content, content_hash, created_at
+		FROM url_store_versions
+		WHERE url_id = $1
+		ORDER BY (created_at <= $2) DESC,
+		CASE WHEN created_at <= $2 THEN created_at END DESC,
+		created_at ASC
+		LIMIT 1;`
+
+	err := p.Pool.QueryRow(ctx, query, urlID, at).Scan(
+		&version.ID,
+		&version.URLID,
+		&version.FullText,
+		&version.ContentHash,
+		&version.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve url store version: %v", err)
+	}
+
+	return &version, nil
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+)
+
+var ErrBrowserPoolClosed = errors.New("browser pool is closed")
+
+// a tab is closed after this many pages so listeners and memory do not pile up
+const maxTabUses = 25
+
+type poolTab struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	uses       int
+	generation int
+	// priority is set while the tab runs a priority fetch
+	priority bool
+}
+
+// BrowserPool keeps one long lived headless Chrome and hands out a bounded number of tabs.
+// Healthy tabs are recycled, a crashed browser is restarted on the next acquire.
+// Some tabs are reserved for priority fetches, background fetches never hold all of them
+type BrowserPool struct {
+	mu            sync.Mutex
+	options       []chromedp.ExecAllocatorOption
+	allocCancel   context.CancelFunc
+	browserCtx    context.Context
+	browserCancel context.CancelFunc
+	generation    int
+	idle          []*poolTab
+	slots         chan struct{}
+	// shared are the slots background fetches take before a slot, the tabs left over are reserved
+	shared chan struct{}
+	closed bool
+}
+
+func NewBrowserPool(maxTabs, reservedTabs int) *BrowserPool {
+	if maxTabs < 1 {
+		maxTabs = 1
+	}
+	// a single tab is shared, a reservation would keep background fetches from running at all
+	reservedTabs = min(max(reservedTabs, 0), maxTabs-1)
+	return &BrowserPool{
+		options: append(chromedp.DefaultExecAllocatorOptions[:],
+			chromedp.UserAgent(constants.HeadlessUserAgent),
+		),
+		idle:   make([]*poolTab, 0, maxTabs),
+		slots:  make(chan struct{}, maxTabs),
+		shared: make(chan struct{}, maxTabs-reservedTabs),
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority marks the fetches of ctx as ones a user waits for, they may use the reserved tabs
+func WithPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, priorityKey{}, true)
+}
+
+func hasPriority(ctx context.Context) bool {
+	priority, _ := ctx.Value(priorityKey{}).(bool)
+	return priority
+}
+
+// browserAlive reports if the current browser is still connected, must hold mu
+func (p *BrowserPool) browserAlive() bool {
+	if p.browserCtx == nil || p.browserCtx.Err() != nil {
+		return false
+	}
+	c := chromedp.FromContext(p.browserCtx)
+	if c == nil || c.Browser == nil {
+		return false
+	}
+	select {
+	case <-c.Browser.LostConnection:
+		return false
+	default:
+		return true
+	}
+}
+
+// startBrowser (re)launches chrome, must hold mu
+func (p *BrowserPool) startBrowser() error {
+	p.stopBrowser()
+
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), p.options...)
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+	if err := chromedp.Run(browserCtx); err != nil {
+		browserCancel()
+		allocCancel()
+		return err
+	}
+
+	p.allocCancel = allocCancel
+	p.browserCtx = browserCtx
+	p.browserCancel = browserCancel
+	p.generation++
+	logger.LogInfo("Started headless browser, generation", p.generation)
+	return nil
+}
+
+// stopBrowser closes all idle tabs and the browser process, must hold mu
+func (p *BrowserPool) stopBrowser() {
+	for _, tab := range p.idle {
+		tab.cancel()
+	}
+	p.idle = p.idle[:0]
+	if p.browserCancel != nil {
+		p.browserCancel()
+		p.browserCancel = nil
+	}
+	if p.allocCancel != nil {
+		p.allocCancel()
+		p.allocCancel = nil
+	}
+	p.browserCtx = nil
+}
+
+func (p *BrowserPool) acquire(ctx context.Context) (*poolTab, error) {
+	priority := hasPriority(ctx)
+	if !priority {
+		select {
+		case p.shared <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		p.releaseSlots(priority)
+		return nil, ctx.Err()
+	}
+
+	tab, err := p.takeTab()
+	if err != nil {
+		<-p.slots
+		p.releaseSlots(priority)
+		return nil, err
+	}
+	tab.priority = priority
+	return tab, nil
+}
+
+// releaseSlots gives back the shared slot of a background fetch, the tab slot is given back by the caller
+func (p *BrowserPool) releaseSlots(priority bool) {
+	if !priority {
+		<-p.shared
+	}
+}
+
+func (p *BrowserPool) takeTab() (*poolTab, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, ErrBrowserPoolClosed
+	}
+	if !p.browserAlive() {
+		if p.browserCtx != nil {
+			logger.LogError("Headless browser lost, restarting")
+		}
+		if err := p.startBrowser(); err != nil {
+			return nil, err
+		}
+	}
+
+	for len(p.idle) > 0 {
+		tab := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if tab.generation == p.generation && tab.ctx.Err() == nil {
+			return tab, nil
+		}
+		tab.cancel()
+	}
+
+	tabCtx, tabCancel := chromedp.NewContext(p.browserCtx)
+	if err := chromedp.Run(tabCtx); err != nil {
+		tabCancel()
+		return nil, err
+	}
+	return &poolTab{
+		ctx:        tabCtx,
+		cancel:     tabCancel,
+		generation: p.generation,
+	}, nil
+}
+
+func (p *BrowserPool) release(tab *poolTab, healthy bool) {
+	defer func() {
+		<-p.slots
+		p.releaseSlots(tab.priority)
+	}()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tab.uses++
+	if p.closed || !healthy || tab.uses >= maxTabUses || tab.generation != p.generation || tab.ctx.Err() != nil {
+		tab.cancel()
+		return
+	}
+	p.idle = append(p.idle, tab)
+}
+
+// Run executes fn on a pooled tab, bounded by timeout and by the caller's ctx
+func (p *BrowserPool) Run(ctx context.Context, timeout time.Duration, fn func(tabCtx context.Context) error) error {
+	tab, err := p.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithTimeout(tab.ctx, timeout)
+	stop := context.AfterFunc(ctx, cancel)
+	err = fn(runCtx)
+	stop()
+	cancel()
+
+	// a tab that errored might be stuck mid navigation, never hand it out again.
+	// The viewport a screenshot emulated stays with the tab, the next page is shown in the browser's own again
+	healthy := err == nil
+	if healthy {
+		blankCtx, blankCancel := context.WithTimeout(tab.ctx, 5*time.Second)
+		healthy = chromedp.Run(blankCtx, emulation.ClearDeviceMetricsOverride(), chromedp.Navigate("about:blank")) == nil
+		blankCancel()
+	}
+	p.release(tab, healthy)
+	return err
+}
+
+// Close shuts the browser down, in flight pages are cancelled
+func (p *BrowserPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.stopBrowser()
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/rajnandan1/smaraka/blobstore"
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/postgres"
+)
+
+func TestMain(m *testing.M) {
+	logger.StartLogger(constants.EnvDevelopment)
+	os.Exit(m.Run())
+}
+
+// completeDB keeps one url store in memory and fails, or cancels the job's context, at a chosen write
+type completeDB struct {
+	postgres.Postgres
+	store  models.URLStore
+	writes []string
+	failAt string
+	cancel context.CancelFunc
+	// snapshots are the snapshots stored, a snapshot is not part of what makes a url store complete
+	snapshots []models.URLSnapshot
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (db *completeDB) write(ctx context.Context, name string) error {
+	if name == db.failAt {
+		if db.cancel == nil {
+			return errWriteFailed
+		}
+		db.cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.writes = append(db.writes, name)
+	return nil
+}
+
+func (db *completeDB) NewID(prefix string) string { return prefix + "_1" }
+
+func (db *completeDB) UpdateURLStoreByID(ctx context.Context, id string, urlData models.URLStore) (*models.URLStore, error) {
+	if err := db.write(ctx, "url_store"); err != nil {
+		return nil, err
+	}
+	db.store = urlData
+	stored := db.store
+	return &stored, nil
+}
+
+func (db *completeDB) UpdateURLStoreStatus(ctx context.Context, id, status string) error {
+	if err := db.write(ctx, "status"); err != nil {
+		return err
+	}
+	db.store.Status = status
+	return nil
+}
+
+func (db *completeDB) UpdateURLStoreStructuredData(ctx context.Context, id string, urlData models.URLStore) error {
+	return db.write(ctx, "structured_data")
+}
+
+func (db *completeDB) UpdateURLStoreReaderView(ctx context.Context, id, articleHTML string, wordCount int) error {
+	return db.write(ctx, "reader_view")
+}
+
+func (db *completeDB) UpdateURLStoreLanguage(ctx context.Context, id, language string) error {
+	return db.write(ctx, "language")
+}
+
+func (db *completeDB) GetLatestURLStoreVersion(ctx context.Context, urlID string) (*models.URLStoreVersion, error) {
+	return nil, errors.New("no version")
+}
+
+func (db *completeDB) InsertURLStoreVersion(ctx context.Context, version models.URLStoreVersion) error {
+	return db.write(ctx, "version")
+}
+
+func (db *completeDB) UpdateURLStoreCrawlState(ctx context.Context, id, etag, lastModified, contentHash string) error {
+	return db.write(ctx, "crawl_state")
+}
+
+func (db *completeDB) UpdateURLStoreSiteMetadata(ctx context.Context, id string, metadata *models.SiteMetadata) error {
+	return db.write(ctx, "site_metadata")
+}
+
+func (db *completeDB) UpdateURLStoreSuggestedTags(ctx context.Context, id string, tags []string) error {
+	return db.write(ctx, "suggested_tags")
+}
+
+func (db *completeDB) UpdateURLStoreSummary(ctx context.Context, id, summary string) error {
+	return db.write(ctx, "summary")
+}
+
+func (db *completeDB) InsertURLSnapshot(ctx context.Context, snapshot models.URLSnapshot) error {
+	db.snapshots = append(db.snapshots, snapshot)
+	return nil
+}
+
+const completePage = `<html><head><title>Saving pages</title><meta name="description" content="How pages are saved"></head>
+<body><article><h1>Saving pages</h1><p>A page is fetched, its text is extracted and indexed, and its summary is written.
+Every part of it is stored before the bookmark is shown as complete.</p></article></body></html>`
+
+func completeFetch() *FetchResult {
+	return &FetchResult{
+		URL:         "https://example.com/saving",
+		StatusCode:  http.StatusOK,
+		ContentType: "text/html; charset=utf-8",
+		Header:      http.Header{},
+		Body:        []byte(completePage),
+	}
+}
+
+func pendingStore() *models.URLStore {
+	return &models.URLStore{ID: "url_1", URL: "https://example.com/saving", Title: "https://example.com/saving", Status: constants.BookmarkStatusPending}
+}
+
+func TestCompleteURLStoreMarksCompleteLast(t *testing.T) {
+	db := &completeDB{}
+	s := &ServicesImplementation{db: db, policy: bluemonday.UGCPolicy()}
+
+	urlStore, err := s.completeURLStore(context.Background(), pendingStore(), completeFetch())
+	if err != nil {
+		t.Fatalf("completeURLStore: %v", err)
+	}
+	if urlStore.Status != constants.BookmarkStatusComplete || db.store.Status != constants.BookmarkStatusComplete {
+		t.Fatalf("status = %q, stored %q, want %q", urlStore.Status, db.store.Status, constants.BookmarkStatusComplete)
+	}
+	if last := db.writes[len(db.writes)-1]; last != "status" {
+		t.Fatalf("last write = %q, want status, writes %v", last, db.writes)
+	}
+}
+
+func TestCompleteURLStoreLeavesPendingWhenCutShort(t *testing.T) {
+	for _, step := range []string{"url_store", "structured_data", "reader_view", "language", "version", "crawl_state", "suggested_tags", "summary", "status"} {
+		for _, cancelled := range []bool{false, true} {
+			ctx, cancel := context.WithCancel(context.Background())
+			db := &completeDB{failAt: step}
+			if cancelled {
+				// the job is cancelled at shutdown while this part is written
+				db.cancel = cancel
+			}
+			s := &ServicesImplementation{db: db, policy: bluemonday.UGCPolicy()}
+
+			_, err := s.completeURLStore(ctx, pendingStore(), completeFetch())
+			cancel()
+			if err == nil {
+				t.Errorf("failing %s (cancelled %v): completeURLStore succeeded, the job would not be retried", step, cancelled)
+			}
+			if db.store.Status == constants.BookmarkStatusComplete {
+				t.Errorf("failing %s (cancelled %v): url store marked complete, writes %v", step, cancelled, db.writes)
+			}
+		}
+	}
+}
+
+const completeSnapshot = "From: <Saved by Blink>\r\n" +
+	"Subject: Saving pages\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/related; type=\"text/html\"; boundary=\"----MultipartBoundary\"\r\n" +
+	"\r\n" +
+	"------MultipartBoundary\r\n" +
+	"Content-Type: text/html\r\n" +
+	"Content-Location: https://example.com/saving\r\n" +
+	"\r\n" +
+	completePage + "\r\n" +
+	"------MultipartBoundary--\r\n"
+
+func TestCompleteURLStoreStoresOneSnapshotWhenRetried(t *testing.T) {
+	blobs, err := blobstore.NewLocalStore(t.TempDir(), "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &completeDB{failAt: "summary"}
+	s := &ServicesImplementation{db: db, blobs: blobs, policy: bluemonday.UGCPolicy()}
+	fetched := completeFetch()
+	fetched.Snapshot = []byte(completeSnapshot)
+
+	if _, err := s.completeURLStore(context.Background(), pendingStore(), fetched); err == nil {
+		t.Fatal("completeURLStore succeeded with a failing write")
+	}
+	if len(db.snapshots) != 0 {
+		t.Fatalf("%d snapshots stored by an attempt that failed, its retry stores another", len(db.snapshots))
+	}
+
+	db.failAt = ""
+	if _, err := s.completeURLStore(context.Background(), pendingStore(), fetched); err != nil {
+		t.Fatalf("retried completeURLStore: %v", err)
+	}
+	if len(db.snapshots) != 1 {
+		t.Fatalf("%d snapshots stored, want the one of the attempt that completed", len(db.snapshots))
+	}
+	body, err := blobs.Get(context.Background(), db.snapshots[0].BlobKey)
+	if err != nil {
+		t.Fatalf("snapshot blob: %v", err)
+	}
+	body.Close()
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	_url "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+)
+
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+const (
+	robotsCacheTTL      = 24 * time.Hour
+	robotsErrorCacheTTL = 1 * time.Hour
+	// sites asking for more than this between requests are clamped, jobs would stall otherwise
+	maxCrawlDelay     = 60 * time.Second
+	defaultRetryAfter = 60 * time.Second
+	maxRetryAfter     = 1 * time.Hour
+	// hosts not fetched from for this long are forgotten, with their robots.txt
+	hostIdleTTL = 1 * time.Hour
+	// idle hosts are looked for at most this often
+	hostSweepInterval = 10 * time.Minute
+)
+
+// ThrottledError is returned when a host answered 429 or 503
+type ThrottledError struct {
+	Host       string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s throttled us with status %d, retry after %s", e.Host, e.StatusCode, e.RetryAfter)
+}
+
+// RobotsUnavailableError is returned while the robots.txt of a host cannot be read for a passing reason,
+// a 429 or 5xx answer or a network error. The host is not crawled until its rules are known
+type RobotsUnavailableError struct {
+	Host       string
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RobotsUnavailableError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("robots.txt of %s could not be fetched, retry after %s: %v", e.Host, e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("robots.txt of %s answered with status %d, retry after %s", e.Host, e.StatusCode, e.RetryAfter)
+}
+
+func (e *RobotsUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+type deferralHookKey struct{}
+
+// WithDeferralHook attaches fn to ctx, it is called with a reason whenever a fetch has to wait for its host
+func WithDeferralHook(ctx context.Context, fn func(reason string)) context.Context {
+	return context.WithValue(ctx, deferralHookKey{}, fn)
+}
+
+func reportDeferral(ctx context.Context, reason string) {
+	if fn, ok := ctx.Value(deferralHookKey{}).(func(reason string)); ok && fn != nil {
+		fn(reason)
+	}
+}
+
+type hostState struct {
+	slots        chan struct{}
+	nextAllowed  time.Time
+	backoffUntil time.Time
+	lastUsed     time.Time
+
+	robotsMu        sync.Mutex
+	robots          *RobotsRules
+	robotsErr       *RobotsUnavailableError
+	robotsExpiresAt time.Time
+}
+
+// CrawlScheduler enforces per host politeness: a concurrency cap, a minimum delay between
+// requests, robots.txt rules and Crawl-delay, and back off after 429/503 answers
+type CrawlScheduler struct {
+	mu         sync.Mutex
+	hosts      map[string]*hostState
+	sweptAt    time.Time
+	MaxPerHost int
+	MinDelay   time.Duration
+	UserAgent  string
+	// AgentToken is the product token matched against robots.txt User-agent lines
+	AgentToken string
+	client     *http.Client
+}
+
+func NewCrawlScheduler(maxPerHost int, minDelay time.Duration) *CrawlScheduler {
+	if maxPerHost < 1 {
+		maxPerHost = 1
+	}
+	return &CrawlScheduler{
+		hosts:      make(map[string]*hostState),
+		MaxPerHost: maxPerHost,
+		MinDelay:   minDelay,
+		UserAgent:  constants.HeadlessUserAgent,
+		AgentToken: constants.CrawlerAgentToken,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (c *CrawlScheduler) host(name string) *hostState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.sweptAt) >= hostSweepInterval {
+		c.evictIdleHosts(now)
+	}
+	state, ok := c.hosts[name]
+	if !ok {
+		state = &hostState{
+			slots: make(chan struct{}, c.MaxPerHost),
+		}
+		c.hosts[name] = state
+	}
+	state.lastUsed = now
+	return state
+}
+
+// evictIdleHosts drops hosts nothing is in flight on, that are past their delays and were not used for hostIdleTTL.
+// c.mu is held by the caller
+func (c *CrawlScheduler) evictIdleHosts(now time.Time) {
+	c.sweptAt = now
+	for name, state := range c.hosts {
+		if len(state.slots) == 0 && now.Sub(state.lastUsed) >= hostIdleTTL && now.After(state.nextAllowed) && now.After(state.backoffUntil) {
+			delete(c.hosts, name)
+		}
+	}
+}
+
+// robotsFor returns the cached robots.txt rules of the url's origin, fetching them when stale.
+// A robots.txt that could not be read is not asked for again before its retry time
+func (c *CrawlScheduler) robotsFor(ctx context.Context, u *_url.URL, state *hostState) (*RobotsRules, error) {
+	state.robotsMu.Lock()
+	defer state.robotsMu.Unlock()
+
+	now := time.Now()
+	if now.Before(state.robotsExpiresAt) {
+		if state.robotsErr != nil {
+			unavailable := *state.robotsErr
+			unavailable.RetryAfter = state.robotsExpiresAt.Sub(now)
+			return nil, &unavailable
+		}
+		if state.robots != nil {
+			return state.robots, nil
+		}
+	}
+
+	robotsURL := u.Scheme + "://" + u.Host + "/robots.txt"
+	rules, ttl, err := c.fetchRobots(ctx, u.Host, robotsURL)
+	var unavailable *RobotsUnavailableError
+	if err != nil && !errors.As(err, &unavailable) {
+		return nil, err
+	}
+	state.robots, state.robotsErr = rules, unavailable
+	state.robotsExpiresAt = time.Now().Add(ttl)
+	return rules, err
+}
+
+// fetchRobots reads a robots.txt. A missing one allows everything, as does a host that can never be
+// reached: the page fetch fails the same way and says why. Throttling, server errors and network errors
+// that may pass are a RobotsUnavailableError, crawling on would ignore rules the host may have
+func (c *CrawlScheduler) fetchRobots(ctx context.Context, host, robotsURL string) (*RobotsRules, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return allowAllRobots, robotsErrorCacheTTL, nil
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+		if !ClassifyIngestError(err).Retryable {
+			return allowAllRobots, robotsErrorCacheTTL, nil
+		}
+		logger.LogError("Error fetching robots.txt", robotsURL, err)
+		return nil, defaultRetryAfter, &RobotsUnavailableError{Host: host, RetryAfter: defaultRetryAfter, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		return nil, retryAfter, &RobotsUnavailableError{Host: host, StatusCode: resp.StatusCode, RetryAfter: retryAfter}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return allowAllRobots, robotsErrorCacheTTL, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 512*1024))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+		return nil, defaultRetryAfter, &RobotsUnavailableError{Host: host, RetryAfter: defaultRetryAfter, Err: err}
+	}
+	return ParseRobots(string(body), c.AgentToken), robotsCacheTTL, nil
+}
+
+// Wait blocks until rawURL may be fetched and returns a release func that has to be called once done
+func (c *CrawlScheduler) Wait(ctx context.Context, rawURL string) (func(), error) {
+	u, err := _url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	hostName := strings.ToLower(u.Host)
+	state := c.host(hostName)
+
+	robots, err := c.robotsFor(ctx, u, state)
+	if err != nil {
+		return nil, err
+	}
+	if !robots.Allowed(u.RequestURI()) {
+		return nil, ErrDisallowedByRobots
+	}
+
+	select {
+	case state.slots <- struct{}{}:
+	default:
+		reportDeferral(ctx, fmt.Sprintf("waiting for a free slot on %s, %d requests in flight", hostName, c.MaxPerHost))
+		select {
+		case state.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() { <-state.slots }
+
+	delay := c.MinDelay
+	if robots.CrawlDelay > delay {
+		delay = min(robots.CrawlDelay, maxCrawlDelay)
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	startAt := now
+	reason := ""
+	if state.nextAllowed.After(startAt) {
+		startAt = state.nextAllowed
+		reason = fmt.Sprintf("crawl delay of %s for %s", delay, hostName)
+	}
+	if state.backoffUntil.After(startAt) {
+		startAt = state.backoffUntil
+		reason = fmt.Sprintf("%s asked us to back off until %s", hostName, state.backoffUntil.Format(time.RFC3339))
+	}
+	// reserve the slot in time before sleeping so concurrent callers queue up behind us
+	state.nextAllowed = startAt.Add(delay)
+	c.mu.Unlock()
+
+	if wait := startAt.Sub(now); wait > 0 {
+		if wait > time.Second {
+			reportDeferral(ctx, reason)
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+// Backoff stops all requests to host for d
+func (c *CrawlScheduler) Backoff(host string, d time.Duration) {
+	state := c.host(strings.ToLower(host))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until := time.Now().Add(d)
+	if until.After(state.backoffUntil) {
+		state.backoffUntil = until
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an http date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultRetryAfter
+	}
+	d := defaultRetryAfter
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		d = time.Until(at)
+	}
+	if d <= 0 {
+		return defaultRetryAfter
+	}
+	return min(d, maxRetryAfter)
+}
+
+// PoliteFetcher runs every fetch through the crawl scheduler
+type PoliteFetcher struct {
+	Scheduler *CrawlScheduler
+	Next      Fetcher
+}
+
+func (f *PoliteFetcher) Fetch(ctx context.Context, req FetchRequest) (*FetchResult, error) {
+	release, err := f.Scheduler.Wait(ctx, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result, err := f.Next.Fetch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.StatusCode == http.StatusTooManyRequests || result.StatusCode == http.StatusServiceUnavailable {
+		host := ""
+		if u, err := _url.Parse(req.URL); err == nil {
+			host = u.Host
+		}
+		retryAfter := parseRetryAfter(result.Header.Get("Retry-After"))
+		f.Scheduler.Backoff(host, retryAfter)
+		return nil, &ThrottledError{
+			Host:       host,
+			StatusCode: result.StatusCode,
+			RetryAfter: retryAfter,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	_url "net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rajnandan1/smaraka/constants"
+)
+
+const testRobots = `
+User-agent: *
+Disallow: /
+
+User-agent: Googlebot
+User-agent: Smaraka
+Disallow: /private
+Disallow: /*.json$
+Allow: /private/shared
+Crawl-delay: 2 # seconds
+`
+
+func TestParseRobots(t *testing.T) {
+	rules := ParseRobots(testRobots, constants.CrawlerAgentToken)
+	if rules.CrawlDelay != 2*time.Second {
+		t.Fatalf("crawl delay = %s, want 2s", rules.CrawlDelay)
+	}
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{path: "/", allowed: true},
+		{path: "/blog/post?id=1", allowed: true},
+		{path: "/private", allowed: false},
+		{path: "/private/notes", allowed: false},
+		{path: "/private/shared/notes", allowed: true},
+		{path: "/api/feed.json", allowed: false},
+		{path: "/api/feed.json?page=2", allowed: true},
+	}
+	for _, tt := range tests {
+		if allowed := rules.Allowed(tt.path); allowed != tt.allowed {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.path, allowed, tt.allowed)
+		}
+	}
+
+	// an agent without a group of its own gets the wildcard one
+	if ParseRobots(testRobots, "otherbot").Allowed("/blog") {
+		t.Error("wildcard group not applied to an unknown agent")
+	}
+	if !ParseRobots("User-agent: otherbot\nDisallow: /\n", constants.CrawlerAgentToken).Allowed("/blog") {
+		t.Error("rules of another agent applied to us")
+	}
+}
+
+// robotsServer serves robots.txt with the given status and body and counts how often it was asked for
+func robotsServer(t *testing.T, status int, robots string) (*httptest.Server, *atomic.Int32) {
+	fetches := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fetches.Add(1)
+			w.WriteHeader(status)
+			w.Write([]byte(robots))
+			return
+		}
+		w.Write([]byte("<html></html>"))
+	}))
+	t.Cleanup(server.Close)
+	return server, fetches
+}
+
+func hostOf(t *testing.T, rawURL string) string {
+	u, err := _url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
+
+func TestCrawlSchedulerFollowsRobots(t *testing.T) {
+	server, fetches := robotsServer(t, http.StatusOK, testRobots)
+	scheduler := NewCrawlScheduler(2, 0)
+	ctx := context.Background()
+
+	if _, err := scheduler.Wait(ctx, server.URL+"/private/notes"); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Fatalf("Wait on a disallowed path = %v, want ErrDisallowedByRobots", err)
+	}
+
+	start := time.Now()
+	release, err := scheduler.Wait(ctx, server.URL+"/private/shared/notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	state := scheduler.host(hostOf(t, server.URL))
+	// the next request to the host is pushed back by the crawl delay
+	if next := state.nextAllowed.Sub(start); next < 2*time.Second || next > 3*time.Second {
+		t.Fatalf("next request allowed in %s, want the 2s crawl delay", next)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("robots.txt fetched %d times, want once", n)
+	}
+}
+
+func TestCrawlSchedulerWithoutRobotsAllowsAll(t *testing.T) {
+	server, _ := robotsServer(t, http.StatusNotFound, "")
+	scheduler := NewCrawlScheduler(1, 0)
+
+	release, err := scheduler.Wait(context.Background(), server.URL+"/private/notes")
+	if err != nil {
+		t.Fatalf("Wait without a robots.txt: %v", err)
+	}
+	release()
+}
+
+func TestCrawlSchedulerWaitsForUnavailableRobots(t *testing.T) {
+	server, fetches := robotsServer(t, http.StatusServiceUnavailable, "")
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{name: "server error", url: server.URL, status: http.StatusServiceUnavailable},
+		{name: "connection refused", url: unreachable.URL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := NewCrawlScheduler(1, 0)
+			_, err := scheduler.Wait(context.Background(), tt.url+"/page")
+			var unavailable *RobotsUnavailableError
+			if !errors.As(err, &unavailable) || unavailable.StatusCode != tt.status || unavailable.RetryAfter != defaultRetryAfter {
+				t.Fatalf("Wait = %v, want the robots.txt to be waited for", err)
+			}
+			// the job snoozes instead of failing or crawling
+			if failure := ClassifyIngestError(err); failure.Class != FailureThrottled || !failure.Retryable || failure.RetryAfter != defaultRetryAfter {
+				t.Fatalf("classified as %+v, want a throttled failure retried after %s", failure, defaultRetryAfter)
+			}
+
+			// until the retry time the host is not asked again
+			_, err = scheduler.Wait(context.Background(), tt.url+"/other")
+			if !errors.As(err, &unavailable) || unavailable.RetryAfter > defaultRetryAfter {
+				t.Fatalf("second Wait = %v, want the cached robots.txt error", err)
+			}
+			state := scheduler.host(hostOf(t, tt.url))
+			state.robotsExpiresAt = time.Now().Add(-time.Second)
+			if _, err := scheduler.Wait(context.Background(), tt.url+"/page"); !errors.As(err, &unavailable) {
+				t.Fatalf("Wait after the retry time = %v, want robots.txt asked for again", err)
+			}
+		})
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("robots.txt fetched %d times, want once before and once after the retry time", n)
+	}
+}
+
+func TestCrawlSchedulerHostSlots(t *testing.T) {
+	server, _ := robotsServer(t, http.StatusNotFound, "")
+	scheduler := NewCrawlScheduler(1, 0)
+
+	release, err := scheduler.Wait(context.Background(), server.URL+"/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the only slot of the host is taken, the second fetch waits for it and says why
+	var reasons []string
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ctx = WithDeferralHook(ctx, func(reason string) { reasons = append(reasons, reason) })
+	if _, err := scheduler.Wait(ctx, server.URL+"/b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait on a busy host = %v, want DeadlineExceeded", err)
+	}
+	if len(reasons) != 1 || !strings.Contains(reasons[0], "free slot") {
+		t.Fatalf("deferrals %q, want one slot wait", reasons)
+	}
+
+	// another host is not held up
+	other, _ := robotsServer(t, http.StatusNotFound, "")
+	otherRelease, err := scheduler.Wait(context.Background(), other.URL+"/a")
+	if err != nil {
+		t.Fatalf("Wait on another host: %v", err)
+	}
+	otherRelease()
+
+	release()
+	release, err = scheduler.Wait(context.Background(), server.URL+"/b")
+	if err != nil {
+		t.Fatalf("Wait after the slot was released: %v", err)
+	}
+	release()
+}
+
+func TestCrawlSchedulerEvictsIdleHosts(t *testing.T) {
+	scheduler := NewCrawlScheduler(1, 0)
+	long := time.Now().Add(-2 * hostIdleTTL)
+
+	idle := scheduler.host("idle.example.com")
+	idle.lastUsed = long
+	busy := scheduler.host("busy.example.com")
+	busy.lastUsed = long
+	busy.slots <- struct{}{}
+	throttled := scheduler.host("throttled.example.com")
+	throttled.lastUsed = long
+	throttled.backoffUntil = time.Now().Add(time.Hour)
+	recent := scheduler.host("recent.example.com")
+
+	// sweeps are rate limited, the next one is only due after hostSweepInterval
+	scheduler.host("example.com")
+	if _, ok := scheduler.hosts["idle.example.com"]; !ok {
+		t.Fatal("idle host evicted before a sweep was due")
+	}
+
+	scheduler.sweptAt = time.Now().Add(-hostSweepInterval)
+	scheduler.host("example.com")
+	if _, ok := scheduler.hosts["idle.example.com"]; ok {
+		t.Fatal("idle host not evicted")
+	}
+	for name, state := range map[string]*hostState{"busy.example.com": busy, "throttled.example.com": throttled, "recent.example.com": recent} {
+		if scheduler.hosts[name] != state {
+			t.Errorf("%s evicted", name)
+		}
+	}
+}
+
+func TestPoliteFetcherBacksOffWhenThrottled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(server.Close)
+	scheduler := NewCrawlScheduler(1, 0)
+	fetcher := &PoliteFetcher{Scheduler: scheduler, Next: NewHTTPFetcher(testFetchTimeout, testBodyCap)}
+
+	_, err := fetcher.Fetch(context.Background(), FetchRequest{URL: server.URL + "/page"})
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.StatusCode != http.StatusTooManyRequests || throttled.RetryAfter != 120*time.Second {
+		t.Fatalf("Fetch = %v, want a 429 ThrottledError retrying after 120s", err)
+	}
+	if until := time.Until(scheduler.host(hostOf(t, server.URL)).backoffUntil); until < 110*time.Second {
+		t.Fatalf("host backed off for %s, want 120s", until)
+	}
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	_url "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/rajnandan1/smaraka/constants"
+)
+
+// FetchRequest describes a single page fetch.
+// ETag and LastModified make the request conditional, only the HTTP fetcher honors them,
+// as it does Method which defaults to GET. Snapshot and Screenshot ask the Chrome fetcher for an MHTML
+// capture and a viewport screenshot of the page
+type FetchRequest struct {
+	URL          string
+	Method       string
+	ETag         string
+	LastModified string
+	Snapshot     bool
+	Screenshot   bool
+}
+
+// FetchResult is the raw response of a page fetch
+type FetchResult struct {
+	URL         string
+	FinalURL    string
+	StatusCode  int
+	ContentType string
+	Header      http.Header
+	Body        []byte
+	// Snapshot is the MHTML capture of the rendered page, only set by the Chrome fetcher
+	Snapshot []byte
+	// Screenshot is a PNG of the viewport, only set by the Chrome fetcher
+	Screenshot []byte
+}
+
+// HTML returns the body of the result as a string
+func (r *FetchResult) HTML() string {
+	return string(r.Body)
+}
+
+// Fetcher fetches the content of a URL
+type Fetcher interface {
+	Fetch(ctx context.Context, req FetchRequest) (*FetchResult, error)
+}
+
+// HTTPFetcher fetches pages with a plain HTTP GET, no javascript is executed
+type HTTPFetcher struct {
+	Client    *http.Client
+	UserAgent string
+	// MaxBodyBytes bounds the body read into memory, larger responses fail with ErrContent
+	MaxBodyBytes int64
+}
+
+func NewHTTPFetcher(timeout time.Duration, maxBodyBytes int64) *HTTPFetcher {
+	return &HTTPFetcher{
+		Client: &http.Client{
+			Timeout: timeout,
+		},
+		UserAgent:    constants.HeadlessUserAgent,
+		MaxBodyBytes: maxBodyBytes,
+	}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, req FetchRequest) (*FetchResult, error) {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, req.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("User-Agent", f.UserAgent)
+	if req.ETag != "" {
+		httpReq.Header.Set("If-None-Match", req.ETag)
+	}
+	if req.LastModified != "" {
+		httpReq.Header.Set("If-Modified-Since", req.LastModified)
+	}
+
+	resp, err := f.Client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if f.MaxBodyBytes > 0 && resp.ContentLength > f.MaxBodyBytes {
+		return nil, fmt.Errorf("%w: response of %d bytes is larger than %d bytes", ErrContent, resp.ContentLength, f.MaxBodyBytes)
+	}
+	reader := io.Reader(resp.Body)
+	if f.MaxBodyBytes > 0 {
+		// one byte more tells a body of exactly the limit from a larger one
+		reader = io.LimitReader(resp.Body, f.MaxBodyBytes+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if f.MaxBodyBytes > 0 && int64(len(body)) > f.MaxBodyBytes {
+		return nil, fmt.Errorf("%w: response is larger than %d bytes", ErrContent, f.MaxBodyBytes)
+	}
+
+	return &FetchResult{
+		URL:         req.URL,
+		FinalURL:    resp.Request.URL.String(),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Header:      resp.Header,
+		Body:        body,
+	}, nil
+}
+
+// ChromeFetcher renders pages on a tab of the shared browser pool and returns the resulting DOM
+type ChromeFetcher struct {
+	Pool        *BrowserPool
+	PageTimeout time.Duration
+}
+
+func NewChromeFetcher(pool *BrowserPool, pageTimeout time.Duration) *ChromeFetcher {
+	return &ChromeFetcher{
+		Pool:        pool,
+		PageTimeout: pageTimeout,
+	}
+}
+
+func (f *ChromeFetcher) Fetch(ctx context.Context, req FetchRequest) (*FetchResult, error) {
+	var result *FetchResult
+	err := f.Pool.Run(ctx, f.PageTimeout, func(tabCtx context.Context) error {
+		var runErr error
+		result, runErr = runChromeFetch(tabCtx, req)
+		return runErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// runChromeFetch navigates an existing tab to the url and reads back the DOM
+func runChromeFetch(tabCtx context.Context, req FetchRequest) (*FetchResult, error) {
+	result := &FetchResult{
+		URL:    req.URL,
+		Header: make(http.Header),
+	}
+
+	// the first document response is the main frame, record its status and headers
+	var mu sync.Mutex
+	chromedp.ListenTarget(tabCtx, func(ev interface{}) {
+		if e, ok := ev.(*network.EventResponseReceived); ok {
+			mu.Lock()
+			defer mu.Unlock()
+			// tabs are recycled, listeners of earlier pages stay registered
+			if tabCtx.Err() != nil {
+				return
+			}
+			if e.Type != network.ResourceTypeDocument || result.StatusCode != 0 {
+				return
+			}
+			result.StatusCode = int(e.Response.Status)
+			result.ContentType = e.Response.MimeType
+			result.FinalURL = e.Response.URL
+			for k, v := range e.Response.Headers {
+				result.Header.Set(k, fmt.Sprint(v))
+			}
+		}
+	})
+
+	var htmlText, snapshot string
+	var screenshot []byte
+	err := chromedp.Run(tabCtx,
+		network.Enable(),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			if !req.Screenshot {
+				return nil
+			}
+			return chromedp.EmulateViewport(screenshotWidth, screenshotHeight).Do(ctx)
+		}),
+		chromedp.Navigate(req.URL),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.Sleep(2*time.Second),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			node, err := dom.GetDocument().Do(ctx)
+			if err != nil {
+				return err
+			}
+			htmlText, err = dom.GetOuterHTML().WithNodeID(node.NodeID).Do(ctx)
+			return err
+		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			if !req.Snapshot {
+				return nil
+			}
+			var err error
+			snapshot, err = page.CaptureSnapshot().WithFormat(page.CaptureSnapshotFormatMhtml).Do(ctx)
+			return err
+		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			if !req.Screenshot {
+				return nil
+			}
+			return chromedp.CaptureScreenshot(&screenshot).Do(ctx)
+		}),
+	)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timeout while fetching page: %w", err)
+		}
+		return nil, fmt.Errorf("error fetching page: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if result.FinalURL == "" {
+		result.FinalURL = req.URL
+	}
+	result.Body = []byte(htmlText)
+	result.Snapshot = []byte(snapshot)
+	result.Screenshot = screenshot
+	return result, nil
+}
+
+// FallbackFetcher tries the primary fetcher and falls back to the secondary one on error
+type FallbackFetcher struct {
+	Primary  Fetcher
+	Fallback Fetcher
+}
+
+func (f *FallbackFetcher) Fetch(ctx context.Context, req FetchRequest) (*FetchResult, error) {
+	result, err := f.Primary.Fetch(ctx, req)
+	if err == nil {
+		return result, nil
+	}
+	if ctx.Err() != nil {
+		return nil, err
+	}
+	return f.Fallback.Fetch(ctx, req)
+}
+
+// FetchRule maps a domain pattern to a fetch strategy.
+// A pattern of "*.example.com" matches example.com and all of its subdomains
+type FetchRule struct {
+	Pattern  string
+	Strategy string
+}
+
+func (r FetchRule) Matches(host string) bool {
+	host = strings.ToLower(host)
+	pattern := strings.ToLower(r.Pattern)
+	if strings.HasPrefix(pattern, "*.") {
+		base := strings.TrimPrefix(pattern, "*.")
+		return host == base || strings.HasSuffix(host, "."+base)
+	}
+	return host == pattern
+}
+
+// ParseFetchRules parses rules of the form "github.com=http;*.medium.com=chrome"
+func ParseFetchRules(rules string) ([]FetchRule, error) {
+	parsed := make([]FetchRule, 0)
+	for _, rule := range strings.Split(rules, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid fetch rule %q", rule)
+		}
+		strategy := strings.ToUpper(strings.TrimSpace(parts[1]))
+		if !isValidFetchStrategy(strategy) {
+			return nil, fmt.Errorf("invalid fetch strategy %q in rule %q", parts[1], rule)
+		}
+		parsed = append(parsed, FetchRule{
+			Pattern:  strings.TrimSpace(parts[0]),
+			Strategy: strategy,
+		})
+	}
+	return parsed, nil
+}
+
+func isValidFetchStrategy(strategy string) bool {
+	switch strategy {
+	case constants.FetchStrategyHTTP, constants.FetchStrategyChrome, constants.FetchStrategyChromeFallback:
+		return true
+	}
+	return false
+}
+
+// RoutedFetcher picks a fetch strategy per domain, first matching rule wins
+type RoutedFetcher struct {
+	DefaultStrategy string
+	Rules           []FetchRule
+	Strategies      map[string]Fetcher
+	// Direct fetches over plain http without the crawl politeness, for images readers are waiting on
+	Direct Fetcher
+}
+
+// StrategyFor returns the strategy that will be used for the url
+func (f *RoutedFetcher) StrategyFor(rawURL string) string {
+	parsedURL, err := _url.Parse(rawURL)
+	if err != nil {
+		return f.DefaultStrategy
+	}
+	for _, rule := range f.Rules {
+		if rule.Matches(parsedURL.Hostname()) {
+			return rule.Strategy
+		}
+	}
+	return f.DefaultStrategy
+}
+
+// With returns the fetcher registered for a strategy
+func (f *RoutedFetcher) With(strategy string) Fetcher {
+	if fetcher, ok := f.Strategies[strategy]; ok {
+		return fetcher
+	}
+	return f.Strategies[f.DefaultStrategy]
+}
+
+type fetchStrategyKey struct{}
+
+// WithFetchStrategy makes every fetch of ctx use strategy instead of the one of the url's domain,
+// a url that failed over http can be tried again in chrome and the other way around
+func WithFetchStrategy(ctx context.Context, strategy string) context.Context {
+	return context.WithValue(ctx, fetchStrategyKey{}, strategy)
+}
+
+func fetchStrategyFrom(ctx context.Context) string {
+	strategy, _ := ctx.Value(fetchStrategyKey{}).(string)
+	return strategy
+}
+
+// Fetch uses the strategy of the url's domain unless ctx asks for another. Chrome shows pdfs in its viewer
+// instead of handing out the file, so they are always fetched over plain http
+func (f *RoutedFetcher) Fetch(ctx context.Context, req FetchRequest) (*FetchResult, error) {
+	strategy := f.StrategyFor(req.URL)
+	if forced := fetchStrategyFrom(ctx); forced != "" {
+		strategy = forced
+	}
+	if isPDFURL(req.URL) {
+		strategy = constants.FetchStrategyHTTP
+	}
+	result, err := f.With(strategy).Fetch(ctx, req)
+	if err != nil || strategy == constants.FetchStrategyHTTP || !isPDF(result) || bytes.HasPrefix(result.Body, []byte("%PDF-")) {
+		return result, err
+	}
+	return f.With(constants.FetchStrategyHTTP).Fetch(ctx, FetchRequest{URL: req.URL, Method: req.Method})
+}
+
+// ConfigureFetcher builds the routed fetcher used by all crawling paths
+func ConfigureFetcher(defaultStrategy, rules string, pool *BrowserPool, pageTimeout time.Duration, maxBodyBytes int64, scheduler *CrawlScheduler) (*RoutedFetcher, error) {
+	defaultStrategy = strings.ToUpper(defaultStrategy)
+	if !isValidFetchStrategy(defaultStrategy) {
+		return nil, fmt.Errorf("invalid fetch strategy %q", defaultStrategy)
+	}
+	fetchRules, err := ParseFetchRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	httpFetcher := NewHTTPFetcher(10*time.Second, maxBodyBytes)
+	chromeFetcher := NewChromeFetcher(pool, pageTimeout)
+
+	return &RoutedFetcher{
+		DefaultStrategy: defaultStrategy,
+		Rules:           fetchRules,
+		Strategies: map[string]Fetcher{
+			constants.FetchStrategyHTTP:   &PoliteFetcher{Scheduler: scheduler, Next: httpFetcher},
+			constants.FetchStrategyChrome: &PoliteFetcher{Scheduler: scheduler, Next: chromeFetcher},
+			constants.FetchStrategyChromeFallback: &PoliteFetcher{Scheduler: scheduler, Next: &FallbackFetcher{
+				Primary:  chromeFetcher,
+				Fallback: httpFetcher,
+			}},
+		},
+		Direct: httpFetcher,
+	}, nil
+}
+
+// fetchHTML does a plain http fetch, used for listing pages and light metadata,
+// unless ctx asks for another strategy
+func (s *ServicesImplementation) fetchHTML(ctx context.Context, url string) (string, error) {
+	result, err := s.fetchLight(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	return result.HTML(), nil
+}
+
+// fetchLight is the plain http fetch of fetchHTML
+func (s *ServicesImplementation) fetchLight(ctx context.Context, url string) (*FetchResult, error) {
+	strategy := constants.FetchStrategyHTTP
+	if forced := fetchStrategyFrom(ctx); forced != "" {
+		strategy = forced
+	}
+	return s.fetcher.With(strategy).Fetch(ctx, FetchRequest{URL: url})
+}
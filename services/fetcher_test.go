@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rajnandan1/smaraka/constants"
+)
+
+const (
+	testBodyCap      = 1 << 10
+	testFetchTimeout = 5 * time.Second
+)
+
+func bodyServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small":
+			w.Write([]byte("<html>small</html>"))
+		case "/exact":
+			w.Write([]byte(strings.Repeat("a", testBodyCap)))
+		case "/declared":
+			// the length is announced, the body is never read
+			w.Header().Set("Content-Length", "1048576")
+			w.WriteHeader(http.StatusOK)
+		case "/chunked":
+			for i := 0; i < 4; i++ {
+				w.Write([]byte(strings.Repeat("a", testBodyCap/2)))
+				w.(http.Flusher).Flush()
+			}
+		case "/conditional":
+			if r.Header.Get("If-None-Match") == `"v1"` && r.Header.Get("If-Modified-Since") != "" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Write([]byte("changed"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPFetcherBodyCap(t *testing.T) {
+	server := bodyServer(t)
+	fetcher := NewHTTPFetcher(testFetchTimeout, testBodyCap)
+
+	tests := []struct {
+		path     string
+		size     int
+		tooLarge bool
+	}{
+		{path: "/small", size: len("<html>small</html>")},
+		{path: "/exact", size: testBodyCap},
+		{path: "/declared", tooLarge: true},
+		{path: "/chunked", tooLarge: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result, err := fetcher.Fetch(context.Background(), FetchRequest{URL: server.URL + tt.path})
+			if tt.tooLarge {
+				if !errors.Is(err, ErrContent) {
+					t.Fatalf("Fetch = %v, want ErrContent", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Body) != tt.size || result.StatusCode != http.StatusOK {
+				t.Fatalf("status %d with %d bytes, want 200 with %d", result.StatusCode, len(result.Body), tt.size)
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherConditionalRequest(t *testing.T) {
+	server := bodyServer(t)
+	fetcher := NewHTTPFetcher(testFetchTimeout, testBodyCap)
+
+	result, err := fetcher.Fetch(context.Background(), FetchRequest{URL: server.URL + "/conditional", ETag: `"v1"`, LastModified: "Mon, 19 Oct 2026 10:00:00 GMT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.StatusCode != http.StatusNotModified {
+		t.Fatalf("status %d, want 304", result.StatusCode)
+	}
+}
+
+// scriptedFetcher answers with a fixed result or error and records that it was asked
+type scriptedFetcher struct {
+	name   string
+	result *FetchResult
+	err    error
+	calls  *[]string
+}
+
+func (f *scriptedFetcher) Fetch(ctx context.Context, req FetchRequest) (*FetchResult, error) {
+	*f.calls = append(*f.calls, f.name)
+	if f.err != nil {
+		return nil, f.err
+	}
+	result := *f.result
+	return &result, nil
+}
+
+func TestFallbackFetcher(t *testing.T) {
+	page := &FetchResult{StatusCode: http.StatusOK, ContentType: "text/html", Body: []byte("<html></html>")}
+	tests := []struct {
+		name       string
+		primaryErr error
+		cancelled  bool
+		calls      []string
+		fails      bool
+	}{
+		{name: "chrome renders the page", calls: []string{"chrome"}},
+		{name: "chrome fails", primaryErr: errors.New("error fetching page: net::ERR_ABORTED"), calls: []string{"chrome", "http"}},
+		{name: "chrome times out", primaryErr: context.DeadlineExceeded, calls: []string{"chrome", "http"}},
+		{name: "job cancelled", primaryErr: context.Canceled, cancelled: true, calls: []string{"chrome"}, fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			fetcher := &FallbackFetcher{
+				Primary:  &scriptedFetcher{name: "chrome", result: page, err: tt.primaryErr, calls: &calls},
+				Fallback: &scriptedFetcher{name: "http", result: page, calls: &calls},
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+
+			_, err := fetcher.Fetch(ctx, FetchRequest{URL: "https://example.com"})
+			if (err != nil) != tt.fails {
+				t.Fatalf("Fetch = %v, want failure %v", err, tt.fails)
+			}
+			if strings.Join(calls, ",") != strings.Join(tt.calls, ",") {
+				t.Fatalf("calls %v, want %v", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestRoutedFetcherStrategies(t *testing.T) {
+	page := &FetchResult{StatusCode: http.StatusOK, ContentType: "text/html", Body: []byte("<html></html>")}
+	pdf := &FetchResult{StatusCode: http.StatusOK, ContentType: PDFContentType, Body: []byte("%PDF-1.7")}
+	// chrome shows a pdf in its viewer, the dom it hands back is html
+	viewer := &FetchResult{StatusCode: http.StatusOK, ContentType: PDFContentType, Body: []byte("<html><embed type=\"application/pdf\"></html>")}
+
+	tests := []struct {
+		name   string
+		url    string
+		forced string
+		chrome *FetchResult
+		http   *FetchResult
+		calls  []string
+	}{
+		{name: "default", url: "https://example.com/post", chrome: page, http: page, calls: []string{"chrome"}},
+		{name: "rule", url: "https://api.github.com/repos", chrome: page, http: page, calls: []string{"http"}},
+		{name: "wildcard rule", url: "https://docs.github.com/en", chrome: page, http: page, calls: []string{"http"}},
+		{name: "forced", url: "https://api.github.com/repos", forced: constants.FetchStrategyChrome, chrome: page, http: page, calls: []string{"chrome"}},
+		{name: "pdf url", url: "https://example.com/paper.PDF", chrome: page, http: pdf, calls: []string{"http"}},
+		{name: "pdf in the viewer", url: "https://example.com/download?id=1", chrome: viewer, http: pdf, calls: []string{"chrome", "http"}},
+		{name: "pdf bytes from chrome", url: "https://example.com/download?id=2", chrome: pdf, http: pdf, calls: []string{"chrome"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			rules, err := ParseFetchRules("*.github.com=http")
+			if err != nil {
+				t.Fatal(err)
+			}
+			fetcher := &RoutedFetcher{
+				DefaultStrategy: constants.FetchStrategyChrome,
+				Rules:           rules,
+				Strategies: map[string]Fetcher{
+					constants.FetchStrategyChrome: &scriptedFetcher{name: "chrome", result: tt.chrome, calls: &calls},
+					constants.FetchStrategyHTTP:   &scriptedFetcher{name: "http", result: tt.http, calls: &calls},
+				},
+			}
+			ctx := context.Background()
+			if tt.forced != "" {
+				ctx = WithFetchStrategy(ctx, tt.forced)
+			}
+
+			if _, err := fetcher.Fetch(ctx, FetchRequest{URL: tt.url}); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(calls, ",") != strings.Join(tt.calls, ",") {
+				t.Fatalf("calls %v, want %v", calls, tt.calls)
+			}
+		})
+	}
+}
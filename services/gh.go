@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"strconv"
 	"strings"
 
@@ -10,9 +11,9 @@ import (
 	"github.com/rajnandan1/smaraka/utils"
 )
 
-func doGithub(githubURL string) (*[]models.GithubRepo, error) {
+func (s *ServicesImplementation) doGithub(ctx context.Context, githubURL string) (*[]models.GithubRepo, error) {
 	logger.LogInfo("Fetching Github repos for user", githubURL)
-	html, err := utils.FetchHTML(githubURL)
+	html, err := s.fetchHTML(ctx, githubURL)
 	if err != nil {
 		logger.LogError("Error fetching Github repos", err)
 		return nil, err
@@ -50,12 +51,12 @@ func doGithub(githubURL string) (*[]models.GithubRepo, error) {
 	return &results, nil
 }
 
-func (s *ServicesImplementation) ImportGithubStars(ghUrl string) (*[]models.GithubRepo, error) {
+func (s *ServicesImplementation) ImportGithubStars(ctx context.Context, ghUrl string) (*[]models.GithubRepo, error) {
 
 	results := make([]models.GithubRepo, 0)
 	for i := 1; i < 101; i++ {
 		githubURL := ghUrl + "?direction=desc&filter=all&page=" + strconv.Itoa(i) + "&sort=created"
-		if repos, err := doGithub(githubURL); err == nil && repos != nil {
+		if repos, err := s.doGithub(ctx, githubURL); err == nil && repos != nil {
 			results = append(results, *repos...)
 		} else {
 			break
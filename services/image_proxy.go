@@ -0,0 +1,310 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/utils"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// ImageProxyPath is where proxied images are served, followed by the url store id and the image kind
+	ImageProxyPath = "/image/"
+	ImageKindSmall = "small"
+	ImageKindLarge = "large"
+
+	maxProxiedImageBytes = 5 << 20
+	// origins that failed are not asked again for this long
+	imageFailureTTL = 1 * time.Hour
+	// at most this many failed image urls are remembered
+	maxImageFailures = 10000
+)
+
+var ErrImageNotFound = errors.New("image not found")
+
+// proxiedImageWidths are the widths images are scaled down to
+var proxiedImageWidths = map[string]int{
+	ImageKindSmall: 64,
+	ImageKindLarge: 800,
+}
+
+// proxiedImageTypes are the content types we accept from origins, mapped to the extension we store them with
+var proxiedImageTypes = map[string]string{
+	"image/png":                ".png",
+	"image/jpeg":               ".jpg",
+	"image/gif":                ".gif",
+	"image/webp":               ".webp",
+	"image/x-icon":             ".ico",
+	"image/vnd.microsoft.icon": ".ico",
+	"image/svg+xml":            ".svg",
+}
+
+// cachedImageFormats are the formats fetchImage stores, in the order the cache is looked up
+var cachedImageFormats = []struct{ contentType, ext string }{
+	{"image/png", ".png"},
+	{"image/jpeg", ".jpg"},
+	{"image/x-icon", ".ico"},
+	{"image/svg+xml", ".svg"},
+}
+
+var cssColorRe = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// imageFailures remembers image urls that could not be fetched
+var imageFailures = &failedImages{at: make(map[string]time.Time)}
+
+// failedImages is a bounded set of image urls with the time their fetch failed
+type failedImages struct {
+	mu sync.Mutex
+	at map[string]time.Time
+}
+
+// recent tells if fetching source failed within imageFailureTTL
+func (f *failedImages) recent(source string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	failedAt, ok := f.at[source]
+	if ok && time.Since(failedAt) >= imageFailureTTL {
+		delete(f.at, source)
+		return false
+	}
+	return ok
+}
+
+// add remembers a failure, when full expired failures are dropped first and then any
+func (f *failedImages) add(source string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.at) >= maxImageFailures {
+		for key, failedAt := range f.at {
+			if time.Since(failedAt) >= imageFailureTTL {
+				delete(f.at, key)
+			}
+		}
+		for key := range f.at {
+			if len(f.at) < maxImageFailures {
+				break
+			}
+			delete(f.at, key)
+		}
+	}
+	f.at[source] = time.Now()
+}
+
+func (f *failedImages) forget(source string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.at, source)
+}
+
+// ProxyImages points the image fields of responses at the image proxy, hot linked origins never see our readers
+func ProxyImages(responses ...*models.URLResponses) {
+	for _, resp := range responses {
+		if resp == nil || resp.URLID == "" {
+			continue
+		}
+		proxyImageFields(resp.URLID, &resp.ImageSmall, &resp.ImageLarge)
+	}
+}
+
+// ProxyStoreImages is ProxyImages for url stores returned as they are
+func ProxyStoreImages(urlStores ...*models.URLStore) {
+	for _, urlStore := range urlStores {
+		if urlStore == nil || urlStore.ID == "" {
+			continue
+		}
+		proxyImageFields(urlStore.ID, &urlStore.ImageSmall, &urlStore.ImageLarge)
+	}
+}
+
+func proxyImageFields(urlID string, imageSmall, imageLarge *string) {
+	// a missing favicon is generated by the proxy
+	*imageSmall = ImageProxyPath + urlID + "/" + ImageKindSmall
+	if *imageLarge != "" && !isThumbnailURL(*imageLarge) {
+		*imageLarge = ImageProxyPath + urlID + "/" + ImageKindLarge
+	}
+}
+
+func proxiedImageBlobKey(urlID, kind, source, ext string) string {
+	sum := sha1.Sum([]byte(source))
+	return fmt.Sprintf("images/%s/%s-%s%s", urlID, kind, hex.EncodeToString(sum[:8]), ext)
+}
+
+// OpenImage returns the cached copy of a url store's favicon or preview image, fetching it on first use.
+// A favicon that cannot be fetched is replaced by a generated one
+func (s *ServicesImplementation) OpenImage(ctx context.Context, urlID, kind, orgID string) (string, io.ReadCloser, error) {
+	if _, ok := proxiedImageWidths[kind]; !ok {
+		return "", nil, ErrImageNotFound
+	}
+	if _, err := s.db.GetURLOrganizationsByURLIDOrgID(ctx, urlID, orgID); err != nil {
+		return "", nil, ErrBookmarkNotFound
+	}
+	urlStore, err := s.db.GetURLStoreByID(ctx, urlID)
+	if err != nil {
+		return "", nil, ErrBookmarkNotFound
+	}
+
+	source := urlStore.ImageLarge
+	if kind == ImageKindSmall {
+		source = urlStore.ImageSmall
+	}
+
+	contentType, body, err := s.cachedImage(ctx, urlID, kind, source)
+	if err == nil {
+		return contentType, body, nil
+	}
+	if kind == ImageKindSmall {
+		return "image/svg+xml", io.NopCloser(strings.NewReader(fallbackFavicon(urlStore))), nil
+	}
+	return "", nil, err
+}
+
+func (s *ServicesImplementation) cachedImage(ctx context.Context, urlID, kind, source string) (string, io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return "", nil, ErrImageNotFound
+	}
+
+	// the key depends on the source, an origin changing its image gets a fresh copy
+	for _, format := range cachedImageFormats {
+		if body, err := s.blobs.Get(ctx, proxiedImageBlobKey(urlID, kind, source, format.ext)); err == nil {
+			return format.contentType, body, nil
+		}
+	}
+
+	if imageFailures.recent(source) {
+		return "", nil, ErrImageNotFound
+	}
+
+	contentType, data, err := s.fetchImage(ctx, source, proxiedImageWidths[kind])
+	if err != nil {
+		logger.LogError("Error proxying image", source, err)
+		imageFailures.add(source)
+		return "", nil, ErrImageNotFound
+	}
+	imageFailures.forget(source)
+
+	key := proxiedImageBlobKey(urlID, kind, source, proxiedImageTypes[contentType])
+	if err := s.blobs.Put(ctx, key, bytes.NewReader(data), contentType); err != nil {
+		logger.LogError("Error caching image", source, err)
+	}
+	return contentType, io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// fetchImage downloads an image, checks it really is one and scales it down to width.
+// Formats we cannot decode, like ico and svg, are kept as they are. A reader is waiting on it,
+// so it does not queue behind the crawl delay of the image's host
+func (s *ServicesImplementation) fetchImage(ctx context.Context, source string, width int) (string, []byte, error) {
+	result, err := s.fetcher.Direct.Fetch(ctx, FetchRequest{URL: source})
+	if err != nil {
+		return "", nil, err
+	}
+	if result.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("image answered with status %d", result.StatusCode)
+	}
+	if len(result.Body) == 0 || len(result.Body) > maxProxiedImageBytes {
+		return "", nil, fmt.Errorf("image of %d bytes is not allowed", len(result.Body))
+	}
+
+	contentType := strings.ToLower(strings.TrimSpace(strings.Split(result.ContentType, ";")[0]))
+	if _, ok := proxiedImageTypes[contentType]; !ok {
+		return "", nil, fmt.Errorf("content type %q is not an image we proxy", result.ContentType)
+	}
+	// never trust the header alone, svg is text and sniffs as such
+	sniffed := http.DetectContentType(result.Body)
+	if contentType != "image/svg+xml" && !strings.HasPrefix(sniffed, "image/") {
+		return "", nil, fmt.Errorf("body of %s sniffs as %s", contentType, sniffed)
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(result.Body))
+	if err != nil {
+		if contentType == "image/svg+xml" || proxiedImageTypes[contentType] == ".ico" {
+			return contentType, result.Body, nil
+		}
+		return "", nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	var buf bytes.Buffer
+	resized := resizeToWidth(src, width)
+	// keep transparency of logos, photos are fine as jpeg
+	if format == "png" || format == "gif" {
+		err = png.Encode(&buf, resized)
+		contentType = "image/png"
+	} else {
+		err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: thumbnailQuality})
+		contentType = "image/jpeg"
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode image: %v", err)
+	}
+	return contentType, buf.Bytes(), nil
+}
+
+// domainInitials returns up to two letters for a domain, "news.ycombinator.com" gives "N", "hacker-news.io" gives "HN"
+func domainInitials(domain string) string {
+	domain = strings.TrimPrefix(strings.ToLower(domain), "www.")
+	name := strings.Split(domain, ".")[0]
+	initials := ""
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' }) {
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials += string(unicode.ToUpper(r))
+				break
+			}
+		}
+		if len([]rune(initials)) == 2 {
+			break
+		}
+	}
+	if initials == "" {
+		return "?"
+	}
+	return initials
+}
+
+// readableOn picks black or white text for a hex background
+func readableOn(color string) string {
+	hexColor := strings.TrimPrefix(color, "#")
+	if len(hexColor) == 3 {
+		hexColor = string([]byte{hexColor[0], hexColor[0], hexColor[1], hexColor[1], hexColor[2], hexColor[2]})
+	}
+	var r, g, b int
+	fmt.Sscanf(hexColor, "%02x%02x%02x", &r, &g, &b)
+	if 299*r+587*g+114*b > 150000 {
+		return "#000000"
+	}
+	return "#FFFFFF"
+}
+
+// fallbackFavicon draws the domain initials on the accent color of the url store
+func fallbackFavicon(urlStore *models.URLStore) string {
+	domain := urlStore.Domain
+	if domain == "" {
+		domain, _ = utils.GetDomain(urlStore.URL)
+	}
+	color := urlStore.AccentColor
+	if !cssColorRe.MatchString(color) {
+		color = utils.MurmurHashToRange(domain)
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="64" height="64" viewBox="0 0 64 64">`+
+		`<rect width="64" height="64" rx="12" fill="%s"/>`+
+		`<text x="32" y="32" dy=".35em" text-anchor="middle" font-family="Helvetica, Arial, sans-serif" font-size="28" font-weight="bold" fill="%s">%s</text>`+
+		`</svg>`, color, readableOn(color), html.EscapeString(domainInitials(domain)))
+}
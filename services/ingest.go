@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rajnandan1/smaraka/blobstore"
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/utils"
+)
+
+// classes of ingest failures, they decide if a url is tried again and are shown with failed urls
+const (
+	FailureDNS       = "DNS"
+	FailureNetwork   = "NETWORK"
+	FailureTimeout   = "TIMEOUT"
+	FailureServer    = "SERVER_ERROR"
+	FailureNotFound  = "NOT_FOUND"
+	FailureClient    = "CLIENT_ERROR"
+	FailureThrottled = "THROTTLED"
+	FailureTLS       = "TLS"
+	FailureRobots    = "ROBOTS"
+	FailureContent   = "CONTENT"
+	FailureInternal  = "INTERNAL"
+)
+
+// HTTPStatusError is a page that answered with an error status
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s answered with status %d", e.URL, e.StatusCode)
+}
+
+// ErrContent is a page that was fetched but could not be read, like a broken or oversized pdf
+var ErrContent = errors.New("page content could not be extracted")
+
+// IngestFailure is why a url could not be saved and whether trying again may help
+type IngestFailure struct {
+	Class     string
+	Retryable bool
+	// RetryAfter is how long the host asked us to wait, zero leaves the wait to the backoff
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (f *IngestFailure) Error() string {
+	return fmt.Sprintf("%s: %v", f.Class, f.Err)
+}
+
+// FailureClassOf reads the failure class back from an error message river recorded for an ingest job
+func FailureClassOf(message string) string {
+	class, _, found := strings.Cut(message, ": ")
+	if !found {
+		return ""
+	}
+	switch class {
+	case FailureDNS, FailureNetwork, FailureTimeout, FailureServer, FailureNotFound,
+		FailureClient, FailureThrottled, FailureTLS, FailureRobots, FailureContent, FailureInternal:
+		return class
+	}
+	return ""
+}
+
+func (f *IngestFailure) Unwrap() error {
+	return f.Err
+}
+
+// chromeNetErrors maps the net errors chrome reports to failure classes
+var chromeNetErrors = map[string]string{
+	"ERR_NAME_NOT_RESOLVED":     FailureDNS,
+	"ERR_NAME_RESOLUTION":       FailureDNS,
+	"ERR_CONNECTION_REFUSED":    FailureNetwork,
+	"ERR_CONNECTION_RESET":      FailureNetwork,
+	"ERR_CONNECTION_CLOSED":     FailureNetwork,
+	"ERR_ADDRESS_UNREACHABLE":   FailureNetwork,
+	"ERR_INTERNET_DISCONNECTED": FailureNetwork,
+	"ERR_TIMED_OUT":             FailureTimeout,
+	"ERR_CONNECTION_TIMED_OUT":  FailureTimeout,
+	"ERR_CERT_":                 FailureTLS,
+	"ERR_SSL_":                  FailureTLS,
+	"ERR_TOO_MANY_REDIRECTS":    FailureClient,
+}
+
+// ClassifyIngestError tells what kind of failure err is. Missing hosts, missing pages, other client errors,
+// robots.txt and unreadable content will fail again, everything else is worth another try
+func ClassifyIngestError(err error) *IngestFailure {
+	var failure *IngestFailure
+	if errors.As(err, &failure) {
+		return failure
+	}
+	failure = &IngestFailure{Class: FailureInternal, Retryable: true, Err: err}
+
+	var dnsErr *net.DNSError
+	var statusErr *HTTPStatusError
+	var throttled *ThrottledError
+	var robotsUnavailable *RobotsUnavailableError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCertErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	switch {
+	case errors.Is(err, ErrDisallowedByRobots):
+		failure.Class, failure.Retryable = FailureRobots, false
+	case errors.As(err, &throttled):
+		failure.Class, failure.RetryAfter = FailureThrottled, throttled.RetryAfter
+	case errors.As(err, &robotsUnavailable):
+		// the page is fetched once the rules of its host are known, like a throttled host it is waited for
+		failure.Class, failure.RetryAfter = FailureThrottled, robotsUnavailable.RetryAfter
+	case errors.As(err, &statusErr):
+		switch {
+		case statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone:
+			failure.Class, failure.Retryable = FailureNotFound, false
+		case statusErr.StatusCode == http.StatusRequestTimeout:
+			failure.Class = FailureTimeout
+		case statusErr.StatusCode >= 500:
+			failure.Class = FailureServer
+		default:
+			failure.Class, failure.Retryable = FailureClient, false
+		}
+	case errors.As(err, &dnsErr):
+		// a name that does not exist will not exist in a minute either, a resolver timing out might answer
+		failure.Class, failure.Retryable = FailureDNS, !dnsErr.IsNotFound
+	case errors.As(err, &certErr), errors.As(err, &authorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &invalidCertErr), errors.As(err, &recordErr):
+		// a certificate does not fix itself between two attempts
+		failure.Class, failure.Retryable = FailureTLS, false
+	case errors.Is(err, ErrContent):
+		failure.Class, failure.Retryable = FailureContent, false
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		failure.Class = FailureTimeout
+	case errors.As(err, &netErr):
+		failure.Class = FailureNetwork
+	default:
+		for code, class := range chromeNetErrors {
+			if strings.Contains(err.Error(), code) {
+				failure.Class = class
+				// chrome does not tell a missing name from a resolver that timed out
+				failure.Retryable = class != FailureClient && class != FailureTLS
+				break
+			}
+		}
+	}
+	return failure
+}
+
+// IngestURL saves a url for an organization, the light fetch creates the url store, the full fetch completes it.
+// It is safe to run again after a failure, steps already done are skipped
+func (s *ServicesImplementation) IngestURL(ctx context.Context, url, orgId string) error {
+	// snapshots and thumbnails of the url count against the org's storage
+	ctx = blobstore.WithOrg(ctx, orgId)
+	s.PublishProgress(ctx, models.IngestProgress{OrgID: orgId, URL: url, Stage: constants.IngestStageFetching})
+
+	urlStore, err := s.db.GetURLStoreByURL(ctx, url)
+	if err != nil {
+		lightStore, err := s.GetContentEasy(ctx, url)
+		if err != nil {
+			return err
+		}
+		lightStore.ID = s.db.NewID("url")
+		if _, err := s.db.InsertNewURLStore(ctx, *lightStore); err != nil {
+			return &IngestFailure{Class: FailureInternal, Retryable: true, Err: err}
+		}
+		if urlStore, err = s.db.GetURLStoreByURL(ctx, url); err != nil {
+			return &IngestFailure{Class: FailureInternal, Retryable: true, Err: err}
+		}
+	}
+
+	urlOrg := models.URLOrganizations{
+		ID:             s.db.NewID("url_org"),
+		URLID:          urlStore.ID,
+		OrganizationID: orgId,
+		Status:         constants.URLStatusActive,
+	}
+	// the organization may have saved the url before, or an earlier attempt got this far
+	if _, err := s.db.InsertNewURLOrganization(ctx, urlOrg); err != nil && !strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return &IngestFailure{Class: FailureInternal, Retryable: true, Err: err}
+	}
+	// the bookmark is listed with its title and excerpt from here on, searching its text waits for the full fetch
+	s.PublishProgress(ctx, models.IngestProgress{OrgID: orgId, URL: url, Stage: constants.IngestStageExtracted})
+
+	if urlStore.Status == constants.BookmarkStatusPending || refetchFrom(ctx) {
+		logger.LogInfo("Fetching inner HTML", urlStore.URL)
+		fetched, err := s.fetchWithRetry(ctx, FetchRequest{URL: url, Snapshot: true, Screenshot: true})
+		if err != nil {
+			return err
+		}
+		if fetched.StatusCode >= 400 {
+			return &HTTPStatusError{URL: url, StatusCode: fetched.StatusCode}
+		}
+		if _, err := s.completeURLStore(ctx, urlStore, fetched); err != nil {
+			return err
+		}
+	}
+	s.PublishProgress(ctx, models.IngestProgress{OrgID: orgId, URL: url, Stage: constants.IngestStageIndexed})
+	return nil
+}
+
+type refetchKey struct{}
+
+// WithRefetch makes IngestURL fetch a url store that is complete already again, a reindex asks for it.
+// The url store stays complete meanwhile, a fetch that fails leaves it as it was
+func WithRefetch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refetchKey{}, true)
+}
+
+func refetchFrom(ctx context.Context) bool {
+	refetch, _ := ctx.Value(refetchKey{}).(bool)
+	return refetch
+}
+
+// KeepWithoutContent saves a url that could not be fetched for an organization with what is known without fetching it,
+// the url itself as title. A page fetched before keeps its title and excerpt, a later recrawl may still fill in the content
+func (s *ServicesImplementation) KeepWithoutContent(ctx context.Context, url, orgId string) error {
+	urlStore, err := s.db.GetURLStoreByURL(ctx, url)
+	if err != nil {
+		if _, err := s.db.InsertNewURLStore(ctx, s.bareURLStore(url, constants.BookmarkStatusComplete)); err != nil {
+			return err
+		}
+		if urlStore, err = s.db.GetURLStoreByURL(ctx, url); err != nil {
+			return err
+		}
+	} else if urlStore.Status == constants.BookmarkStatusPending {
+		urlStore.Status = constants.BookmarkStatusComplete
+		urlStore.UpdatedAt = time.Now()
+		if _, err := s.db.UpdateURLStoreByID(ctx, urlStore.ID, *urlStore); err != nil {
+			return err
+		}
+	}
+
+	urlOrg := models.URLOrganizations{
+		ID:             s.db.NewID("url_org"),
+		URLID:          urlStore.ID,
+		OrganizationID: orgId,
+		Status:         constants.URLStatusActive,
+	}
+	if _, err := s.db.InsertNewURLOrganization(ctx, urlOrg); err != nil && !strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return err
+	}
+	return nil
+}
+
+// GetOrCreatePendingURLStore returns the url store of a url, a url never seen before is stored pending
+// with the url as title until its ingest job fetches it
+func (s *ServicesImplementation) GetOrCreatePendingURLStore(ctx context.Context, url string) (*models.URLStore, error) {
+	if urlStore, err := s.db.GetURLStoreByURL(ctx, url); err == nil {
+		return urlStore, nil
+	}
+	// a concurrent save of the url may have stored it first, it is read back either way
+	if _, err := s.db.InsertNewURLStore(ctx, s.bareURLStore(url, constants.BookmarkStatusPending)); err != nil {
+		logger.LogError("Error inserting url store", url, err)
+	}
+	return s.db.GetURLStoreByURL(ctx, url)
+}
+
+// bareURLStore is what is known of a url without fetching it
+func (s *ServicesImplementation) bareURLStore(url, status string) models.URLStore {
+	bookmark := models.URLStore{
+		ID:          s.db.NewID("url"),
+		URL:         url,
+		Title:       url,
+		AccentColor: utils.MurmurHashToRange(url),
+		Status:      status,
+	}
+	if domain, err := utils.GetDomain(url); err == nil {
+		bookmark.Domain = domain
+	}
+	return bookmark
+}
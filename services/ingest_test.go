@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// ingestDB is completeDB with the url store saved by an organization already
+type ingestDB struct {
+	completeDB
+}
+
+func (db *ingestDB) GetURLStoreByURL(ctx context.Context, url string) (*models.URLStore, error) {
+	stored := db.store
+	return &stored, nil
+}
+
+func (db *ingestDB) InsertNewURLOrganization(ctx context.Context, urlOrg models.URLOrganizations) (*models.URLOrganizations, error) {
+	return nil, errors.New(`duplicate key value violates unique constraint "url_organizations_url_id_organization_id_key"`)
+}
+
+func (db *ingestDB) NotifyIngestProgress(ctx context.Context, payload string) error { return nil }
+
+// staticFetcher answers every fetch with the same result
+type staticFetcher struct {
+	result *FetchResult
+}
+
+func (f *staticFetcher) Fetch(ctx context.Context, req FetchRequest) (*FetchResult, error) {
+	result := *f.result
+	return &result, nil
+}
+
+func ingestService(db *ingestDB, fetched *FetchResult) *ServicesImplementation {
+	return &ServicesImplementation{
+		db:     db,
+		policy: bluemonday.UGCPolicy(),
+		fetcher: &RoutedFetcher{
+			DefaultStrategy: constants.FetchStrategyHTTP,
+			Strategies:      map[string]Fetcher{constants.FetchStrategyHTTP: &staticFetcher{result: fetched}},
+		},
+	}
+}
+
+func TestIngestURLReindexOfMissingPageKeepsURLStoreComplete(t *testing.T) {
+	complete := *pendingStore()
+	complete.Status = constants.BookmarkStatusComplete
+	db := &ingestDB{completeDB{store: complete}}
+	gone := completeFetch()
+	gone.StatusCode = http.StatusNotFound
+	s := ingestService(db, gone)
+
+	err := s.IngestURL(WithRefetch(context.Background()), complete.URL, "org_1")
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("IngestURL = %v, want the 404", err)
+	}
+	if failure := ClassifyIngestError(err); failure.Retryable {
+		t.Fatalf("failure %v is retryable, the reindex would be tried again", failure)
+	}
+	if db.store.Status != constants.BookmarkStatusComplete || len(db.writes) != 0 {
+		t.Fatalf("status = %q, writes %v, want the url store untouched", db.store.Status, db.writes)
+	}
+}
+
+func TestIngestURLReindexRefetchesCompleteURLStore(t *testing.T) {
+	complete := *pendingStore()
+	complete.Status = constants.BookmarkStatusComplete
+	db := &ingestDB{completeDB{store: complete}}
+	s := ingestService(db, completeFetch())
+
+	if err := s.IngestURL(context.Background(), complete.URL, "org_1"); err != nil {
+		t.Fatalf("IngestURL: %v", err)
+	}
+	if len(db.writes) != 0 {
+		t.Fatalf("a complete url store was fetched again without a reindex, writes %v", db.writes)
+	}
+
+	if err := s.IngestURL(WithRefetch(context.Background()), complete.URL, "org_1"); err != nil {
+		t.Fatalf("IngestURL reindex: %v", err)
+	}
+	if db.store.Title != "Saving pages" || db.store.Status != constants.BookmarkStatusComplete {
+		t.Fatalf("url store = %+v, want it refetched and complete", db.store)
+	}
+}
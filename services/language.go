@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+
+	"github.com/rajnandan1/smaraka/lang"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// pageLanguage detects the language of a page from its title and text
+func pageLanguage(title, text string) string {
+	return lang.Detect(title + "\n" + text)
+}
+
+// indexLanguage stores the language of a url store, which decides how its text is tokenized for search
+func (s *ServicesImplementation) indexLanguage(ctx context.Context, urlStore *models.URLStore) error {
+	urlStore.Language = pageLanguage(urlStore.Title, urlStore.FullText)
+	return s.db.UpdateURLStoreLanguage(ctx, urlStore.ID, urlStore.Language)
+}
+
+// DetectLanguages detects the language of url stores saved before languages were detected
+func (s *ServicesImplementation) DetectLanguages(ctx context.Context, limit int) error {
+	urlStores, err := s.db.GetURLStoresWithoutLanguage(ctx, limit)
+	if err != nil {
+		return err
+	}
+
+	logger.LogInfo("Detecting language of url stores", len(urlStores))
+	for i := range urlStores {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.indexLanguage(ctx, &urlStores[i]); err != nil {
+			logger.LogError("Error storing language", urlStores[i].URL, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	_url "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+// number of links checked in parallel, the crawl scheduler still limits each host
+const linkCheckWorkers = 8
+
+// LinkHealth is the outcome of checking a single link
+type LinkHealth struct {
+	State      string
+	StatusCode int
+	FinalURL   string
+}
+
+// CheckLinks checks the links of url stores not checked for maxAge and records their health
+func (s *ServicesImplementation) CheckLinks(ctx context.Context, maxAge time.Duration, limit int) error {
+	urlStores, err := s.db.GetURLStoresDueForLinkCheck(ctx, time.Now().Add(-maxAge), limit)
+	if err != nil {
+		return err
+	}
+	logger.LogInfo("Checking links, count: ", len(urlStores))
+
+	jobs := make(chan *models.URLStore)
+	var wg sync.WaitGroup
+	for i := 0; i < linkCheckWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for urlStore := range jobs {
+				s.recordLinkHealth(ctx, urlStore)
+			}
+		}()
+	}
+	for i := range urlStores {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- &urlStores[i]
+	}
+	close(jobs)
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func (s *ServicesImplementation) recordLinkHealth(ctx context.Context, urlStore *models.URLStore) {
+	health, err := s.checkLink(ctx, urlStore.URL)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		// we were not allowed to look, keep what we knew so the link goes to the back of the queue
+		logger.LogError("Skipping link check", urlStore.URL, err)
+		health = &LinkHealth{
+			State:      urlStore.LinkState,
+			StatusCode: urlStore.LinkStatusCode,
+			FinalURL:   urlStore.LinkFinalURL,
+		}
+	}
+	if err := s.db.UpdateURLStoreLinkHealth(ctx, urlStore.ID, health.State, health.StatusCode, health.FinalURL); err != nil {
+		logger.LogError("Error recording link health", urlStore.URL, err)
+	}
+}
+
+// checkLink does a HEAD request and confirms failures with a GET, plenty of servers mishandle HEAD.
+// An error means the link could not be checked at all, not that it is broken
+func (s *ServicesImplementation) checkLink(ctx context.Context, rawURL string) (*LinkHealth, error) {
+	fetcher := s.fetcher.With(constants.FetchStrategyHTTP)
+
+	result, err := fetcher.Fetch(ctx, FetchRequest{URL: rawURL, Method: http.MethodHead})
+	if err == nil && result.StatusCode < 400 {
+		return classifyLink(rawURL, result.FinalURL, result.StatusCode), nil
+	}
+	if err != nil && isUncheckable(ctx, err) {
+		return nil, err
+	}
+
+	result, err = fetcher.Fetch(ctx, FetchRequest{URL: rawURL})
+	if err != nil {
+		if isUncheckable(ctx, err) {
+			return nil, err
+		}
+		return &LinkHealth{State: constants.LinkStateUnreachable}, nil
+	}
+	return classifyLink(rawURL, result.FinalURL, result.StatusCode), nil
+}
+
+// isUncheckable reports errors that say nothing about the link itself, a body too large to read was still served.
+// A host whose robots.txt answered with an error is up, one that could not be reached for it is not
+func isUncheckable(ctx context.Context, err error) bool {
+	var throttled *ThrottledError
+	var robotsUnavailable *RobotsUnavailableError
+	return ctx.Err() != nil || errors.Is(err, ErrDisallowedByRobots) || errors.As(err, &throttled) || errors.Is(err, ErrContent) ||
+		errors.As(err, &robotsUnavailable) && robotsUnavailable.StatusCode != 0
+}
+
+// classifyLink turns the answer for a link into a link state
+func classifyLink(rawURL, finalURL string, statusCode int) *LinkHealth {
+	health := &LinkHealth{
+		State:      constants.LinkStateOK,
+		StatusCode: statusCode,
+		FinalURL:   finalURL,
+	}
+
+	switch {
+	case statusCode == http.StatusNotFound || statusCode == http.StatusGone:
+		health.State = constants.LinkStateBroken
+		return health
+	case statusCode >= 400:
+		health.State = constants.LinkStateUnreachable
+		return health
+	}
+
+	original, err := _url.Parse(rawURL)
+	if err != nil {
+		return health
+	}
+	final, err := _url.Parse(finalURL)
+	if err != nil || finalURL == "" {
+		return health
+	}
+
+	if !sameSite(original.Hostname(), final.Hostname()) {
+		health.State = constants.LinkStateDomainChanged
+	} else if isHomePath(final.Path) && !isHomePath(original.Path) {
+		health.State = constants.LinkStateRedirectHome
+	}
+	return health
+}
+
+// sameSite treats www and other subdomains of the same host as the same site
+func sameSite(a, b string) bool {
+	a = strings.TrimPrefix(strings.ToLower(a), "www.")
+	b = strings.TrimPrefix(strings.ToLower(b), "www.")
+	return a == b || strings.HasSuffix(a, "."+b) || strings.HasSuffix(b, "."+a)
+}
+
+func isHomePath(path string) bool {
+	return path == "" || path == "/"
+}
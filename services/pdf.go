@@ -0,0 +1,218 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	_url "net/url"
+	"path"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+const (
+	PDFContentType = "application/pdf"
+
+	// pdfs bigger than this are saved as bookmarks without their text, the http fetch is
+	// capped by SMARAKA_MAX_FETCH_MB so a larger pdf never reaches memory in full
+	maxPDFBytes = 50 << 20
+	// text of later pages is not extracted, it would only bloat the index
+	maxPDFPages      = 500
+	pdfExcerptLength = 300
+)
+
+var ErrDocumentNotFound = errors.New("document not found")
+
+// pdfDocument is the text and metadata of a pdf
+type pdfDocument struct {
+	Title  string
+	Author string
+	Pages  []string
+}
+
+// Text joins the pages, separated by blank lines
+func (d *pdfDocument) Text() string {
+	pages := make([]string, 0, len(d.Pages))
+	for _, page := range d.Pages {
+		if page != "" {
+			pages = append(pages, page)
+		}
+	}
+	return strings.Join(pages, "\n\n")
+}
+
+// isPDFURL guesses from the path whether a url points at a pdf
+func isPDFURL(rawURL string) bool {
+	parsedURL, err := _url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(path.Ext(parsedURL.Path), ".pdf")
+}
+
+// isPDF tells if a fetch returned a pdf, either by its content type or by its magic bytes
+func isPDF(fetched *FetchResult) bool {
+	if fetched == nil {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(fetched.ContentType)
+	return mediaType == PDFContentType || bytes.HasPrefix(fetched.Body, []byte("%PDF-"))
+}
+
+// extractPDF reads the metadata and the text of every page of a pdf
+func extractPDF(data []byte) (doc *pdfDocument, err error) {
+	// the pdf reader panics on malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("malformed pdf: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pdf: %v", err)
+	}
+
+	info := reader.Trailer().Key("Info")
+	doc = &pdfDocument{
+		Title:  strings.TrimSpace(info.Key("Title").Text()),
+		Author: strings.TrimSpace(info.Key("Author").Text()),
+		Pages:  make([]string, 0),
+	}
+
+	// fonts are shared between pages, parsing their character maps once saves most of the work
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= min(reader.NumPage(), maxPDFPages); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			logger.LogError("Error extracting text of pdf page", i, err)
+		}
+		doc.Pages = append(doc.Pages, strings.Join(strings.Fields(text), " "))
+	}
+	return doc, nil
+}
+
+// pdfTitle picks the metadata title unless it is a leftover of the authoring tool, then the file name
+func pdfTitle(doc *pdfDocument, rawURL string) string {
+	title := doc.Title
+	lower := strings.ToLower(title)
+	if title != "" && !strings.HasPrefix(lower, "microsoft word - ") && !strings.HasSuffix(lower, ".dvi") && lower != "untitled" {
+		return title
+	}
+	if parsedURL, err := _url.Parse(rawURL); err == nil {
+		if name := strings.TrimSuffix(path.Base(parsedURL.Path), path.Ext(parsedURL.Path)); name != "" && name != "." && name != "/" {
+			return name
+		}
+	}
+	return title
+}
+
+func documentBlobKey(urlID string) string {
+	return fmt.Sprintf("documents/%s/original.pdf", urlID)
+}
+
+// completePDF indexes the text of a fetched pdf and keeps the original file
+func (s *ServicesImplementation) completePDF(ctx context.Context, urlStore *models.URLStore, fetched *FetchResult) (*models.URLStore, error) {
+	if len(fetched.Body) > maxPDFBytes {
+		return nil, fmt.Errorf("%w: pdf of %d bytes is too large", ErrContent, len(fetched.Body))
+	}
+	doc, err := extractPDF(fetched.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrContent, err)
+	}
+
+	text := doc.Text()
+	if title := pdfTitle(doc, urlStore.URL); title != "" {
+		urlStore.Title = title
+	}
+	if excerpt := []rune(text); len(excerpt) > pdfExcerptLength {
+		urlStore.Excerpt = strings.TrimSpace(string(excerpt[:pdfExcerptLength])) + "…"
+	} else {
+		urlStore.Excerpt = text
+	}
+	urlStore.FullText = text
+	// a pdf has no reader view, whatever an earlier html fetch left is dropped
+	urlStore.ArticleHTML = ""
+	urlStore.WordCount = len(strings.Fields(text))
+
+	updatedUrlStore, err := s.db.UpdateURLStoreByID(ctx, urlStore.ID, *urlStore)
+	if err != nil {
+		logger.LogError("Error updating bookmark", err)
+		return nil, err
+	}
+	if err := s.db.UpdateURLStoreReaderView(ctx, urlStore.ID, urlStore.ArticleHTML, urlStore.WordCount); err != nil {
+		return nil, fmt.Errorf("failed to store reader view: %w", err)
+	}
+
+	documentKey := documentBlobKey(urlStore.ID)
+	if err := s.blobs.Put(ctx, documentKey, bytes.NewReader(fetched.Body), PDFContentType); err != nil {
+		logger.LogError("Error storing pdf", urlStore.URL, err)
+		documentKey = ""
+	}
+	if err := s.db.UpdateURLStoreDocument(ctx, urlStore.ID, doc.Author, PDFContentType, documentKey, len(doc.Pages)); err != nil {
+		return nil, fmt.Errorf("failed to store document: %w", err)
+	}
+	if err := s.indexLanguage(ctx, urlStore); err != nil {
+		return nil, fmt.Errorf("failed to store language: %w", err)
+	}
+	updatedUrlStore.Language = urlStore.Language
+	if err := s.suggestTags(ctx, updatedUrlStore, ""); err != nil {
+		return nil, fmt.Errorf("failed to store suggested tags: %w", err)
+	}
+	if err := s.summarize(ctx, updatedUrlStore); err != nil {
+		return nil, fmt.Errorf("failed to store summary: %w", err)
+	}
+	if err := s.recordCrawl(ctx, updatedUrlStore, fetched); err != nil {
+		return nil, fmt.Errorf("failed to record crawl: %w", err)
+	}
+
+	logger.LogInfo("Extracted pdf", urlStore.URL, len(doc.Pages))
+	return s.markComplete(ctx, updatedUrlStore)
+}
+
+// fetchedText returns the plain text of a fetch, pdfs are read page by page, anything else as html
+func (s *ServicesImplementation) fetchedText(fetched *FetchResult) (string, error) {
+	if !isPDF(fetched) {
+		return s.extractText(fetched.HTML()), nil
+	}
+	if len(fetched.Body) > maxPDFBytes {
+		return "", fmt.Errorf("pdf of %d bytes is too large", len(fetched.Body))
+	}
+	doc, err := extractPDF(fetched.Body)
+	if err != nil {
+		return "", err
+	}
+	return doc.Text(), nil
+}
+
+// OpenDocument returns the original file of a bookmark that is a document, the caller closes the reader
+func (s *ServicesImplementation) OpenDocument(ctx context.Context, urlOrgID, orgID string) (*models.URLStore, io.ReadCloser, error) {
+	urlStore, err := s.db.GetURLStoreByURLOrgIDOrgID(ctx, urlOrgID, orgID)
+	if err != nil {
+		return nil, nil, ErrBookmarkNotFound
+	}
+	if urlStore.DocumentKey == "" {
+		return nil, nil, ErrDocumentNotFound
+	}
+	body, err := s.blobs.Get(ctx, urlStore.DocumentKey)
+	if err != nil {
+		return nil, nil, ErrDocumentNotFound
+	}
+	return urlStore, body, nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/postgres"
+)
+
+const (
+	// maxProgressError keeps events well below the 8000 bytes a notification payload may have
+	maxProgressError = 1000
+	// progressBuffer is how many events a slow subscriber may fall behind before events are dropped for it
+	progressBuffer = 64
+	// progressReconnectDelay is the wait before listening again after the listen connection failed
+	progressReconnectDelay = 5 * time.Second
+)
+
+// PublishProgress tells every instance that a url reached an ingest stage, progress is best effort
+// and never fails the ingest itself
+func (s *ServicesImplementation) PublishProgress(ctx context.Context, event models.IngestProgress) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	if len(event.Error) > maxProgressError {
+		event.Error = event.Error[:maxProgressError]
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.LogError("Error encoding ingest progress", event.URL, err)
+		return
+	}
+	if err := s.db.NotifyIngestProgress(ctx, string(payload)); err != nil {
+		logger.LogError("Error publishing ingest progress", event.URL, err)
+	}
+}
+
+// ProgressHub listens for ingest progress events of all instances and hands them to the subscribers
+// of their organization
+type ProgressHub struct {
+	db          postgres.Postgres
+	mu          sync.Mutex
+	subscribers map[string]map[chan models.IngestProgress]struct{}
+	done        chan struct{}
+}
+
+func NewProgressHub(db postgres.Postgres) *ProgressHub {
+	return &ProgressHub{
+		db:          db,
+		subscribers: make(map[string]map[chan models.IngestProgress]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Run listens until ctx is done, listening again whenever the connection is lost
+func (h *ProgressHub) Run(ctx context.Context) {
+	defer close(h.done)
+	for ctx.Err() == nil {
+		err := h.db.ListenIngestProgress(ctx, h.dispatch)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.LogError("Ingest progress listener stopped", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(progressReconnectDelay):
+		}
+	}
+}
+
+// Done is closed once Run returned, subscribers stop reading then so the server can shut down
+func (h *ProgressHub) Done() <-chan struct{} {
+	return h.done
+}
+
+// Subscribe returns the progress events of an organization, unsubscribe must be called once they are no longer read
+func (h *ProgressHub) Subscribe(orgID string) (events <-chan models.IngestProgress, unsubscribe func()) {
+	ch := make(chan models.IngestProgress, progressBuffer)
+	h.mu.Lock()
+	if h.subscribers[orgID] == nil {
+		h.subscribers[orgID] = make(map[chan models.IngestProgress]struct{})
+	}
+	h.subscribers[orgID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[orgID], ch)
+		if len(h.subscribers[orgID]) == 0 {
+			delete(h.subscribers, orgID)
+		}
+	}
+}
+
+func (h *ProgressHub) dispatch(payload string) {
+	var event models.IngestProgress
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		logger.LogError("Error decoding ingest progress", err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[event.OrgID] {
+		select {
+		case ch <- event:
+		default:
+			// the subscriber is not keeping up, it can catch up from the job counts
+		}
+	}
+}
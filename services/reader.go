@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"html"
+	_url "net/url"
+	"strings"
+
+	"github.com/go-shiori/go-readability"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/utils"
+)
+
+// readerArticle is the main content of a page as readability finds it
+type readerArticle struct {
+	*readability.Article
+	// HTML is the article content passed through the html policy, safe to render inside smaraka
+	HTML      string
+	WordCount int
+}
+
+// readArticle runs readability on a page and sanitizes the article it finds
+func (s *ServicesImplementation) readArticle(rawURL, htmlText string) (*readerArticle, error) {
+	parsedURL, err := _url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	article, err := readability.FromReader(strings.NewReader(htmlText), parsedURL)
+	if err != nil {
+		return nil, err
+	}
+	return &readerArticle{
+		Article:   &article,
+		HTML:      strings.TrimSpace(s.policy.Sanitize(article.Content)),
+		WordCount: len(strings.Fields(article.TextContent)),
+	}, nil
+}
+
+// textToHTML turns plain text into escaped paragraphs, for documents and bookmarks saved before reader view
+func textToHTML(text string) string {
+	var b strings.Builder
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			b.WriteString("<p>" + html.EscapeString(paragraph) + "</p>\n")
+		}
+	}
+	return b.String()
+}
+
+// ReaderView returns the cleaned article of a bookmark of the organization
+func (s *ServicesImplementation) ReaderView(ctx context.Context, urlOrgID, orgID string) (*models.ReaderViewResponse, error) {
+	urlStore, err := s.db.GetURLStoreByURLOrgIDOrgID(ctx, urlOrgID, orgID)
+	if err != nil {
+		return nil, ErrBookmarkNotFound
+	}
+
+	resp := &models.ReaderViewResponse{
+		URLID:       urlStore.ID,
+		Title:       urlStore.Title,
+		URL:         urlStore.URL,
+		Author:      urlStore.Author,
+		SiteName:    urlStore.SiteName,
+		PublishedAt: urlStore.PublishedAt,
+		Summary:     urlStore.Summary,
+		Content:     urlStore.ArticleHTML,
+		WordCount:   urlStore.WordCount,
+		ReadingTime: urlStore.ReadingTime,
+	}
+	if resp.Content == "" {
+		resp.Content = textToHTML(urlStore.FullText)
+	}
+	if resp.WordCount == 0 {
+		resp.WordCount = len(strings.Fields(urlStore.FullText))
+	}
+	// the stored reading time may be a duration the page declares, like the cooking time of a recipe
+	if resp.WordCount > 0 {
+		resp.ReadingTime = utils.ReadingTime(resp.WordCount)
+	}
+	return resp, nil
+}
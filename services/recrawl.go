@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/utils"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	// a recrawl only counts as a change when at least this share of the text changed
+	minChangedRatio = 0.02
+	// or when at least this many characters changed
+	minChangedChars = 500
+)
+
+var ErrBookmarkNotFound = errors.New("bookmark not found")
+
+// extractText turns fetched html into the sanitized plain text we index
+func (s *ServicesImplementation) extractText(html string) string {
+	result := strings.Trim(s.policy.Sanitize(html), " ")
+	return utils.StripHTML(result)
+}
+
+// contentHash hashes text ignoring case and whitespace differences
+func contentHash(text string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// wordDiff diffs two texts word by word
+func wordDiff(from, to string) []diffmatchpatch.Diff {
+	dmp := diffmatchpatch.New()
+	// every word becomes a line so the line mode diff works on words
+	fromRunes, toRunes, words := dmp.DiffLinesToRunes(
+		strings.ReplaceAll(from, " ", " \n"),
+		strings.ReplaceAll(to, " ", " \n"),
+	)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMainRunes(fromRunes, toRunes, false), words)
+	for i := range diffs {
+		diffs[i].Text = strings.ReplaceAll(diffs[i].Text, "\n", "")
+	}
+	return dmp.DiffCleanupSemantic(diffs)
+}
+
+// meaningfulChange ignores small edits like rotating dates or counters
+func meaningfulChange(from, to string) bool {
+	changed := 0
+	for _, diff := range wordDiff(from, to) {
+		if diff.Type != diffmatchpatch.DiffEqual {
+			changed += len(diff.Text)
+		}
+	}
+	longest := max(len(from), len(to))
+	if longest == 0 {
+		return false
+	}
+	return changed >= minChangedChars || float64(changed)/float64(longest) >= minChangedRatio
+}
+
+// recordCrawl stores the validators of a full fetch and a version of its text when it is new
+func (s *ServicesImplementation) recordCrawl(ctx context.Context, urlStore *models.URLStore, fetched *FetchResult) error {
+	hash := contentHash(urlStore.FullText)
+	latest, err := s.db.GetLatestURLStoreVersion(ctx, urlStore.ID)
+	if err != nil || latest.ContentHash != hash {
+		err := s.db.InsertURLStoreVersion(ctx, models.URLStoreVersion{
+			ID:          s.db.NewID("url_ver"),
+			URLID:       urlStore.ID,
+			FullText:    urlStore.FullText,
+			ContentHash: hash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return s.db.UpdateURLStoreCrawlState(ctx, urlStore.ID, fetched.Header.Get("ETag"), fetched.Header.Get("Last-Modified"), hash)
+}
+
+// RecrawlStale refetches complete url stores that were not crawled for maxAge and tracks content changes
+func (s *ServicesImplementation) RecrawlStale(ctx context.Context, maxAge time.Duration, limit int) error {
+	urlStores, err := s.db.GetURLStoresDueForRecrawl(ctx, time.Now().Add(-maxAge), limit)
+	if err != nil {
+		return err
+	}
+	logger.LogInfo("Recrawling url stores, count: ", len(urlStores))
+	for i := range urlStores {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.recrawlURLStore(ctx, &urlStores[i]); err != nil {
+			logger.LogError("Error recrawling url", urlStores[i].URL, err)
+		}
+	}
+	return nil
+}
+
+func (s *ServicesImplementation) recrawlURLStore(ctx context.Context, urlStore *models.URLStore) error {
+	oldHash := urlStore.ContentHash
+	if oldHash == "" {
+		oldHash = contentHash(urlStore.FullText)
+	}
+
+	// a conditional plain http request tells us cheaply if anything changed at all
+	probe, err := s.fetcher.With(constants.FetchStrategyHTTP).Fetch(ctx, FetchRequest{
+		URL:          urlStore.URL,
+		ETag:         urlStore.ETag,
+		LastModified: urlStore.LastModified,
+	})
+	if err != nil {
+		s.db.UpdateURLStoreCrawlState(ctx, urlStore.ID, urlStore.ETag, urlStore.LastModified, oldHash)
+		return err
+	}
+	if probe.StatusCode == http.StatusNotModified || probe.StatusCode >= 400 {
+		return s.db.UpdateURLStoreCrawlState(ctx, urlStore.ID, urlStore.ETag, urlStore.LastModified, oldHash)
+	}
+
+	etag := probe.Header.Get("ETag")
+	lastModified := probe.Header.Get("Last-Modified")
+
+	fetched := probe
+	if !isPDF(probe) && s.fetcher.StrategyFor(urlStore.URL) != constants.FetchStrategyHTTP {
+		fetched, err = s.fetchWithRetry(ctx, FetchRequest{URL: urlStore.URL, Snapshot: true})
+		if err != nil {
+			s.db.UpdateURLStoreCrawlState(ctx, urlStore.ID, etag, lastModified, oldHash)
+			return err
+		}
+	}
+
+	text, err := s.fetchedText(fetched)
+	if err != nil {
+		s.db.UpdateURLStoreCrawlState(ctx, urlStore.ID, etag, lastModified, oldHash)
+		return err
+	}
+	newHash := contentHash(text)
+	if newHash == oldHash || !meaningfulChange(urlStore.FullText, text) {
+		return s.db.UpdateURLStoreCrawlState(ctx, urlStore.ID, etag, lastModified, oldHash)
+	}
+
+	// url stores completed before versions existed get their saved text as the baseline
+	if _, err := s.db.GetLatestURLStoreVersion(ctx, urlStore.ID); err != nil {
+		err := s.db.InsertURLStoreVersion(ctx, models.URLStoreVersion{
+			ID:          s.db.NewID("url_ver"),
+			URLID:       urlStore.ID,
+			FullText:    urlStore.FullText,
+			ContentHash: oldHash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	logger.LogInfo("Content changed for url", urlStore.URL)
+	err = s.db.UpdateURLStoreChangedContent(ctx, urlStore.ID, text, newHash, etag, lastModified, models.URLStoreVersion{
+		ID:          s.db.NewID("url_ver"),
+		URLID:       urlStore.ID,
+		FullText:    text,
+		ContentHash: newHash,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.storeSnapshot(ctx, urlStore, fetched); err != nil {
+		logger.LogError("Error storing snapshot", err)
+	}
+	urlStore.FullText = text
+	if err := s.indexLanguage(ctx, urlStore); err != nil {
+		logger.LogError("Error storing language", urlStore.URL, err)
+	}
+	if isPDF(fetched) {
+		return nil
+	}
+	if article, err := s.readArticle(urlStore.URL, fetched.HTML()); err == nil && article.HTML != "" {
+		if err := s.db.UpdateURLStoreReaderView(ctx, urlStore.ID, article.HTML, article.WordCount); err != nil {
+			logger.LogError("Error storing reader view", err)
+		}
+	}
+	return nil
+}
+
+// DiffSinceSaved diffs the text of a bookmark as it was when the organization saved it against the current text
+func (s *ServicesImplementation) DiffSinceSaved(ctx context.Context, urlOrgID, orgID string) (*models.BookmarkDiffResponse, error) {
+	urlOrg, err := s.db.GetURLOrganizationByID(ctx, urlOrgID)
+	if err != nil || urlOrg.OrganizationID != orgID {
+		return nil, ErrBookmarkNotFound
+	}
+	urlStore, err := s.db.GetURLStoreByID(ctx, urlOrg.URLID)
+	if err != nil {
+		return nil, ErrBookmarkNotFound
+	}
+
+	resp := &models.BookmarkDiffResponse{
+		URLID:         urlStore.ID,
+		SavedAt:       urlOrg.CreatedAt,
+		FromVersionAt: urlOrg.CreatedAt,
+		ChangedAt:     urlStore.ContentChangedAt,
+		Diff:          make([]models.BookmarkDiffChunk, 0),
+	}
+	resp.ChangedSinceSaved = urlStore.ContentChangedAt != nil && urlStore.ContentChangedAt.After(urlOrg.CreatedAt)
+
+	from := urlStore.FullText
+	if version, err := s.db.GetURLStoreVersionAsOf(ctx, urlStore.ID, urlOrg.CreatedAt); err == nil {
+		from = version.FullText
+		resp.FromVersionAt = version.CreatedAt
+	}
+
+	for _, diff := range wordDiff(from, urlStore.FullText) {
+		op := "equal"
+		switch diff.Type {
+		case diffmatchpatch.DiffInsert:
+			op = "insert"
+		case diffmatchpatch.DiffDelete:
+			op = "delete"
+		}
+		resp.Diff = append(resp.Diff, models.BookmarkDiffChunk{
+			Op:   op,
+			Text: diff.Text,
+		})
+	}
+	return resp, nil
+}
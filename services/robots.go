@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robotsGroup holds the rules of one User-agent block of a robots.txt
+type robotsGroup struct {
+	agents     []string
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+}
+
+// RobotsRules are the rules of a robots.txt that apply to our crawler
+type RobotsRules struct {
+	allow      []string
+	disallow   []string
+	CrawlDelay time.Duration
+}
+
+// allowAllRobots is used when a site has no usable robots.txt
+var allowAllRobots = &RobotsRules{}
+
+// ParseRobots parses a robots.txt and keeps the group matching agent, falling back to "*"
+func ParseRobots(body string, agent string) *RobotsRules {
+	groups := make([]*robotsGroup, 0)
+	var current *robotsGroup
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		switch key {
+		case "user-agent":
+			// consecutive user-agent lines share the same group
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow":
+			if current != nil && value != "" {
+				current.allow = append(current.allow, value)
+			}
+		case "disallow":
+			if current != nil && value != "" {
+				current.disallow = append(current.disallow, value)
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+		lastWasAgent = false
+	}
+
+	agent = strings.ToLower(agent)
+	var wildcard *robotsGroup
+	for _, group := range groups {
+		for _, a := range group.agents {
+			if a == agent {
+				return group.rules()
+			}
+			if a == "*" && wildcard == nil {
+				wildcard = group
+			}
+		}
+	}
+	if wildcard != nil {
+		return wildcard.rules()
+	}
+	return allowAllRobots
+}
+
+func (g *robotsGroup) rules() *RobotsRules {
+	return &RobotsRules{
+		allow:      g.allow,
+		disallow:   g.disallow,
+		CrawlDelay: g.crawlDelay,
+	}
+}
+
+// Allowed reports if path (with query) may be crawled, the longest matching rule wins
+func (r *RobotsRules) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	bestAllow, bestDisallow := -1, -1
+	for _, rule := range r.allow {
+		if robotsMatch(rule, path) && len(rule) > bestAllow {
+			bestAllow = len(rule)
+		}
+	}
+	for _, rule := range r.disallow {
+		if robotsMatch(rule, path) && len(rule) > bestDisallow {
+			bestDisallow = len(rule)
+		}
+	}
+	return bestDisallow < 0 || bestAllow >= bestDisallow
+}
+
+// robotsMatch matches a robots.txt path pattern supporting * and a trailing $
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	pieces := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, pieces[0]) {
+		return false
+	}
+	rest := path[len(pieces[0]):]
+	for _, piece := range pieces[1:] {
+		i := strings.Index(rest, piece)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(piece):]
+	}
+	if anchored {
+		last := pieces[len(pieces)-1]
+		if len(pieces) == 1 {
+			return rest == ""
+		}
+		return strings.HasSuffix(path, last)
+	}
+	return true
+}
@@ -11,12 +11,11 @@ import (
 	"github.com/rajnandan1/smaraka/constants"
 	"github.com/rajnandan1/smaraka/logger"
 	"github.com/rajnandan1/smaraka/models"
-	"github.com/rajnandan1/smaraka/utils"
 )
 
 //handle sch_gh_tranding
 
-func HandleGHTrending(githubURL string, interval int) (*[]string, error) {
+func (s *ServicesImplementation) HandleGHTrending(ctx context.Context, githubURL string, interval int) (*[]string, error) {
 
 	if interval == 1 {
 		githubURL = githubURL + "/trending?since=daily"
@@ -26,7 +25,7 @@ func HandleGHTrending(githubURL string, interval int) (*[]string, error) {
 		githubURL = githubURL + "/trending?since=monthly"
 	}
 
-	html, err := utils.FetchHTML(githubURL)
+	html, err := s.fetchHTML(ctx, githubURL)
 	if err != nil {
 		logger.LogError("Error fetching Github repos", err)
 		return nil, err
@@ -64,8 +63,8 @@ func HandleGHTrending(githubURL string, interval int) (*[]string, error) {
 
 }
 
-func HandleHackerNews(url string) (*[]string, error) {
-	html, err := utils.FetchHTML(url)
+func (s *ServicesImplementation) HandleHackerNews(ctx context.Context, url string) (*[]string, error) {
+	html, err := s.fetchHTML(ctx, url)
 	if err != nil {
 		logger.LogError("Error fetching HackerNews", err)
 		return nil, err
@@ -112,8 +111,8 @@ func createPhURL(interval int) string {
 	return ""
 }
 
-func HandlePHDaily(phURL string) (*[]string, error) {
-	html, err := utils.FetchHTML(phURL)
+func (s *ServicesImplementation) HandlePHDaily(ctx context.Context, phURL string) (*[]string, error) {
+	html, err := s.fetchHTML(ctx, phURL)
 	if err != nil {
 		logger.LogError("Error fetching Product Hunt daily", err)
 		return nil, err
@@ -150,7 +149,7 @@ func (s *ServicesImplementation) RunSchedule(ctx context.Context, interval int)
 	if err != nil {
 		return nil, err
 	}
-	return s.CreateRun(dailySchedules)
+	return s.CreateRun(ctx, dailySchedules)
 }
 
 func (s *ServicesImplementation) PlaySchedule(ctx context.Context, schedule_ids []string, org_id string) (*[]models.PeriodicResponse, error) {
@@ -158,10 +157,10 @@ func (s *ServicesImplementation) PlaySchedule(ctx context.Context, schedule_ids
 	if err != nil {
 		return nil, err
 	}
-	return s.CreateRun(dailySchedules)
+	return s.CreateRun(ctx, dailySchedules)
 }
 
-func (s *ServicesImplementation) CreateRun(dailySchedules *[]models.Schedule) (*[]models.PeriodicResponse, error) {
+func (s *ServicesImplementation) CreateRun(ctx context.Context, dailySchedules *[]models.Schedule) (*[]models.PeriodicResponse, error) {
 
 	if dailySchedules == nil {
 		return nil, nil
@@ -176,15 +175,15 @@ func (s *ServicesImplementation) CreateRun(dailySchedules *[]models.Schedule) (*
 
 		switch schedule.ScheduleType {
 		case constants.ScheduleTypeGHTrending:
-			if repos, err := HandleGHTrending(schedule.ScheduleURL, schedule.IntervalDays); err == nil && repos != nil {
+			if repos, err := s.HandleGHTrending(ctx, schedule.ScheduleURL, schedule.IntervalDays); err == nil && repos != nil {
 				orgMap[schedule.OrganizationID] = append(orgMap[schedule.OrganizationID], *repos...)
 			}
 		case constants.ScheduleTypeHNTrending:
-			if urls, err := HandleHackerNews(schedule.ScheduleURL); err == nil && urls != nil {
+			if urls, err := s.HandleHackerNews(ctx, schedule.ScheduleURL); err == nil && urls != nil {
 				orgMap[schedule.OrganizationID] = append(orgMap[schedule.OrganizationID], *urls...)
 			}
 		case constants.ScheduleTypeGHStarredRepo:
-			repos, err := s.ImportGithubStars(schedule.ScheduleURL)
+			repos, err := s.ImportGithubStars(ctx, schedule.ScheduleURL)
 			if err == nil && repos != nil {
 				for _, repo := range *repos {
 					orgMap[schedule.OrganizationID] = append(orgMap[schedule.OrganizationID], repo.URL)
@@ -193,7 +192,7 @@ func (s *ServicesImplementation) CreateRun(dailySchedules *[]models.Schedule) (*
 
 		case constants.ScheduleTypePHLeaderBoard:
 			phURL := schedule.ScheduleURL + createPhURL(schedule.IntervalDays)
-			if products, err := HandlePHDaily(phURL); err == nil && products != nil {
+			if products, err := s.HandlePHDaily(ctx, phURL); err == nil && products != nil {
 				orgMap[schedule.OrganizationID] = append(orgMap[schedule.OrganizationID], *products...)
 			}
 		}
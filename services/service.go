@@ -2,34 +2,56 @@ package services
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/microcosm-cc/bluemonday"
+	"github.com/rajnandan1/smaraka/blobstore"
 	"github.com/rajnandan1/smaraka/crypt"
 	"github.com/rajnandan1/smaraka/models"
 	"github.com/rajnandan1/smaraka/postgres"
 )
 
 type Services interface {
-	ImportGithubStars(ghUrl string) (*[]models.GithubRepo, error)
+	ImportGithubStars(ctx context.Context, ghUrl string) (*[]models.GithubRepo, error)
 	ParseUploadFile(fileObj models.FileUpload) ([]models.FileUploadResponse, error)
-	GetContentEasy(url string) (*models.URLStore, error)
-	DoContentCompleteByID(url_id string) (*models.URLStore, error)
-	BulkLightAndFullJob(validURLs []string, orgId string) error
+	GetContentEasy(ctx context.Context, url string) (*models.URLStore, error)
+	DoContentCompleteByID(ctx context.Context, url_id string) (*models.URLStore, error)
+	IngestURL(ctx context.Context, url, orgId string) error
+	PublishProgress(ctx context.Context, event models.IngestProgress)
+	KeepWithoutContent(ctx context.Context, url, orgId string) error
+	GetOrCreatePendingURLStore(ctx context.Context, url string) (*models.URLStore, error)
 	CreateNewSecret(ctx context.Context, userId, orgId, secretType, secretValue, secretName string) (*models.DbSecret, error)
 	GetSecretByValue(ctx context.Context, secretValue string) (*models.DbSecret, error)
 	RunSchedule(ctx context.Context, interval int) (*[]models.PeriodicResponse, error)
 	PlaySchedule(ctx context.Context, schedule_ids []string, org_id string) (*[]models.PeriodicResponse, error)
+	RecrawlStale(ctx context.Context, maxAge time.Duration, limit int) error
+	DiffSinceSaved(ctx context.Context, urlOrgID, orgID string) (*models.BookmarkDiffResponse, error)
+	CheckLinks(ctx context.Context, maxAge time.Duration, limit int) error
+	OpenSnapshot(ctx context.Context, urlOrgID, orgID string) (*models.URLSnapshot, io.ReadCloser, error)
+	OpenDocument(ctx context.Context, urlOrgID, orgID string) (*models.URLStore, io.ReadCloser, error)
+	DetectLanguages(ctx context.Context, limit int) error
+	SummarizeURLStore(ctx context.Context, urlID string) error
+	SummarizePending(ctx context.Context, limit int) error
+	ReaderView(ctx context.Context, urlOrgID, orgID string) (*models.ReaderViewResponse, error)
+	OpenThumbnail(ctx context.Context, urlID, size, orgID string) (string, io.ReadCloser, error)
+	OpenImage(ctx context.Context, urlID, kind, orgID string) (string, io.ReadCloser, error)
 }
+
 type ServicesImplementation struct {
-	db     postgres.Postgres
-	cr     crypt.Crypt
-	policy *bluemonday.Policy
+	db      postgres.Postgres
+	cr      crypt.Crypt
+	policy  *bluemonday.Policy
+	fetcher *RoutedFetcher
+	blobs   blobstore.Store
 }
 
-func ConfigureServices(db postgres.Postgres, c crypt.Crypt, p *bluemonday.Policy) (Services, error) {
+func ConfigureServices(db postgres.Postgres, c crypt.Crypt, p *bluemonday.Policy, f *RoutedFetcher, b blobstore.Store) (Services, error) {
 	return &ServicesImplementation{
-		db:     db,
-		cr:     c,
-		policy: p,
+		db:      db,
+		cr:      c,
+		policy:  p,
+		fetcher: f,
+		blobs:   b,
 	}, nil
 }
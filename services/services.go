@@ -3,26 +3,40 @@ package services
 import (
 	"context"
 	"errors"
-	_url "net/url"
+	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
-	"github.com/chromedp/cdproto/dom"
-	"github.com/chromedp/chromedp"
-	"github.com/go-shiori/go-readability"
 	"github.com/rajnandan1/smaraka/constants"
+	"github.com/rajnandan1/smaraka/extractors"
 	"github.com/rajnandan1/smaraka/logger"
 	"github.com/rajnandan1/smaraka/models"
 	"github.com/rajnandan1/smaraka/utils"
 )
 
-func (s *ServicesImplementation) GetContentEasy(url string) (*models.URLStore, error) {
+const (
+	maxThrottledAttempts = 3
+	maxThrottledWait     = 2 * time.Minute
+)
+
+func (s *ServicesImplementation) GetContentEasy(ctx context.Context, url string) (*models.URLStore, error) {
 
 	// call url and get html
-	html, err := utils.FetchHTML(url)
+	fetched, err := s.fetchLight(ctx, url)
 	if err != nil {
 		return nil, err
 	}
+	// an error page is not the bookmark, nothing is stored for it
+	if fetched.StatusCode >= 400 {
+		return nil, &HTTPStatusError{URL: url, StatusCode: fetched.StatusCode}
+	}
+	// a pdf is not html, it is read by the full fetch
+	if isPDF(fetched) {
+		bookmark := s.bareURLStore(url, constants.BookmarkStatusPending)
+		return &bookmark, nil
+	}
+	html := fetched.HTML()
 
 	bookmark, err := utils.ParseSEOFromHTML(html)
 	if err != nil {
@@ -33,24 +47,22 @@ func (s *ServicesImplementation) GetContentEasy(url string) (*models.URLStore, e
 	bookmark.ImageLarge = utils.ProperImageURL(url, bookmark.ImageLarge)
 	bookmark.ImageSmall = utils.ProperImageURL(url, bookmark.ImageSmall)
 
-	reader := strings.NewReader(html)
-	if parsedURL, err := _url.Parse(url); err == nil {
-		article, err := readability.FromReader(reader, parsedURL)
-		if err == nil {
-
-			if article.Excerpt != "" {
-				bookmark.Excerpt = article.Excerpt
-			}
+	if article, err := s.readArticle(url, html); err == nil {
 
-			if article.Favicon != "" {
-				bookmark.ImageSmall = article.Favicon
-			}
+		if article.Excerpt != "" {
+			bookmark.Excerpt = article.Excerpt
+		}
 
-			if article.Title != "" {
-				bookmark.Title = article.Title
-			}
+		if article.Favicon != "" {
+			bookmark.ImageSmall = article.Favicon
+		}
 
+		if article.Title != "" {
+			bookmark.Title = article.Title
 		}
+
+		bookmark.ArticleHTML = article.HTML
+		bookmark.WordCount = article.WordCount
 	}
 	//https://github.com/stars/GreatMach/repositories?direction=desc&filter=all&page=2&sort=created
 	if bookmark.Title == "" {
@@ -63,6 +75,8 @@ func (s *ServicesImplementation) GetContentEasy(url string) (*models.URLStore, e
 	bookmark.URL = url
 	bookmark.Status = constants.BookmarkStatusPending
 
+	bookmark.Language = pageLanguage(bookmark.Title, bookmark.Excerpt)
+
 	//get domain
 	if domain, err := utils.GetDomain(url); err == nil {
 
@@ -73,25 +87,31 @@ func (s *ServicesImplementation) GetContentEasy(url string) (*models.URLStore, e
 	return bookmark, nil
 
 }
-func (s *ServicesImplementation) DoContentCompleteByID(url string) (*models.URLStore, error) {
+func (s *ServicesImplementation) DoContentCompleteByID(ctx context.Context, url string) (*models.URLStore, error) {
 
-	//add a timeout for context cancel 60 secs
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
 	urlStore, err := s.db.GetURLStoreByURL(ctx, url)
 	if err != nil {
 		logger.LogError("Error getting URLStore by URL", err)
 		return nil, err
 	}
 	logger.LogInfo("Fetching inner HTML", urlStore.URL)
-	htmlText, err := utils.FetchInnerHTMLChrome(urlStore.URL)
+	fetched, err := s.fetcher.Fetch(ctx, FetchRequest{URL: urlStore.URL, Snapshot: true, Screenshot: true})
 	if err != nil {
 		logger.LogError("Error fetching inner HTML", err)
 		return nil, err
 	}
 
-	result := strings.Trim(s.policy.Sanitize(htmlText), " ")
-	result = utils.StripHTML(result)
+	return s.completeURLStore(ctx, urlStore, fetched)
+}
+
+// completeURLStore extracts the text and metadata of a full fetch and marks the url store complete
+func (s *ServicesImplementation) completeURLStore(ctx context.Context, urlStore *models.URLStore, fetched *FetchResult) (*models.URLStore, error) {
+	if isPDF(fetched) {
+		return s.completePDF(ctx, urlStore, fetched)
+	}
+	htmlText := fetched.HTML()
+
+	result := s.extractText(htmlText)
 
 	if newBookmark, newBookmarkErr := utils.ParseSEOFromHTML(htmlText); newBookmarkErr == nil {
 		if newBookmark.Title != "" {
@@ -103,148 +123,155 @@ func (s *ServicesImplementation) DoContentCompleteByID(url string) (*models.URLS
 		if newBookmark.AccentColor != "" {
 			urlStore.AccentColor = newBookmark.AccentColor
 		}
+		urlStore.Author = newBookmark.Author
+		urlStore.PublishedAt = newBookmark.PublishedAt
+		urlStore.ReadingTime = newBookmark.ReadingTime
+		urlStore.SiteName = newBookmark.SiteName
+		if newBookmark.CanonicalImage != "" {
+			urlStore.CanonicalImage = utils.ProperImageURL(urlStore.URL, newBookmark.CanonicalImage)
+		}
+		if newBookmark.OEmbedURL != "" {
+			s.applyOEmbed(ctx, urlStore, newBookmark.OEmbedURL)
+		}
+		// a url saved on its own is stored bare, its images come from this fetch
+		if urlStore.ImageLarge == "" {
+			urlStore.ImageLarge = newBookmark.ImageLarge
+		}
+		if urlStore.ImageSmall == "" {
+			urlStore.ImageSmall = newBookmark.ImageSmall
+		}
 
-		urlStore.ImageLarge = utils.ProperImageURL(urlStore.URL, urlStore.ImageLarge)
+		if !isThumbnailURL(urlStore.ImageLarge) {
+			urlStore.ImageLarge = utils.ProperImageURL(urlStore.URL, urlStore.ImageLarge)
+		}
 		urlStore.ImageSmall = utils.ProperImageURL(urlStore.URL, urlStore.ImageSmall)
 	}
 
-	urlStore.FullText = result
-	urlStore.Status = constants.BookmarkStatusComplete
-
-	updatedUrlStore, err := s.db.UpdateURLStoreByID(ctx, urlStore.ID, *urlStore)
-	if err != nil {
-		logger.LogError("Error updating bookmark", err)
-		return nil, err
-	}
-
-	return updatedUrlStore, nil
-
-}
-
-func (s *ServicesImplementation) BulkLightAndFullJob(validURLs []string, orgId string) error {
-	jobStartAt := time.Now()
-	logger.LogInfo("BulkLightAndFullJob for count: ", len(validURLs))
-
-	//loop through urls
-	options := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.UserAgent(constants.HeadlessUserAgent),
-	)
-
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), options...)
-	defer cancel()
-
-	ctx, cancel := chromedp.NewContext(allocCtx)
-	defer cancel()
-
-	ctx, cancel = context.WithTimeout(ctx, time.Duration(len(validURLs)*15+60)*time.Second)
-	defer cancel()
-
-	for _, validURL := range validURLs {
-		s.db.InsertJobQueue(ctx, orgId, validURL)
-	}
-
-	for _, validURL := range validURLs {
-
-		s.db.UpdateJobQueueStatus(ctx, orgId, validURL, constants.JobQueueStatusQueued)
-		_, getURLStoreByURLErr := s.db.GetURLStoreByURL(ctx, validURL)
-		if getURLStoreByURLErr != nil {
-			urlStore, getContentEasyErr := s.GetContentEasy(validURL)
-			if getContentEasyErr != nil {
-				logger.LogError("Error getting content", getContentEasyErr)
-				s.db.UpdateJobQueueStatus(ctx, orgId, validURL, constants.JobQueueStatusFailed)
-				continue
-			}
-			urlStore.ID = s.db.NewID("url")
-			if _, insertNewURLStoreErr := s.db.InsertNewURLStore(ctx, *urlStore); insertNewURLStoreErr != nil {
-				logger.LogError("Error inserting url store", insertNewURLStoreErr)
-				s.db.UpdateJobQueueStatus(ctx, orgId, validURL, constants.JobQueueStatusFailed)
-				continue
-			}
+	// the rendered page is read again, scripts may have filled in the article since the light fetch
+	if article, err := s.readArticle(urlStore.URL, htmlText); err == nil && article.HTML != "" {
+		if article.Favicon != "" && urlStore.ImageSmall == "" {
+			urlStore.ImageSmall = utils.ProperImageURL(urlStore.URL, article.Favicon)
 		}
-		urlStore, getURLStoreByURLErr := s.db.GetURLStoreByURL(ctx, validURL)
-		if getURLStoreByURLErr != nil {
-			logger.LogError("Error getting url store", getURLStoreByURLErr)
-			s.db.UpdateJobQueueStatus(ctx, orgId, validURL, constants.JobQueueStatusFailed)
-			continue
+		urlStore.ArticleHTML = article.HTML
+		urlStore.WordCount = article.WordCount
+		if urlStore.Author == "" {
+			urlStore.Author = article.Byline
 		}
-
-		urlOrg := models.URLOrganizations{
-			ID:             s.db.NewID("url_org"),
-			URLID:          urlStore.ID,
-			OrganizationID: orgId,
-			Status:         constants.URLStatusActive,
+		if urlStore.SiteName == "" {
+			urlStore.SiteName = article.SiteName
 		}
-
-		if _, insertNewURLOrganizationErr := s.db.InsertNewURLOrganization(ctx, urlOrg); insertNewURLOrganizationErr != nil {
-			// logger.LogError("Error inserting url org", insertNewURLOrganizationErr)
-			if strings.Contains(insertNewURLOrganizationErr.Error(), "duplicate key value violates unique constraint") {
-				s.db.UpdateJobQueueStatus(ctx, orgId, validURL, constants.JobQueueStatusComplete)
-			}
-			s.db.UpdateJobQueueStatus(ctx, orgId, validURL, constants.JobQueueStatusFailed)
-			continue
+		if urlStore.PublishedAt == nil && article.PublishedTime != nil {
+			publishedAt := article.PublishedTime.UTC()
+			urlStore.PublishedAt = &publishedAt
 		}
+	}
+	if urlStore.ReadingTime == 0 {
+		urlStore.ReadingTime = utils.ReadingTime(len(strings.Fields(result)))
+	}
 
-		if urlStore.Status != constants.BookmarkStatusPending {
-			continue
+	// pages without an image of their own get a thumbnail of their screenshot instead of whatever image came first
+	if len(fetched.Screenshot) > 0 && utils.OpenGraphImage(htmlText) == "" && urlStore.CanonicalImage == "" {
+		if thumbnailURL, err := s.storeThumbnails(ctx, urlStore.ID, fetched.Screenshot); err == nil {
+			urlStore.ImageLarge = thumbnailURL
+		} else {
+			logger.LogError("Error storing thumbnails", err)
 		}
+	}
 
-		logger.LogInfo("Fetching inner HTML", urlStore.URL)
-
-		var htmlText string
-
-		chromeErr := chromedp.Run(ctx,
-			chromedp.Navigate(validURL),
-			chromedp.WaitReady("body", chromedp.ByQuery),
-			chromedp.Sleep(2*time.Second),
-			chromedp.ActionFunc(func(ctx context.Context) error {
-				node, chromeErr2 := dom.GetDocument().Do(ctx)
-				if chromeErr2 != nil {
-					return chromeErr2
-				}
-				htmlText, chromeErr2 = dom.GetOuterHTML().WithNodeID(node.NodeID).Do(ctx)
-				return chromeErr2
-			}),
-		)
-		if chromeErr != nil {
-			if errors.Is(chromeErr, context.DeadlineExceeded) {
-				logger.LogError("timeout while fetching page:", chromeErr)
+	urlStore.FullText = result
 
-			}
-			logger.LogError("error fetching page:", chromeErr)
-			s.db.UpdateJobQueueStatus(ctx, orgId, validURL, constants.JobQueueStatusFailed)
-			continue
+	// the status is written last, a url store is only complete once everything of its fetch was stored.
+	// A fetch that is cut short leaves it as it was and its job is retried
+	updatedUrlStore, err := s.db.UpdateURLStoreByID(ctx, urlStore.ID, *urlStore)
+	if err != nil {
+		logger.LogError("Error updating bookmark", err)
+		return nil, err
+	}
+	if err := s.db.UpdateURLStoreStructuredData(ctx, urlStore.ID, *urlStore); err != nil {
+		return nil, fmt.Errorf("failed to store structured data: %w", err)
+	}
+	if err := s.db.UpdateURLStoreReaderView(ctx, urlStore.ID, urlStore.ArticleHTML, urlStore.WordCount); err != nil {
+		return nil, fmt.Errorf("failed to store reader view: %w", err)
+	}
+	if err := s.indexLanguage(ctx, urlStore); err != nil {
+		return nil, fmt.Errorf("failed to store language: %w", err)
+	}
+	updatedUrlStore.Language = urlStore.Language
+	if err := s.recordCrawl(ctx, updatedUrlStore, fetched); err != nil {
+		return nil, fmt.Errorf("failed to record crawl: %w", err)
+	}
+	if siteMetadata := extractors.Default.Extract(urlStore.URL, htmlText); siteMetadata != nil {
+		if err := s.db.UpdateURLStoreSiteMetadata(ctx, urlStore.ID, siteMetadata); err != nil {
+			return nil, fmt.Errorf("failed to store site metadata: %w", err)
 		}
+		updatedUrlStore.SiteMetadata = siteMetadata
+	}
+	if err := s.suggestTags(ctx, updatedUrlStore, htmlText); err != nil {
+		return nil, fmt.Errorf("failed to store suggested tags: %w", err)
+	}
+	if err := s.summarize(ctx, updatedUrlStore); err != nil {
+		return nil, fmt.Errorf("failed to store summary: %w", err)
+	}
 
-		result := strings.Trim(s.policy.Sanitize(htmlText), " ")
-		result = utils.StripHTML(result)
-
-		if newBookmark, newBookmarkErr := utils.ParseSEOFromHTML(htmlText); newBookmarkErr == nil {
-			urlStore.Title = newBookmark.Title
-			urlStore.Excerpt = newBookmark.Excerpt
-			if newBookmark.AccentColor != "" {
-				urlStore.AccentColor = newBookmark.AccentColor
-			}
+	completed, err := s.markComplete(ctx, updatedUrlStore)
+	if err != nil {
+		return nil, err
+	}
+	// a snapshot is added with every fetch, it is stored once nothing can fail the job any more so a retry does not add another.
+	// It is kept when there is room for it, the bookmark is complete without it
+	if err := s.storeSnapshot(ctx, completed, fetched); err != nil {
+		logger.LogError("Error storing snapshot", err)
+	}
+	return completed, nil
+}
 
-			urlStore.ImageLarge = utils.ProperImageURL(urlStore.URL, urlStore.ImageLarge)
-			urlStore.ImageSmall = utils.ProperImageURL(urlStore.URL, urlStore.ImageSmall)
-		}
+// markComplete marks a url store complete once all of its fetch is stored
+func (s *ServicesImplementation) markComplete(ctx context.Context, urlStore *models.URLStore) (*models.URLStore, error) {
+	if err := s.db.UpdateURLStoreStatus(ctx, urlStore.ID, constants.BookmarkStatusComplete); err != nil {
+		return nil, err
+	}
+	urlStore.Status = constants.BookmarkStatusComplete
+	return urlStore, nil
+}
 
-		urlStore.FullText = result
-		urlStore.Status = constants.BookmarkStatusComplete
+// applyOEmbed fills the author, site name and image a page left out from its oEmbed endpoint
+func (s *ServicesImplementation) applyOEmbed(ctx context.Context, urlStore *models.URLStore, oembedURL string) {
+	if urlStore.Author != "" && urlStore.SiteName != "" && urlStore.CanonicalImage != "" {
+		return
+	}
+	oembedURL = utils.ProperImageURL(urlStore.URL, oembedURL)
+	if !strings.HasPrefix(oembedURL, "http://") && !strings.HasPrefix(oembedURL, "https://") {
+		return
+	}
+	result, err := s.fetcher.With(constants.FetchStrategyHTTP).Fetch(ctx, FetchRequest{URL: oembedURL})
+	if err != nil || result.StatusCode != http.StatusOK {
+		logger.LogError("Error fetching oembed", oembedURL, err)
+		return
+	}
+	oembed, err := utils.ParseOEmbed(result.Body)
+	if err != nil {
+		logger.LogError("Error parsing oembed", oembedURL, err)
+		return
+	}
+	if urlStore.Author == "" {
+		urlStore.Author = oembed.AuthorName
+	}
+	if urlStore.SiteName == "" {
+		urlStore.SiteName = oembed.ProviderName
+	}
+	if urlStore.CanonicalImage == "" && oembed.ThumbnailURL != "" {
+		urlStore.CanonicalImage = utils.ProperImageURL(urlStore.URL, oembed.ThumbnailURL)
+	}
+}
 
-		_, updateURLStoreByIDErr := s.db.UpdateURLStoreByID(ctx, urlStore.ID, *urlStore)
-		if updateURLStoreByIDErr != nil {
-			logger.LogError("Error updating bookmark", updateURLStoreByIDErr)
-			s.db.UpdateJobQueueStatus(ctx, orgId, validURL, constants.JobQueueStatusFailed)
-			return updateURLStoreByIDErr
+// fetchWithRetry retries throttled fetches, the crawl scheduler holds them back until the host lets us in again.
+// Long back offs are not waited for, the ingest job of the url is snoozed until the host lets us in
+func (s *ServicesImplementation) fetchWithRetry(ctx context.Context, req FetchRequest) (*FetchResult, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := s.fetcher.Fetch(ctx, req)
+		var throttled *ThrottledError
+		if err == nil || !errors.As(err, &throttled) || attempt >= maxThrottledAttempts || throttled.RetryAfter > maxThrottledWait {
+			return result, err
 		}
-
-		s.db.UpdateJobQueueStatus(ctx, orgId, validURL, constants.JobQueueStatusComplete)
 	}
-
-	jobEndAt := time.Now()
-
-	logger.LogInfo("BulkLightAndFullJob for count: ", len(validURLs), " took: ", jobEndAt.Sub(jobStartAt))
-
-	return nil
 }
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	_url "net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// snapshots bigger than this are not stored, resources stop being inlined once it is reached
+	maxSnapshotBytes = 25 << 20
+	// nested @import rules followed when inlining stylesheets
+	maxCSSImportDepth = 3
+
+	snapshotContentType = "text/html; charset=utf-8"
+)
+
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+var (
+	cssURLRe    = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)`)
+	cssImportRe = regexp.MustCompile(`@import\s+(?:url\(\s*)?['"]?([^'")\s;]+)['"]?\s*\)?[^;]*;`)
+)
+
+// mhtmlPart is a single resource of an MHTML archive
+type mhtmlPart struct {
+	contentType string
+	location    string
+	data        []byte
+}
+
+// parseMHTML splits an MHTML archive into its main document and the resources keyed by location
+func parseMHTML(raw []byte) (*mhtmlPart, map[string]*mhtmlPart, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid mhtml: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, nil, fmt.Errorf("invalid mhtml content type %q", msg.Header.Get("Content-Type"))
+	}
+
+	var root *mhtmlPart
+	resources := make(map[string]*mhtmlPart)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid mhtml part: %v", err)
+		}
+
+		// quoted-printable is decoded by the multipart reader, base64 is not
+		var body io.Reader = part
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			body = base64.NewDecoder(base64.StdEncoding, part)
+		}
+		data, err := io.ReadAll(io.LimitReader(body, maxSnapshotBytes))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid mhtml part: %v", err)
+		}
+
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		p := &mhtmlPart{
+			contentType: contentType,
+			location:    part.Header.Get("Content-Location"),
+			data:        data,
+		}
+		if cid := part.Header.Get("Content-ID"); cid != "" {
+			resources["cid:"+strings.Trim(cid, "<>")] = p
+		}
+		if root == nil && contentType == "text/html" {
+			root = p
+			continue
+		}
+		if p.location != "" {
+			resources[p.location] = p
+		}
+	}
+
+	if root == nil {
+		return nil, nil, errors.New("mhtml has no html document")
+	}
+	return root, resources, nil
+}
+
+// snapshotInliner replaces references to archived resources with data uris
+type snapshotInliner struct {
+	resources map[string]*mhtmlPart
+	budget    int
+}
+
+func (in *snapshotInliner) lookup(base *_url.URL, ref string) *mhtmlPart {
+	ref = strings.TrimSpace(ref)
+	if part, ok := in.resources[ref]; ok {
+		return part
+	}
+	if base == nil {
+		return nil
+	}
+	resolved, err := base.Parse(ref)
+	if err != nil {
+		return nil
+	}
+	resolved.Fragment = ""
+	return in.resources[resolved.String()]
+}
+
+func (in *snapshotInliner) dataURI(base *_url.URL, ref string) (string, bool) {
+	if strings.HasPrefix(strings.TrimSpace(ref), "data:") {
+		return ref, true
+	}
+	part := in.lookup(base, ref)
+	if part == nil || len(part.data) > in.budget {
+		return "", false
+	}
+	in.budget -= len(part.data)
+	return "data:" + part.contentType + ";base64," + base64.StdEncoding.EncodeToString(part.data), true
+}
+
+// inlineCSS inlines imports and url() references of a stylesheet
+func (in *snapshotInliner) inlineCSS(base *_url.URL, css string, depth int) string {
+	css = cssImportRe.ReplaceAllStringFunc(css, func(rule string) string {
+		ref := cssImportRe.FindStringSubmatch(rule)[1]
+		part := in.lookup(base, ref)
+		if part == nil || depth >= maxCSSImportDepth {
+			return ""
+		}
+		importBase := base
+		if u, err := _url.Parse(part.location); err == nil && part.location != "" {
+			importBase = u
+		}
+		return in.inlineCSS(importBase, string(part.data), depth+1)
+	})
+	return cssURLRe.ReplaceAllStringFunc(css, func(match string) string {
+		ref := cssURLRe.FindStringSubmatch(match)[2]
+		if uri, ok := in.dataURI(base, ref); ok {
+			return `url("` + uri + `")`
+		}
+		return match
+	})
+}
+
+// inlineSrcset inlines the candidates of a srcset, candidates that are not archived are dropped
+func (in *snapshotInliner) inlineSrcset(base *_url.URL, srcset string) string {
+	candidates := make([]string, 0)
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		if uri, ok := in.dataURI(base, fields[0]); ok {
+			fields[0] = uri
+			candidates = append(candidates, strings.Join(fields, " "))
+		}
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// setRawText replaces the children of a raw text element like style, goquery's SetText would escape the text.
+// Closing tags are escaped so the text cannot end the element early
+func setRawText(sel *goquery.Selection, text string) {
+	text = strings.ReplaceAll(text, "</", `<\/`)
+	node := sel.Get(0)
+	for child := node.FirstChild; child != nil; child = node.FirstChild {
+		node.RemoveChild(child)
+	}
+	node.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+}
+
+// mhtmlToHTML turns a Chrome MHTML capture into a single html file with stylesheets and images inlined
+// and everything that could run code removed
+func mhtmlToHTML(raw []byte) ([]byte, error) {
+	root, resources, err := parseMHTML(raw)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(root.data))
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := _url.Parse(root.location)
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok && base != nil {
+		if resolved, err := base.Parse(href); err == nil {
+			base = resolved
+		}
+	}
+
+	in := &snapshotInliner{
+		resources: resources,
+		budget:    maxSnapshotBytes,
+	}
+
+	doc.Find("script, noscript, iframe, frame, frameset, object, embed, applet, base, meta[http-equiv]").Remove()
+
+	doc.Find("style").Each(func(i int, style *goquery.Selection) {
+		setRawText(style, in.inlineCSS(base, style.Text(), 0))
+	})
+
+	doc.Find("link").Each(func(i int, link *goquery.Selection) {
+		rel := strings.ToLower(link.AttrOr("rel", ""))
+		part := in.lookup(base, link.AttrOr("href", ""))
+		if !strings.Contains(rel, "stylesheet") || part == nil {
+			link.Remove()
+			return
+		}
+		cssBase := base
+		if u, err := _url.Parse(part.location); err == nil && part.location != "" {
+			cssBase = u
+		}
+		style := goquery.NewDocumentFromNode(&html.Node{Type: html.ElementNode, Data: "style", DataAtom: atom.Style}).Selection
+		setRawText(style, in.inlineCSS(cssBase, string(part.data), 0))
+		if media, ok := link.Attr("media"); ok {
+			style.SetAttr("media", media)
+		}
+		link.ReplaceWithSelection(style)
+	})
+
+	doc.Find("*").Each(func(i int, el *goquery.Selection) {
+		node := el.Get(0)
+		for _, attr := range append(node.Attr[:0:0], node.Attr...) {
+			name := strings.ToLower(attr.Key)
+			switch {
+			case strings.HasPrefix(name, "on"):
+				el.RemoveAttr(attr.Key)
+			case name == "style":
+				el.SetAttr(attr.Key, in.inlineCSS(base, attr.Val, 0))
+			case name == "srcset":
+				el.SetAttr(attr.Key, in.inlineSrcset(base, attr.Val))
+			case name == "src" || name == "poster" || (name == "href" && node.Data == "image"):
+				if uri, ok := in.dataURI(base, attr.Val); ok {
+					el.SetAttr(attr.Key, uri)
+				} else {
+					el.RemoveAttr(attr.Key)
+				}
+			case name == "href" || name == "action":
+				if strings.HasPrefix(strings.ToLower(strings.TrimSpace(attr.Val)), "javascript:") {
+					el.RemoveAttr(attr.Key)
+				} else if base != nil {
+					if resolved, err := base.Parse(attr.Val); err == nil {
+						el.SetAttr(attr.Key, resolved.String())
+					}
+				}
+			}
+		}
+	})
+
+	doc.Find("head").PrependHtml(`<meta charset="utf-8">`)
+
+	htmlText, err := doc.Html()
+	if err != nil {
+		return nil, err
+	}
+	if len(htmlText) > maxSnapshotBytes {
+		return nil, fmt.Errorf("snapshot of %d bytes is too large", len(htmlText))
+	}
+	return []byte(htmlText), nil
+}
+
+func snapshotBlobKey(urlID, snapshotID string) string {
+	return fmt.Sprintf("snapshots/%s/%s.html", urlID, snapshotID)
+}
+
+// storeSnapshot converts the MHTML captured during a fetch and stores it next to the url store
+func (s *ServicesImplementation) storeSnapshot(ctx context.Context, urlStore *models.URLStore, fetched *FetchResult) error {
+	if len(fetched.Snapshot) == 0 {
+		return nil
+	}
+	htmlSnapshot, err := mhtmlToHTML(fetched.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	snapshot := models.URLSnapshot{
+		ID:          s.db.NewID("url_snap"),
+		URLID:       urlStore.ID,
+		ContentType: snapshotContentType,
+		SizeBytes:   int64(len(htmlSnapshot)),
+	}
+	snapshot.BlobKey = snapshotBlobKey(snapshot.URLID, snapshot.ID)
+
+	if err := s.blobs.Put(ctx, snapshot.BlobKey, bytes.NewReader(htmlSnapshot), snapshot.ContentType); err != nil {
+		return err
+	}
+	if err := s.db.InsertURLSnapshot(ctx, snapshot); err != nil {
+		s.blobs.Delete(ctx, snapshot.BlobKey)
+		return err
+	}
+	logger.LogInfo("Stored snapshot for url", urlStore.URL, snapshot.SizeBytes)
+	return nil
+}
+
+// OpenSnapshot returns the latest snapshot of a bookmark of the organization, the caller closes the reader
+func (s *ServicesImplementation) OpenSnapshot(ctx context.Context, urlOrgID, orgID string) (*models.URLSnapshot, io.ReadCloser, error) {
+	urlStore, err := s.db.GetURLStoreByURLOrgIDOrgID(ctx, urlOrgID, orgID)
+	if err != nil {
+		return nil, nil, ErrBookmarkNotFound
+	}
+	snapshot, err := s.db.GetLatestURLSnapshot(ctx, urlStore.ID)
+	if err != nil {
+		return nil, nil, ErrSnapshotNotFound
+	}
+	body, err := s.blobs.Get(ctx, snapshot.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshot, body, nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+
+	"github.com/rajnandan1/smaraka/logger"
+	"github.com/rajnandan1/smaraka/models"
+	"github.com/rajnandan1/smaraka/summary"
+)
+
+// summarize stores a TextRank summary of the text of a url store, texts too short to summarize get an empty one
+func (s *ServicesImplementation) summarize(ctx context.Context, urlStore *models.URLStore) error {
+	urlStore.Summary = summary.Summarize(urlStore.FullText, urlStore.Language)
+	return s.db.UpdateURLStoreSummary(ctx, urlStore.ID, urlStore.Summary)
+}
+
+// SummarizeURLStore summarizes one url store again, for summaries asked for by users
+func (s *ServicesImplementation) SummarizeURLStore(ctx context.Context, urlID string) error {
+	urlStore, err := s.db.GetURLStoreByID(ctx, urlID)
+	if err != nil {
+		return err
+	}
+	return s.summarize(ctx, urlStore)
+}
+
+// SummarizePending summarizes url stores completed or changed since they were last summarized
+func (s *ServicesImplementation) SummarizePending(ctx context.Context, limit int) error {
+	urlStores, err := s.db.GetURLStoresDueForSummary(ctx, limit)
+	if err != nil {
+		return err
+	}
+
+	logger.LogInfo("Summarizing url stores", len(urlStores))
+	for i := range urlStores {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.summarize(ctx, &urlStores[i]); err != nil {
+			logger.LogError("Error summarizing url", urlStores[i].URL, err)
+		}
+	}
+	return nil
+}
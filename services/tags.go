@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/rajnandan1/smaraka/keywords"
+	"github.com/rajnandan1/smaraka/models"
+)
+
+const (
+	// maxSuggestedTags is how many tags are suggested for a page
+	maxSuggestedTags = 10
+	// maxMetaKeywords keeps pages stuffing their keywords meta tag from crowding out the rest
+	maxMetaKeywords = 5
+)
+
+// metaKeywords returns the keywords a page lists in its keywords meta tag
+func metaKeywords(htmlText string) []string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlText))
+	if err != nil {
+		return nil
+	}
+	found := make([]string, 0)
+	doc.Find(`meta[name="keywords"], meta[name="news_keywords"]`).Each(func(i int, s *goquery.Selection) {
+		for _, keyword := range strings.Split(s.AttrOr("content", ""), ",") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				found = append(found, keyword)
+			}
+		}
+	})
+	if len(found) > maxMetaKeywords {
+		found = found[:maxMetaKeywords]
+	}
+	return found
+}
+
+// siteTags are the tags a site gives a page itself, like the topics of a GitHub repository
+func siteTags(siteMetadata *models.SiteMetadata) []string {
+	switch {
+	case siteMetadata == nil:
+		return nil
+	case siteMetadata.GitHub != nil:
+		tags := append([]string{}, siteMetadata.GitHub.Topics...)
+		if siteMetadata.GitHub.Language != "" {
+			tags = append(tags, siteMetadata.GitHub.Language)
+		}
+		return tags
+	case siteMetadata.StackOverflow != nil:
+		return siteMetadata.StackOverflow.Tags
+	}
+	return nil
+}
+
+// suggestTags stores tags for a url store from what its site says about it, its keywords meta tag and
+// the keyphrases of its text, in that order
+func (s *ServicesImplementation) suggestTags(ctx context.Context, urlStore *models.URLStore, htmlText string) error {
+	tags := keywords.Merge(maxSuggestedTags,
+		siteTags(urlStore.SiteMetadata),
+		metaKeywords(htmlText),
+		keywords.Extract(urlStore.Title+".\n"+urlStore.FullText, urlStore.Language, maxSuggestedTags),
+	)
+	return s.db.UpdateURLStoreSuggestedTags(ctx, urlStore.ID, tags)
+}
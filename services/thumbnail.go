@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// viewport used for screenshots
+	screenshotWidth  = 1280
+	screenshotHeight = 800
+
+	// ThumbnailPath is where thumbnails are served, followed by the url store id
+	ThumbnailPath        = "/thumbnail/"
+	ThumbnailSizeLarge   = "large"
+	ThumbnailSizeSmall   = "small"
+	thumbnailContentType = "image/jpeg"
+	thumbnailQuality     = 80
+)
+
+var ErrThumbnailNotFound = errors.New("thumbnail not found")
+
+// thumbnailWidths are the widths thumbnails are generated in, the height keeps the viewport ratio
+var thumbnailWidths = map[string]int{
+	ThumbnailSizeLarge: 1200,
+	ThumbnailSizeSmall: 400,
+}
+
+func isThumbnailURL(imageURL string) bool {
+	return strings.HasPrefix(imageURL, ThumbnailPath)
+}
+
+func thumbnailBlobKey(urlID, size string) string {
+	return fmt.Sprintf("thumbnails/%s/%s.jpg", urlID, size)
+}
+
+// resizeToWidth scales an image down to width keeping its aspect ratio
+func resizeToWidth(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	if bounds.Dx() <= width {
+		return src
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
+
+// storeThumbnails generates the thumbnails of a screenshot and returns the url of the large one
+func (s *ServicesImplementation) storeThumbnails(ctx context.Context, urlID string, screenshot []byte) (string, error) {
+	src, _, err := image.Decode(bytes.NewReader(screenshot))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode screenshot: %v", err)
+	}
+
+	for size, width := range thumbnailWidths {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resizeToWidth(src, width), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return "", fmt.Errorf("failed to encode thumbnail: %v", err)
+		}
+		if err := s.blobs.Put(ctx, thumbnailBlobKey(urlID, size), &buf, thumbnailContentType); err != nil {
+			return "", err
+		}
+	}
+	return ThumbnailPath + urlID, nil
+}
+
+// OpenThumbnail returns a thumbnail of a url store the organization has bookmarked, the caller closes the reader
+func (s *ServicesImplementation) OpenThumbnail(ctx context.Context, urlID, size, orgID string) (string, io.ReadCloser, error) {
+	if _, ok := thumbnailWidths[size]; !ok {
+		size = ThumbnailSizeLarge
+	}
+	if _, err := s.db.GetURLOrganizationsByURLIDOrgID(ctx, urlID, orgID); err != nil {
+		return "", nil, ErrBookmarkNotFound
+	}
+	body, err := s.blobs.Get(ctx, thumbnailBlobKey(urlID, size))
+	if err != nil {
+		return "", nil, ErrThumbnailNotFound
+	}
+	return thumbnailContentType, body, nil
+}
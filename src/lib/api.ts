@@ -79,9 +79,9 @@ export async function fetchGithubStarredRepos(username: String): Promise<any> {
   });
 }
 
-export async function bulkAddUrls(urls: String[], direction: String): Promise<any> {
+export async function bulkAddUrls(urls: String[], direction: String, source: String): Promise<any> {
   const apiURL = `${serverAPIURL}/url/import-bulk`;
-  const body = JSON.stringify({ urls, direction });
+  const body = JSON.stringify({ urls, direction, source });
   const headers = {
     "Content-Type": "application/json",
   };
@@ -191,6 +191,11 @@ export async function getJobQueueStatus(): Promise<any> {
   });
 }
 
+//streams ingest progress events of the organization, the event name is the stage
+export function subscribeIngestProgress(): EventSource {
+  return new EventSource(`${serverAPIURL}/jobs/events`);
+}
+
 //implement patch
 export async function updateBookmark(id: String, data: any): Promise<any> {
   const apiURL = `${serverAPIURL}/url/bookmark-update/${id}`;
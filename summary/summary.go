@@ -0,0 +1,194 @@
+package summary
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rajnandan1/smaraka/keywords"
+	"github.com/rajnandan1/smaraka/lang"
+)
+
+const (
+	// MinSentences and MaxSentences bound the length of a summary, texts with fewer sentences get none
+	MinSentences = 3
+	MaxSentences = 5
+
+	// maxSentences caps how much of a text is ranked, comparing every sentence with every other grows quadratically
+	maxSentences     = 300
+	minSentenceWords = 5
+	maxSentenceWords = 60
+
+	damping    = 0.85
+	iterations = 50
+	tolerance  = 1e-6
+)
+
+// sentence is a sentence of the text and the words it is compared by
+type sentence struct {
+	text  string
+	words map[string]bool
+}
+
+// isSentenceEnd tells if a rune ends a sentence when whitespace follows it
+func isSentenceEnd(r rune) bool {
+	return r == '.' || r == '!' || r == '?'
+}
+
+// isCJKSentenceEnd tells if a rune ends a sentence on its own, chinese and japanese put no space after a full stop
+func isCJKSentenceEnd(r rune) bool {
+	return r == '。' || r == '！' || r == '？'
+}
+
+// splitSentences cuts text into sentences at sentence ends followed by whitespace and at line breaks,
+// which end headings and list items that have no full stop
+func splitSentences(text string) []string {
+	sentences := make([]string, 0)
+	runes := []rune(text)
+	start := 0
+	for i, r := range runes {
+		end := r == '\n' || isCJKSentenceEnd(r) || isSentenceEnd(r) && (i+1 == len(runes) || unicode.IsSpace(runes[i+1]))
+		if !end {
+			continue
+		}
+		if s := strings.Join(strings.Fields(string(runes[start:i+1])), " "); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = i + 1
+	}
+	if s := strings.Join(strings.Fields(string(runes[start:])), " "); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+// sentenceWords returns the words a sentence is compared by, stopwords left out.
+// Languages written without spaces are compared by characters
+func sentenceWords(text, language string) map[string]bool {
+	words := map[string]bool{}
+	if lang.IsCJK(language) {
+		for _, r := range text {
+			if unicode.IsLetter(r) {
+				words[string(r)] = true
+			}
+		}
+		return words
+	}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if !keywords.IsStopword(language, word) {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// wordCount counts words, or characters for languages written without spaces
+func wordCount(text, language string) int {
+	if lang.IsCJK(language) {
+		return len([]rune(text)) / 2
+	}
+	return len(strings.Fields(text))
+}
+
+// similarity is the TextRank similarity of two sentences, the words they share normalized by their lengths
+// so long sentences do not win just by being long
+func similarity(a, b sentence) float64 {
+	if len(a.words) < 2 || len(b.words) < 2 {
+		return 0
+	}
+	shared := 0
+	for word := range a.words {
+		if b.words[word] {
+			shared++
+		}
+	}
+	return float64(shared) / (math.Log(float64(len(a.words))) + math.Log(float64(len(b.words))))
+}
+
+// rank runs PageRank on the graph of sentences weighted by their similarity
+func rank(sentences []sentence) []float64 {
+	n := len(sentences)
+	weights := make([][]float64, n)
+	totals := make([]float64, n)
+	for i := range sentences {
+		weights[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			w := similarity(sentences[i], sentences[j])
+			weights[i][j], weights[j][i] = w, w
+			totals[i] += w
+			totals[j] += w
+		}
+	}
+
+	scores := make([]float64, n)
+	for i := range scores {
+		scores[i] = 1
+	}
+	for iteration := 0; iteration < iterations; iteration++ {
+		next := make([]float64, n)
+		change := 0.0
+		for i := 0; i < n; i++ {
+			sum := 0.0
+			for j := 0; j < n; j++ {
+				if weights[j][i] > 0 {
+					sum += weights[j][i] / totals[j] * scores[j]
+				}
+			}
+			next[i] = 1 - damping + damping*sum
+			change += math.Abs(next[i] - scores[i])
+		}
+		scores = next
+		if change < tolerance {
+			break
+		}
+	}
+	return scores
+}
+
+// Summarize picks the MinSentences to MaxSentences most central sentences of a text with TextRank and
+// returns them in the order they appear. Empty when the text has too few sentences to summarize
+func Summarize(text, language string) string {
+	sentences := make([]sentence, 0)
+	seen := map[string]bool{}
+	for _, s := range splitSentences(text) {
+		// repeated sentences are navigation and footers more often than content
+		if count := wordCount(s, language); count < minSentenceWords || count > maxSentenceWords || seen[s] {
+			continue
+		}
+		seen[s] = true
+		sentences = append(sentences, sentence{text: s, words: sentenceWords(s, language)})
+		if len(sentences) == maxSentences {
+			break
+		}
+	}
+	if len(sentences) < MinSentences {
+		return ""
+	}
+
+	// a summary gets longer with the text, one sentence of every ten up to MaxSentences
+	want := len(sentences) / 10
+	if want < MinSentences {
+		want = MinSentences
+	}
+	if want > MaxSentences {
+		want = MaxSentences
+	}
+
+	scores := rank(sentences)
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	picked := order[:want]
+	sort.Ints(picked)
+
+	texts := make([]string, 0, want)
+	for _, i := range picked {
+		texts = append(texts, sentences[i].text)
+	}
+	return strings.Join(texts, " ")
+}
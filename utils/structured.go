@@ -0,0 +1,303 @@
+package utils
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// wordsPerMinute is the reading speed reading times are estimated with
+const wordsPerMinute = 230
+
+var isoDurationRe = regexp.MustCompile(`^P(?:(\d+)D)?T?(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?$`)
+
+// schema.org types that describe the main content of a page, in order of preference
+var structuredContentTypes = []string{
+	"Article", "NewsArticle", "BlogPosting", "TechArticle", "ScholarlyArticle", "Report",
+	"Recipe", "Product", "VideoObject", "Book", "Movie", "Event", "WebPage",
+}
+
+// structuredDateLayouts are the date formats seen in JSON-LD, microdata and article meta tags
+var structuredDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// structuredData is the metadata a page declares about itself in JSON-LD, microdata, meta tags and its oEmbed link
+type structuredData struct {
+	Author         string
+	PublishedAt    *time.Time
+	ReadingTime    int
+	SiteName       string
+	CanonicalImage string
+	OEmbedURL      string
+}
+
+// ParseISODuration turns durations like PT1H2M3S into seconds
+func ParseISODuration(duration string) int {
+	match := isoDurationRe.FindStringSubmatch(strings.TrimSpace(duration))
+	if match == nil {
+		return 0
+	}
+	seconds := 0
+	for i, unit := range []int{86400, 3600, 60, 1} {
+		n, _ := strconv.Atoi(match[i+1])
+		seconds += n * unit
+	}
+	return seconds
+}
+
+// ReadingTime estimates the minutes it takes to read a number of words, any text takes at least a minute
+func ReadingTime(words int) int {
+	if words <= 0 {
+		return 0
+	}
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
+
+// parseStructuredDate reads the dates pages publish, nil when none of the known formats fit
+func parseStructuredDate(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range structuredDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			// timestamps are stored without zone, keep them all in utc
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
+
+// jsonLDTypes returns the @type of a node, which is either a string or a list
+func jsonLDTypes(node map[string]any) []string {
+	switch t := node["@type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// jsonLDNodes flattens the JSON-LD blocks of a page, blocks can be single nodes, lists or @graph containers
+func jsonLDNodes(value any) []map[string]any {
+	nodes := make([]map[string]any, 0)
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			nodes = append(nodes, jsonLDNodes(item)...)
+		}
+	case map[string]any:
+		if graph, ok := v["@graph"]; ok {
+			nodes = append(nodes, jsonLDNodes(graph)...)
+		}
+		if _, ok := v["@type"]; ok {
+			nodes = append(nodes, v)
+		}
+	}
+	return nodes
+}
+
+// jsonLDText reads a value that may be a string, an object with a name or url, or a list of either
+func jsonLDText(value any, keys ...string) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any:
+		for _, key := range keys {
+			if s := jsonLDText(v[key], keys...); s != "" {
+				return s
+			}
+		}
+	case []any:
+		values := make([]string, 0)
+		for _, item := range v {
+			if s := jsonLDText(item, keys...); s != "" {
+				values = append(values, s)
+			}
+		}
+		// authors are listed, images are alternatives of which the first will do
+		if len(keys) > 0 && keys[0] == "name" {
+			return strings.Join(values, ", ")
+		}
+		if len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// mainJSONLDNode picks the node describing the content of the page
+func mainJSONLDNode(nodes []map[string]any) map[string]any {
+	for _, wanted := range structuredContentTypes {
+		for _, node := range nodes {
+			for _, t := range jsonLDTypes(node) {
+				if t == wanted {
+					return node
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (d *structuredData) fromJSONLD(doc *goquery.Document) {
+	nodes := make([]map[string]any, 0)
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var value any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(s.Text())), &value); err == nil {
+			nodes = append(nodes, jsonLDNodes(value)...)
+		}
+	})
+
+	for _, node := range nodes {
+		for _, t := range jsonLDTypes(node) {
+			if t == "WebSite" && d.SiteName == "" {
+				d.SiteName = jsonLDText(node["name"])
+			}
+		}
+	}
+
+	node := mainJSONLDNode(nodes)
+	if node == nil {
+		return
+	}
+	if d.Author == "" {
+		d.Author = jsonLDText(node["author"], "name")
+	}
+	if d.PublishedAt == nil {
+		for _, key := range []string{"datePublished", "uploadDate", "dateCreated"} {
+			if d.PublishedAt = parseStructuredDate(jsonLDText(node[key])); d.PublishedAt != nil {
+				break
+			}
+		}
+	}
+	if d.CanonicalImage == "" {
+		d.CanonicalImage = jsonLDText(node["image"], "url", "contentUrl")
+	}
+	if d.CanonicalImage == "" {
+		d.CanonicalImage = jsonLDText(node["thumbnailUrl"], "url")
+	}
+	if d.SiteName == "" {
+		d.SiteName = jsonLDText(node["publisher"], "name")
+	}
+	if d.ReadingTime == 0 {
+		// recipes and videos take the time they declare, articles the time their words take
+		for _, key := range []string{"timeRequired", "totalTime", "duration"} {
+			if seconds := ParseISODuration(jsonLDText(node[key])); seconds > 0 {
+				d.ReadingTime = (seconds + 59) / 60
+				break
+			}
+		}
+	}
+	if d.ReadingTime == 0 {
+		words, _ := strconv.Atoi(jsonLDText(node["wordCount"]))
+		d.ReadingTime = ReadingTime(words)
+	}
+}
+
+// microdataValue reads an itemprop the way microdata defines it, from content, datetime, src or href before the text
+func microdataValue(s *goquery.Selection) string {
+	for _, attr := range []string{"content", "datetime", "src", "href"} {
+		if value, ok := s.Attr(attr); ok && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
+
+func (d *structuredData) fromMicrodata(doc *goquery.Document) {
+	scope := doc.Find(`[itemscope][itemtype*="schema.org"]`).First()
+	if scope.Length() == 0 {
+		return
+	}
+	if d.Author == "" {
+		author := scope.Find(`[itemprop="author"]`).First()
+		if name := author.Find(`[itemprop="name"]`).First(); name.Length() > 0 {
+			d.Author = microdataValue(name)
+		} else if author.Length() > 0 {
+			d.Author = microdataValue(author)
+		}
+	}
+	if d.PublishedAt == nil {
+		d.PublishedAt = parseStructuredDate(microdataValue(scope.Find(`[itemprop="datePublished"], [itemprop="uploadDate"]`).First()))
+	}
+	if d.CanonicalImage == "" {
+		if image := scope.Find(`[itemprop="image"], [itemprop="thumbnailUrl"]`).First(); image.Length() > 0 {
+			d.CanonicalImage = microdataValue(image)
+		}
+	}
+	if d.SiteName == "" {
+		if publisher := scope.Find(`[itemprop="publisher"] [itemprop="name"]`).First(); publisher.Length() > 0 {
+			d.SiteName = microdataValue(publisher)
+		}
+	}
+}
+
+// fromMeta fills what is still missing from article and OpenGraph meta tags and the oEmbed discovery link
+func (d *structuredData) fromMeta(doc *goquery.Document) {
+	metaContent := func(selector string) string {
+		return strings.TrimSpace(doc.Find(selector).First().AttrOr("content", ""))
+	}
+	if d.Author == "" {
+		d.Author = metaContent(`meta[name="author"], meta[property="article:author"], meta[name="citation_author"]`)
+		// article:author is often a profile url, that is no name
+		if strings.HasPrefix(d.Author, "http://") || strings.HasPrefix(d.Author, "https://") {
+			d.Author = ""
+		}
+	}
+	if d.PublishedAt == nil {
+		d.PublishedAt = parseStructuredDate(metaContent(`meta[property="article:published_time"], meta[name="date"], meta[name="citation_publication_date"], meta[itemprop="datePublished"]`))
+	}
+	if d.SiteName == "" {
+		d.SiteName = metaContent(`meta[property="og:site_name"], meta[name="application-name"]`)
+	}
+	if d.CanonicalImage == "" {
+		d.CanonicalImage = metaContent(`meta[property="og:image"], meta[name="twitter:image"]`)
+	}
+	d.OEmbedURL = strings.TrimSpace(doc.Find(`link[type="application/json+oembed"]`).First().AttrOr("href", ""))
+}
+
+// parseStructuredData collects structured metadata, JSON-LD wins over microdata which wins over meta tags
+func parseStructuredData(doc *goquery.Document) *structuredData {
+	d := &structuredData{}
+	d.fromJSONLD(doc)
+	d.fromMicrodata(doc)
+	d.fromMeta(doc)
+	return d
+}
+
+// OEmbed is the part of an oEmbed response we use
+type OEmbed struct {
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	ProviderName string `json:"provider_name"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// ParseOEmbed reads an oEmbed json response
+func ParseOEmbed(body []byte) (*OEmbed, error) {
+	var oembed OEmbed
+	if err := json.Unmarshal(body, &oembed); err != nil {
+		return nil, err
+	}
+	return &oembed, nil
+}
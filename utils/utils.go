@@ -2,25 +2,16 @@ package utils
 
 import (
 	"bytes"
-	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	_url "net/url"
 	"reflect"
 	"regexp"
 	"strings"
-	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/chromedp/cdproto/dom"
-	"github.com/chromedp/chromedp"
-	"github.com/rajnandan1/smaraka/constants"
-	"github.com/rajnandan1/smaraka/logger"
 	"github.com/rajnandan1/smaraka/models"
 	"golang.org/x/net/html"
 
@@ -57,39 +48,6 @@ func CreateMD5Hash(input string) string {
 	return hex.EncodeToString(hash.Sum(nil))
 }
 
-func FetchHTML(url string) (string, error) {
-	// Create a new context
-
-	// Perform the HTTP GET request with a custom User-Agent header
-	client := http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		logger.LogError("Error creating request", err)
-		return "", err
-	}
-	req.Header.Set("User-Agent", constants.HeadlessUserAgent)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		logger.LogError("Error fetching URL", err)
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		logger.LogError("Error reading response body", err)
-		return "", err
-	}
-
-	// Print the HTML content
-	return string(body), nil
-}
-
 func ProperImageURL(url string, imageURL string) string {
 
 	if imageURL == "" {
@@ -245,6 +203,13 @@ func ParseSEOFromHTML(html string) (*models.URLStore, error) {
 		}
 	})
 
+	structured := parseStructuredData(doc)
+
+	//prefer the image the page declares as its own over the first <img> tag
+	if imageLarge == "" {
+		imageLarge = structured.CanonicalImage
+	}
+
 	//if imageLarge is not set check for first <img> tag
 	if imageLarge == "" {
 		doc.Find("img").Each(func(i int, s *goquery.Selection) {
@@ -261,10 +226,31 @@ func ParseSEOFromHTML(html string) (*models.URLStore, error) {
 		AccentColor: accentColor,
 		ImageSmall:  imageSmall,
 		ImageLarge:  imageLarge,
+
+		Author:         structured.Author,
+		PublishedAt:    structured.PublishedAt,
+		ReadingTime:    structured.ReadingTime,
+		SiteName:       structured.SiteName,
+		CanonicalImage: structured.CanonicalImage,
+		OEmbedURL:      structured.OEmbedURL,
 	}
 	return bookmark, nil
 }
 
+// OpenGraphImage returns the og:image or twitter:image of a page, empty if it declares none
+func OpenGraphImage(html string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return ""
+	}
+	image := ""
+	doc.Find("meta[property='og:image'], meta[name='twitter:image']").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		image, _ = s.Attr("content")
+		return image == ""
+	})
+	return image
+}
+
 func RemoveNonNumeric(s string) string {
 	// Compile a regular expression that matches non-numeric characters
 	re := regexp.MustCompile("[^0-9]")
@@ -279,45 +265,6 @@ func RemoveAllNewlines(s string) string {
 	return strings.Trim(re.ReplaceAllString(s1, " "), " ")
 }
 
-func FetchInnerHTMLChrome(url string) (string, error) {
-	options := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.UserAgent(constants.HeadlessUserAgent),
-	)
-
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), options...)
-	defer cancel()
-
-	ctx, cancel := chromedp.NewContext(allocCtx)
-	defer cancel()
-
-	ctx, cancel = context.WithTimeout(ctx, 120*time.Second)
-	defer cancel()
-
-	var htmlText string
-	err := chromedp.Run(ctx,
-		chromedp.Navigate(url),
-		chromedp.WaitReady("body", chromedp.ByQuery),
-		chromedp.Sleep(2*time.Second),
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			node, err := dom.GetDocument().Do(ctx)
-			if err != nil {
-				return err
-			}
-			htmlText, err = dom.GetOuterHTML().WithNodeID(node.NodeID).Do(ctx)
-			return err
-		}),
-	)
-
-	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			return "", fmt.Errorf("timeout while fetching page: %w", err)
-		}
-		return "", fmt.Errorf("error fetching page: %w", err)
-	}
-
-	return htmlText, nil
-}
-
 // given array of strings, break the array n subArrays
 func ChunkStringArray(input []string, numOfSubArrays int) [][]string {
 	var output [][]string